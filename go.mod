@@ -2,4 +2,12 @@ module github.com/drakelthedragon/bazaar
 
 go 1.25.0
 
-require golang.org/x/sync v0.16.0
+require (
+	golang.org/x/crypto v0.54.0
+	golang.org/x/sync v0.22.0
+)
+
+require (
+	golang.org/x/net v0.56.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+)
@@ -0,0 +1,148 @@
+// Package happ provides an application runner that manages the lifecycle of multiple components.
+package happ
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/hio"
+)
+
+const _defaultStopTimeout = 10 * time.Second
+
+// ErrStopTimeout is returned when a component does not return within its stop timeout.
+var ErrStopTimeout = errors.New("stop timed out")
+
+// Runnable is a component that runs until its context is canceled.
+type Runnable interface {
+	Run(ctx context.Context) error
+}
+
+// RunnableFunc is an adapter to allow the use of ordinary functions as a [Runnable].
+type RunnableFunc func(ctx context.Context) error
+
+// Run calls f(ctx).
+func (f RunnableFunc) Run(ctx context.Context) error { return f(ctx) }
+
+// Serve returns a [Runnable] that serves h using [hio.Serve] without its signal handling, signals being handled
+// once by [App.Run] so that the server stops in order with the other components. Stopping it by canceling its
+// context is not an error.
+func Serve(h http.Handler, opts ...hio.ServeOption) Runnable {
+	opts = append([]hio.ServeOption{hio.WithoutSignalHandling()}, opts...)
+
+	return RunnableFunc(func(ctx context.Context) error {
+		err := hio.Serve(ctx, h, opts...)
+		if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	})
+}
+
+// ComponentError reports the component that caused an [App] to fail.
+type ComponentError struct {
+	Name string
+	Err  error
+}
+
+// Error returns the component name and its error.
+func (e *ComponentError) Error() string { return e.Name + ": " + e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *ComponentError) Unwrap() error { return e.Err }
+
+// App runs registered components together, stopping them in reverse order of registration.
+type App struct {
+	components []*component
+}
+
+type component struct {
+	name        string
+	r           Runnable
+	stopTimeout time.Duration
+	cancel      context.CancelFunc
+	done        chan error
+}
+
+// NewApp returns a new [App].
+func NewApp() *App { return &App{} }
+
+// Add registers the [Runnable] under the given name.
+func (a *App) Add(name string, r Runnable, opts ...ComponentOption) {
+	c := &component{name: name, r: r, stopTimeout: _defaultStopTimeout}
+
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+
+	a.components = append(a.components, c)
+}
+
+// Run starts all components and blocks until ctx is canceled, a signal is received, or a component fails.
+// Components are then stopped one at a time in reverse order of registration, each given its stop timeout
+// to return. The returned error joins a [ComponentError] for every component that failed.
+func (a *App) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	failed := make(chan struct{}, len(a.components))
+
+	for _, c := range a.components {
+		var cctx context.Context
+		cctx, c.cancel = context.WithCancel(context.WithoutCancel(ctx))
+		c.done = make(chan error, 1)
+
+		go func() {
+			err := c.r.Run(cctx)
+			if err != nil {
+				err = &ComponentError{Name: c.name, Err: err}
+				failed <- struct{}{}
+			}
+			c.done <- err
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-failed:
+	}
+
+	var errs []error
+
+	for _, c := range slices.Backward(a.components) {
+		c.cancel()
+
+		timer := time.NewTimer(c.stopTimeout)
+
+		select {
+		case err := <-c.done:
+			errs = append(errs, err)
+		case <-timer.C:
+			errs = append(errs, &ComponentError{Name: c.name, Err: fmt.Errorf("%w after %s", ErrStopTimeout, c.stopTimeout)})
+		}
+
+		timer.Stop()
+	}
+
+	return errors.Join(errs...)
+}
+
+// ComponentOption applies options to a component registered with [App.Add].
+type ComponentOption interface{ apply(*component) }
+
+type stopTimeoutOption struct{ value time.Duration }
+
+// WithStopTimeout sets how long the component is given to return after its context is canceled.
+func WithStopTimeout(v time.Duration) ComponentOption { return stopTimeoutOption{value: v} }
+
+func (o stopTimeoutOption) apply(c *component) {
+	if o.value > 0 {
+		c.stopTimeout = o.value
+	}
+}
@@ -0,0 +1,256 @@
+// Package hbus provides a typed in-process publish/subscribe event bus.
+package hbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/inmem"
+)
+
+const (
+	_defaultBuffer      = 64
+	_defaultMaxAttempts = 3
+	_defaultRetryDelay  = 100 * time.Millisecond
+)
+
+// ErrClosed is returned when publishing to a closed [Bus].
+var ErrClosed = errors.New("bus closed")
+
+// Topic identifies a stream of events of type T.
+type Topic[T any] struct{ name string }
+
+// NewTopic returns a new [Topic] with the given name.
+func NewTopic[T any](name string) Topic[T] { return Topic[T]{name: name} }
+
+// Name returns the topic name.
+func (t Topic[T]) Name() string { return t.name }
+
+// Bus delivers published events to the subscribers of their topic.
+// Each subscriber has its own buffer and goroutine so a slow or failing subscriber does not affect others.
+type Bus struct {
+	mu      sync.RWMutex
+	subs    map[string][]*subscriber
+	closed  bool
+	wg      sync.WaitGroup
+	onError func(topic string, err error)
+}
+
+type subscriber struct {
+	topic       string
+	ch          chan envelope
+	done        chan struct{}
+	handle      func(context.Context, any) error
+	maxAttempts int
+	retryDelay  time.Duration
+}
+
+type envelope struct {
+	ctx   context.Context
+	event any
+}
+
+// NewBus returns a new [Bus].
+func NewBus(opts ...BusOption) *Bus {
+	b := &Bus{
+		subs:    make(map[string][]*subscriber),
+		onError: func(string, error) {},
+	}
+
+	for _, opt := range opts {
+		opt.apply(b)
+	}
+
+	return b
+}
+
+// Subscribe registers h to receive the events published to t and returns a function that unsubscribes it.
+// A handler that returns an error or panics is retried until it exhausts its attempts, after which the
+// error is reported to the bus error handler. Subscribing to a closed bus does nothing.
+func Subscribe[T any](b *Bus, t Topic[T], h func(context.Context, T) error, opts ...SubscribeOption) func() {
+	s := &subscriber{
+		topic:       t.name,
+		ch:          make(chan envelope, _defaultBuffer),
+		done:        make(chan struct{}),
+		handle:      func(ctx context.Context, v any) error { return h(ctx, v.(T)) },
+		maxAttempts: _defaultMaxAttempts,
+		retryDelay:  _defaultRetryDelay,
+	}
+
+	for _, opt := range opts {
+		opt.apply(s)
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return func() {}
+	}
+	b.subs[t.name] = append(b.subs[t.name], s)
+	b.mu.Unlock()
+
+	b.wg.Go(func() { b.run(s) })
+
+	var once sync.Once
+
+	return func() { once.Do(func() { b.unsubscribe(s) }) }
+}
+
+// Publish delivers v to every subscriber of t, blocking while a subscriber buffer is full until ctx is done.
+// Handlers receive a context carrying the values of ctx that is not canceled with it.
+func Publish[T any](ctx context.Context, b *Bus, t Topic[T], v T) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	subs := slices.Clone(b.subs[t.name])
+	b.mu.RUnlock()
+
+	e := envelope{ctx: context.WithoutCancel(ctx), event: v}
+
+	// Events are sent without holding the lock, so that handlers publishing and subscribing while a buffer is
+	// full do not deadlock. Subscribers stopped in the meantime are skipped.
+	for _, s := range subs {
+		select {
+		case s.ch <- e:
+		case <-s.done:
+		case <-ctx.Done():
+			return fmt.Errorf("publishing to %s: %w", t.name, context.Cause(ctx))
+		}
+	}
+
+	return nil
+}
+
+// Close stops accepting events and waits until subscribers have handled their buffered events.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, subs := range b.subs {
+			for _, s := range subs {
+				close(s.done)
+			}
+		}
+		b.subs = nil
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+// Forward publishes every change of r to t until ctx is done.
+func Forward[K comparable, V inmem.IDer[K]](ctx context.Context, b *Bus, t Topic[inmem.Change[K, V]], r *inmem.Repository[K, V]) error {
+	for c := range r.Watch(ctx) {
+		if err := Publish(ctx, b, t, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bus) unsubscribe(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	subs := b.subs[s.topic]
+	for i, sub := range subs {
+		if sub == s {
+			b.subs[s.topic] = append(subs[:i:i], subs[i+1:]...)
+			close(s.done)
+			return
+		}
+	}
+}
+
+// run handles the events of s until it is stopped, and then those still buffered.
+func (b *Bus) run(s *subscriber) {
+	for {
+		select {
+		case e := <-s.ch:
+			b.handle(s, e)
+		case <-s.done:
+			for {
+				select {
+				case e := <-s.ch:
+					b.handle(s, e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (b *Bus) handle(s *subscriber, e envelope) {
+	var err error
+	for attempt := range s.maxAttempts {
+		if attempt > 0 {
+			time.Sleep(s.retryDelay)
+		}
+		if err = s.call(e); err == nil {
+			return
+		}
+	}
+	b.onError(s.topic, err)
+}
+
+func (s *subscriber) call(e envelope) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("handler panicked: %v", v)
+		}
+	}()
+	return s.handle(e.ctx, e.event)
+}
+
+// BusOption applies options to a [Bus].
+type BusOption interface{ apply(*Bus) }
+
+type errorHandlerOption struct{ value func(string, error) }
+
+// WithErrorHandler sets the function called with the errors of handlers that exhausted their attempts.
+func WithErrorHandler(v func(topic string, err error)) BusOption {
+	return errorHandlerOption{value: v}
+}
+
+func (o errorHandlerOption) apply(b *Bus) { b.onError = o.value }
+
+// SubscribeOption applies options to a subscription.
+type SubscribeOption interface{ apply(*subscriber) }
+
+type (
+	bufferOption      struct{ value int }
+	maxAttemptsOption struct{ value int }
+	retryDelayOption  struct{ value time.Duration }
+)
+
+// WithBuffer sets the number of events buffered for the subscriber.
+func WithBuffer(v int) SubscribeOption { return bufferOption{value: v} }
+
+// WithMaxAttempts sets how many times an event is handled before its error is reported.
+func WithMaxAttempts(v int) SubscribeOption { return maxAttemptsOption{value: v} }
+
+// WithRetryDelay sets the delay between attempts.
+func WithRetryDelay(v time.Duration) SubscribeOption { return retryDelayOption{value: v} }
+
+func (o bufferOption) apply(s *subscriber) {
+	if o.value >= 0 {
+		s.ch = make(chan envelope, o.value)
+	}
+}
+func (o maxAttemptsOption) apply(s *subscriber) {
+	if o.value > 0 {
+		s.maxAttempts = o.value
+	}
+}
+func (o retryDelayOption) apply(s *subscriber) { s.retryDelay = o.value }
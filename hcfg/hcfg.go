@@ -0,0 +1,447 @@
+// Package hcfg provides struct-tagged configuration loading from files, environment variables, and flags.
+//
+// Fields take part in loading when they carry a cfg tag of the form `cfg:"name[,required][,secret]"`
+// and may declare a fallback with `default:"value"`. Values are applied with increasing precedence
+// from defaults, files, environment variables, and finally flags. Nested structs are addressed by
+// joining names with a dot, e.g. "tls.cert_file" which maps to the TLS_CERT_FILE environment variable
+// and the -tls.cert-file flag. Files are decoded as JSON, or as YAML with [DecodeYAML] for the .yaml and .yml
+// extensions, and other formats are added with [WithDecoder].
+//
+// The configurations of the other packages, hio.ServeConfig, hlog.Config, and ppp.Config, carry cfg tags so that
+// a service nests them in its own configuration and loads them together.
+package hcfg
+
+import (
+	"encoding"
+	"encoding/json/v2"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Load populates the struct pointed to by v from the configured sources and validates it.
+// If v or a nested struct implements Validate() error it is called after all values are applied, nested
+// structs first.
+func Load(v any, opts ...Option) error {
+	var cfg loadConfig
+
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("loading config: value must be a pointer to a struct")
+	}
+
+	fields, err := collect(rv.Elem(), "")
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	values := make(map[string]string)
+
+	for _, f := range fields {
+		if d, ok := f.sf.Tag.Lookup("default"); ok {
+			values[f.name] = d
+		}
+	}
+
+	for _, path := range cfg.files {
+		if err := readFile(path, cfg.decoders, values); err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+	}
+
+	for _, f := range fields {
+		if s, ok := os.LookupEnv(envName(cfg.envPrefix, f.name)); ok {
+			values[f.name] = s
+		}
+	}
+
+	if cfg.flags != nil {
+		if err := parseFlags(cfg.flags, cfg.args, fields, values); err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+	}
+
+	var errs []error
+
+	for _, f := range fields {
+		s, ok := values[f.name]
+		if !ok {
+			if f.required {
+				errs = append(errs, fmt.Errorf("%s: required", f.name))
+			}
+			continue
+		}
+		if err := set(f.v, s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.name, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	if err := validate(rv.Elem(), ""); err != nil {
+		return fmt.Errorf("validating config: %w", err)
+	}
+
+	return nil
+}
+
+// validate calls the Validate methods of the cfg tagged nested structs, such as an hlog.Config, and then of rv.
+func validate(rv reflect.Value, name string) error {
+	for i := range rv.NumField() {
+		sf := rv.Type().Field(i)
+		tag, ok := sf.Tag.Lookup("cfg")
+		fv := rv.Field(i)
+		if !ok || tag == "-" || !sf.IsExported() || fv.Kind() != reflect.Struct || isScalar(fv) {
+			continue
+		}
+		nested, _, _ := strings.Cut(tag, ",")
+		if err := validate(fv, name+nested+"."); err != nil {
+			return err
+		}
+	}
+
+	if val, ok := rv.Addr().Interface().(interface{ Validate() error }); ok {
+		if err := val.Validate(); err != nil {
+			if name != "" {
+				return fmt.Errorf("%s: %w", strings.TrimSuffix(name, "."), err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// String formats the cfg tagged fields of v as space separated name=value pairs with secret values redacted.
+func String(v any) string {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Sprint(v)
+	}
+
+	if !rv.CanAddr() {
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		rv = p.Elem()
+	}
+
+	fields, err := collect(rv, "")
+	if err != nil {
+		return err.Error()
+	}
+
+	var b strings.Builder
+
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(f.name)
+		b.WriteByte('=')
+		if f.secret && !f.v.IsZero() {
+			b.WriteString("[REDACTED]")
+			continue
+		}
+		fmt.Fprint(&b, f.v.Interface())
+	}
+
+	return b.String()
+}
+
+// Option applies options to [Load].
+type Option interface{ apply(*loadConfig) }
+
+type loadConfig struct {
+	envPrefix string
+	files     []string
+	flags     *flag.FlagSet
+	args      []string
+	decoders  map[string]func([]byte, any) error
+}
+
+type (
+	envPrefixOption struct{ value string }
+	filesOption     struct{ value []string }
+
+	flagsOption struct {
+		fs   *flag.FlagSet
+		args []string
+	}
+
+	decoderOption struct {
+		ext string
+		fn  func([]byte, any) error
+	}
+)
+
+// WithEnvPrefix sets the prefix prepended to environment variable names.
+func WithEnvPrefix(v string) Option { return envPrefixOption{value: v} }
+
+// WithFiles reads the files in order, later files overriding earlier ones. Missing files are skipped.
+func WithFiles(v ...string) Option { return filesOption{value: v} }
+
+// WithFlags registers a flag per field on fs and parses args.
+func WithFlags(fs *flag.FlagSet, args []string) Option { return flagsOption{fs: fs, args: args} }
+
+// WithDecoder registers a decoder for files with the extension ext, such as yaml.Unmarshal for ".yaml".
+// Decoders unmarshal into a map[string]any. Files with the .yaml and .yml extensions are decoded with
+// [DecodeYAML] and others as JSON by default.
+func WithDecoder(ext string, fn func([]byte, any) error) Option {
+	return decoderOption{ext: ext, fn: fn}
+}
+
+func (o envPrefixOption) apply(cfg *loadConfig) { cfg.envPrefix = o.value }
+func (o filesOption) apply(cfg *loadConfig)     { cfg.files = append(cfg.files, o.value...) }
+func (o flagsOption) apply(cfg *loadConfig)     { cfg.flags, cfg.args = o.fs, o.args }
+func (o decoderOption) apply(cfg *loadConfig) {
+	if cfg.decoders == nil {
+		cfg.decoders = make(map[string]func([]byte, any) error)
+	}
+	cfg.decoders[o.ext] = o.fn
+}
+
+type field struct {
+	name     string
+	required bool
+	secret   bool
+	sf       reflect.StructField
+	v        reflect.Value
+}
+
+func collect(rv reflect.Value, prefix string) ([]field, error) {
+	var fields []field
+
+	for i := range rv.NumField() {
+		sf := rv.Type().Field(i)
+
+		tag, ok := sf.Tag.Lookup("cfg")
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+
+		name, flags, _ := strings.Cut(tag, ",")
+		if name == "" {
+			return nil, fmt.Errorf("field %s: cfg tag must have a name", sf.Name)
+		}
+		name = prefix + name
+
+		fv := rv.Field(i)
+
+		if fv.Kind() == reflect.Struct && !isScalar(fv) {
+			nested, err := collect(fv, name+".")
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+			continue
+		}
+
+		f := field{name: name, sf: sf, v: fv}
+
+		for opt := range strings.SplitSeq(flags, ",") {
+			switch opt {
+			case "required":
+				f.required = true
+			case "secret":
+				f.secret = true
+			}
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+func isScalar(v reflect.Value) bool {
+	_, ok := v.Addr().Interface().(encoding.TextUnmarshaler)
+	return ok
+}
+
+func envName(prefix, name string) string {
+	return prefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(name))
+}
+
+func flagName(name string) string { return strings.ReplaceAll(name, "_", "-") }
+
+func readFile(path string, decoders map[string]func([]byte, any) error, values map[string]string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(path)
+	decode := decoders[ext]
+	switch {
+	case decode != nil:
+	case ext == ".yaml" || ext == ".yml":
+		decode = DecodeYAML
+	default:
+		decode = func(b []byte, v any) error { return json.Unmarshal(b, v) }
+	}
+
+	var m map[string]any
+	if err := decode(data, &m); err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+
+	flatten("", m, values)
+
+	return nil
+}
+
+func flatten(prefix string, m map[string]any, values map[string]string) {
+	for k, v := range m {
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(prefix+k+".", v, values)
+		case string:
+			values[prefix+k] = v
+		case []any:
+			s := make([]string, len(v))
+			for i, e := range v {
+				s[i] = scalarString(e)
+			}
+			values[prefix+k] = strings.Join(s, ",")
+		case nil:
+		default:
+			values[prefix+k] = scalarString(v)
+		}
+	}
+}
+
+// scalarString formats a decoded value, writing floats such as the numbers of JSON without an exponent so that
+// integral ones parse as integers.
+func scalarString(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return fmt.Sprint(v)
+}
+
+// flagValue holds the value of a field flag, reused when the same [flag.FlagSet] is loaded again.
+type flagValue struct {
+	value   string
+	set     bool
+	boolean bool
+}
+
+func (v *flagValue) String() string { return v.value }
+
+// IsBoolFlag lets the flags of bool fields be set without a value, such as -h2c.
+func (v *flagValue) IsBoolFlag() bool { return v.boolean }
+
+func (v *flagValue) Set(s string) error {
+	v.value, v.set = s, true
+	return nil
+}
+
+func parseFlags(fs *flag.FlagSet, args []string, fields []field, values map[string]string) error {
+	flags := make(map[string]*flagValue, len(fields))
+
+	for _, f := range fields {
+		name := flagName(f.name)
+		boolean := f.sf.Type.Kind() == reflect.Bool
+		if fl := fs.Lookup(name); fl != nil {
+			fv, ok := fl.Value.(*flagValue)
+			if !ok {
+				return fmt.Errorf("flag %s: already defined", name)
+			}
+			*fv = flagValue{boolean: boolean}
+			flags[f.name] = fv
+			continue
+		}
+		fv := &flagValue{boolean: boolean}
+		fs.Var(fv, name, f.sf.Tag.Get("usage"))
+		flags[f.name] = fv
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	for name, fv := range flags {
+		if fv.set {
+			values[name] = fv.value
+		}
+	}
+
+	return nil
+}
+
+var _durationType = reflect.TypeFor[time.Duration]()
+
+func set(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == _durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", v.Type())
+		}
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+		v.Set(reflect.ValueOf(parts).Convert(v.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
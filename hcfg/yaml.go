@@ -0,0 +1,268 @@
+package hcfg
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DecodeYAML decodes the subset of YAML used by configuration files into a map[string]any pointed to by v, and
+// is the default decoder of files with the .yaml and .yml extensions. It supports block mappings, block sequences
+// and flow sequences of scalars, plain and quoted scalars, and comments. Scalars are decoded as strings, which
+// fields parse according to their type. Anchors, tags, multi-line scalars, and mappings within sequences are not
+// supported, for which a full decoder such as yaml.Unmarshal is registered with [WithDecoder].
+func DecodeYAML(data []byte, v any) error {
+	m, ok := v.(*map[string]any)
+	if !ok {
+		return fmt.Errorf("decoding yaml: %T is not a *map[string]any", v)
+	}
+
+	lines, err := yamlLines(string(data))
+	if err != nil {
+		return err
+	}
+
+	*m = make(map[string]any)
+	if len(lines) == 0 {
+		return nil
+	}
+
+	p := &yamlParser{lines: lines}
+	node, err := p.node(lines[0].indent)
+	if err != nil {
+		return err
+	}
+	if p.i < len(p.lines) {
+		return p.errorf("unexpected indentation")
+	}
+
+	root, ok := node.(map[string]any)
+	if !ok {
+		return errors.New("decoding yaml: document is not a mapping")
+	}
+	*m = root
+	return nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// yamlLines returns the lines holding content, without comments and document markers.
+func yamlLines(data string) ([]yamlLine, error) {
+	var lines []yamlLine
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(stripComment(line), " \t\r")
+		text := strings.TrimLeft(line, " ")
+		if text == "" || text == "---" || text == "..." {
+			continue
+		}
+		if text[0] == '\t' {
+			return nil, fmt.Errorf("decoding yaml: line %d: tab in indentation", i+1)
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: len(line) - len(text), text: text})
+	}
+	return lines, nil
+}
+
+// stripComment removes a comment starting with a # outside quotes at the start of the line or after a space.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	i     int
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("decoding yaml: line %d: %s", p.lines[p.i].num, fmt.Sprintf(format, args...))
+}
+
+// node parses the mapping or sequence starting at the current line, whose lines are indented by indent.
+func (p *yamlParser) node(indent int) (any, error) {
+	if isSequenceItem(p.lines[p.i].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	m := make(map[string]any)
+
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent {
+		line := p.lines[p.i]
+		if isSequenceItem(line.text) {
+			return nil, p.errorf("unexpected sequence item")
+		}
+
+		rawKey, value, ok := splitKey(line.text)
+		if !ok {
+			return nil, p.errorf("expected key: value")
+		}
+		key, err := yamlScalar(rawKey)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, p.errorf("null key")
+		}
+		if _, dup := m[name]; dup {
+			return nil, p.errorf("duplicate key %s", name)
+		}
+		p.i++
+
+		if value != "" {
+			if m[name], err = yamlScalar(value); err != nil {
+				p.i--
+				return nil, p.errorf("%v", err)
+			}
+			continue
+		}
+
+		switch {
+		case p.i < len(p.lines) && p.lines[p.i].indent > indent:
+			m[name], err = p.node(p.lines[p.i].indent)
+		case p.i < len(p.lines) && p.lines[p.i].indent == indent && isSequenceItem(p.lines[p.i].text):
+			m[name], err = p.sequence(indent)
+		default:
+			m[name] = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.i < len(p.lines) && p.lines[p.i].indent > indent {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return m, nil
+}
+
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	var s []any
+
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent && isSequenceItem(p.lines[p.i].text) {
+		item := strings.TrimSpace(p.lines[p.i].text[1:])
+		if item == "" {
+			return nil, p.errorf("nested sequence item not supported")
+		}
+		if _, _, ok := splitKey(item); ok {
+			return nil, p.errorf("mapping in sequence not supported")
+		}
+		v, err := yamlScalar(item)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		s = append(s, v)
+		p.i++
+	}
+
+	return s, nil
+}
+
+func isSequenceItem(text string) bool { return text == "-" || strings.HasPrefix(text, "- ") }
+
+// splitKey splits the text at the first colon outside quotes followed by a space or ending the text.
+func splitKey(text string) (key, value string, ok bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// yamlScalar decodes a plain, quoted, or null scalar, or a flow sequence of them.
+func yamlScalar(s string) (any, error) {
+	switch {
+	case s == "~" || s == "null" || s == "Null" || s == "NULL":
+		return nil, nil
+	case s[0] == '"':
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("malformed double-quoted scalar %s", s)
+		}
+		return v, nil
+	case s[0] == '\'':
+		if len(s) < 2 || s[len(s)-1] != '\'' {
+			return nil, fmt.Errorf("malformed single-quoted scalar %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case s[0] == '[':
+		return flowSequence(s)
+	case strings.ContainsRune("{|>&*!", rune(s[0])):
+		return nil, fmt.Errorf("unsupported scalar %s", s)
+	}
+	return s, nil
+}
+
+func flowSequence(s string) ([]any, error) {
+	if s[len(s)-1] != ']' {
+		return nil, fmt.Errorf("malformed flow sequence %s", s)
+	}
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	if inner == "" {
+		return []any{}, nil
+	}
+
+	var (
+		items []any
+		quote byte
+		start int
+	)
+	for i := 0; i <= len(inner); i++ {
+		if i < len(inner) {
+			switch c := inner[i]; {
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+				continue
+			case c == '"' || c == '\'':
+				quote = c
+				continue
+			case c == '[' || c == '{':
+				return nil, fmt.Errorf("nested flow collection in %s not supported", s)
+			case c != ',':
+				continue
+			}
+		}
+		item := strings.TrimSpace(inner[start:i])
+		if item == "" {
+			return nil, fmt.Errorf("empty item in flow sequence %s", s)
+		}
+		v, err := yamlScalar(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+		start = i + 1
+	}
+	return items, nil
+}
@@ -0,0 +1,25 @@
+// Package hclient provides middleware for HTTP clients.
+package hclient
+
+import "net/http"
+
+// Middleware is an alias for a function that takes and returns an [http.RoundTripper].
+type Middleware = func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an adapter to use a function as an [http.RoundTripper].
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(r).
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// Chain wraps the transport with the middlewares so that the first one sees requests first.
+// A nil transport is replaced by [http.DefaultTransport].
+func Chain(rt http.RoundTripper, mws ...Middleware) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	for i := len(mws) - 1; i >= 0; i-- {
+		rt = mws[i](rt)
+	}
+	return rt
+}
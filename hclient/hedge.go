@@ -0,0 +1,228 @@
+package hclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultMaxHedges       = 1
+	_defaultHedgeBudget     = 0.1
+	_defaultHedgeBurst      = 10
+	_defaultHedgeQuantile   = 0.95
+	_hedgeLatencySamples    = 1000
+	_hedgeMinLatencySamples = 20
+	_hedgeRecomputeEvery    = 50
+)
+
+// HedgeOption configures [Hedge].
+type HedgeOption interface{ apply(*hedgeConfig) }
+
+type hedgeConfig struct {
+	delay     time.Duration
+	maxHedges int
+	budget    float64
+}
+
+type (
+	hedgeDelayOption  struct{ value time.Duration }
+	maxHedgesOption   struct{ value int }
+	hedgeBudgetOption struct{ value float64 }
+)
+
+// WithHedgeDelay sends hedges after a fixed delay instead of the observed 95th percentile latency.
+func WithHedgeDelay(d time.Duration) HedgeOption { return hedgeDelayOption{value: d} }
+
+// WithMaxHedges sets how many extra attempts are sent for a request, one more after each delay. Defaults to 1.
+func WithMaxHedges(n int) HedgeOption { return maxHedgesOption{value: n} }
+
+// WithHedgeBudget caps hedges to the ratio of requests, such as 0.1 for at most one hedge per ten requests
+// on average, so that a slow upstream does not receive a multiple of its load. Defaults to 0.1.
+func WithHedgeBudget(ratio float64) HedgeOption { return hedgeBudgetOption{value: ratio} }
+
+func (o hedgeDelayOption) apply(cfg *hedgeConfig)  { cfg.delay = o.value }
+func (o maxHedgesOption) apply(cfg *hedgeConfig)   { cfg.maxHedges = o.value }
+func (o hedgeBudgetOption) apply(cfg *hedgeConfig) { cfg.budget = o.value }
+
+// Hedge returns a [Middleware] sending another attempt of GET and HEAD requests without a body when the previous
+// attempts did not return within the 95th percentile of the latencies observed so far. The first attempt to
+// respond wins and the others are canceled. Until enough latencies were observed requests are not hedged
+// unless a delay is set with [WithHedgeDelay].
+func Hedge(opts ...HedgeOption) Middleware {
+	cfg := hedgeConfig{maxHedges: _defaultMaxHedges, budget: _defaultHedgeBudget}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		h := &hedger{next: next, cfg: cfg, tokens: _defaultHedgeBurst}
+		return RoundTripperFunc(h.roundTrip)
+	}
+}
+
+type hedger struct {
+	next http.RoundTripper
+	cfg  hedgeConfig
+
+	mu        sync.Mutex
+	tokens    float64
+	latencies []time.Duration
+	pos       int
+	observed  int
+	quantile  time.Duration
+}
+
+type attempt struct {
+	n     int
+	res   *http.Response
+	err   error
+	start time.Time
+}
+
+func (h *hedger) roundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead || (r.Body != nil && r.Body != http.NoBody) {
+		return h.next.RoundTrip(r)
+	}
+
+	h.mu.Lock()
+	h.tokens = min(h.tokens+h.cfg.budget, _defaultHedgeBurst)
+	h.mu.Unlock()
+
+	delay := h.delay()
+	if delay <= 0 || h.cfg.maxHedges <= 0 {
+		start := time.Now()
+		res, err := h.next.RoundTrip(r)
+		if err == nil {
+			h.observe(time.Since(start))
+		}
+		return res, err
+	}
+
+	var (
+		results  = make(chan attempt, 1+h.cfg.maxHedges)
+		cancels  []context.CancelFunc
+		inflight = 1
+		hedges   = 0
+		lastErr  error
+	)
+
+	launch := func() {
+		ctx, cancel := context.WithCancel(r.Context())
+		n, start := len(cancels), time.Now()
+		cancels = append(cancels, cancel)
+		go func() {
+			res, err := h.next.RoundTrip(r.Clone(ctx))
+			results <- attempt{n: n, res: res, err: err, start: start}
+		}()
+	}
+
+	launch()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case a := <-results:
+			inflight--
+
+			if a.err != nil {
+				cancels[a.n]()
+				lastErr = a.err
+				if inflight == 0 && (hedges == h.cfg.maxHedges || !h.spend()) {
+					return nil, lastErr
+				}
+				if inflight == 0 {
+					hedges++
+					inflight++
+					launch()
+				}
+				continue
+			}
+
+			h.observe(time.Since(a.start))
+
+			go func() {
+				for range inflight {
+					if l := <-results; l.res != nil {
+						l.res.Body.Close()
+					}
+				}
+			}()
+			for n, cancel := range cancels {
+				if n != a.n {
+					cancel()
+				}
+			}
+
+			a.res.Body = &cancelBody{ReadCloser: a.res.Body, cancel: cancels[a.n]}
+			return a.res, nil
+		case <-timer.C:
+			if hedges < h.cfg.maxHedges && h.spend() {
+				hedges++
+				inflight++
+				launch()
+				timer.Reset(delay)
+			}
+		}
+	}
+}
+
+// delay returns the fixed delay or the observed quantile, zero while too few latencies were observed.
+func (h *hedger) delay() time.Duration {
+	if h.cfg.delay > 0 {
+		return h.cfg.delay
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.quantile
+}
+
+func (h *hedger) spend() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+func (h *hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < _hedgeLatencySamples {
+		h.latencies = append(h.latencies, d)
+	} else {
+		h.latencies[h.pos] = d
+		h.pos = (h.pos + 1) % _hedgeLatencySamples
+	}
+
+	h.observed++
+	if len(h.latencies) < _hedgeMinLatencySamples || h.observed%_hedgeRecomputeEvery != 0 && h.quantile > 0 {
+		return
+	}
+
+	sorted := slices.Sorted(slices.Values(h.latencies))
+	h.quantile = sorted[int(float64(len(sorted)-1)*_defaultHedgeQuantile)]
+}
+
+// cancelBody cancels the context of the winning attempt once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,231 @@
+// Package hfeature provides feature flag evaluation with pluggable, hot reloadable stores.
+package hfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/hio"
+)
+
+const _defaultReloadInterval = 30 * time.Second
+
+// Flag is a feature flag.
+//
+// A disabled flag evaluates to false. Otherwise the first rule matching the subject decides the result,
+// falling back to the percentage rollout when set and to true when not.
+type Flag struct {
+	Key        string   `json:"key"`
+	Enabled    bool     `json:"enabled"`
+	Rules      []Rule   `json:"rules,omitempty"`
+	Percentage *float64 `json:"percentage,omitempty"`
+}
+
+// Rule targets subjects whose attribute has one of the values.
+type Rule struct {
+	Attribute string   `json:"attribute"`
+	Values    []string `json:"values"`
+	Enabled   bool     `json:"enabled"`
+}
+
+// Subject is what a flag is evaluated for, such as a user or tenant.
+type Subject struct {
+	Key        string
+	Attributes map[string]string
+}
+
+// Evaluate returns whether the flag is enabled for the subject.
+// Percentage rollouts are sticky per subject key.
+func (f Flag) Evaluate(s Subject) bool {
+	if !f.Enabled {
+		return false
+	}
+
+	for _, r := range f.Rules {
+		if v, ok := s.Attributes[r.Attribute]; ok && slices.Contains(r.Values, v) {
+			return r.Enabled
+		}
+	}
+
+	if f.Percentage == nil {
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(f.Key + "\x00" + s.Key))
+
+	return float64(h.Sum32()%10000) < *f.Percentage*100
+}
+
+// Store provides flags to an [Engine].
+type Store interface {
+	Flags(ctx context.Context) ([]Flag, error)
+}
+
+// Engine evaluates flags from a snapshot of its [Store] that is refreshed by [Engine.Reload].
+type Engine struct {
+	store    Store
+	interval time.Duration
+	flags    atomic.Pointer[map[string]Flag]
+}
+
+// NewEngine returns a new [Engine] reading flags from the store.
+func NewEngine(store Store, opts ...EngineOption) *Engine {
+	e := &Engine{store: store, interval: _defaultReloadInterval}
+
+	for _, opt := range opts {
+		opt.apply(e)
+	}
+
+	e.flags.Store(&map[string]Flag{})
+
+	return e
+}
+
+// Reload replaces the flag snapshot with the flags currently in the store.
+func (e *Engine) Reload(ctx context.Context) error {
+	flags, err := e.store.Flags(ctx)
+	if err != nil {
+		return fmt.Errorf("reloading flags: %w", err)
+	}
+
+	m := make(map[string]Flag, len(flags))
+	for _, f := range flags {
+		m[f.Key] = f
+	}
+
+	e.flags.Store(&m)
+
+	return nil
+}
+
+// Run reloads the flags on every interval until ctx is canceled.
+// Reload errors keep the previous snapshot and are reported when ctx is canceled.
+func (e *Engine) Run(ctx context.Context) error {
+	err := e.Reload(ctx)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-ticker.C:
+			err = e.Reload(ctx)
+		}
+	}
+}
+
+// Enabled returns whether the flag is enabled for the subject. Unknown flags are disabled.
+func (e *Engine) Enabled(key string, s Subject) bool {
+	f, ok := (*e.flags.Load())[key]
+	return ok && f.Evaluate(s)
+}
+
+// Evaluate returns the result of every flag for the subject.
+func (e *Engine) Evaluate(s Subject) Flags {
+	flags := *e.flags.Load()
+
+	m := make(Flags, len(flags))
+	for k, f := range flags {
+		m[k] = f.Evaluate(s)
+	}
+
+	return m
+}
+
+// Middleware evaluates every flag for the subject returned by fn and stores the results in the request context.
+func (e *Engine) Middleware(fn func(*http.Request) Subject) hio.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), flagsKey{}, e.Evaluate(fn(r)))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Flags holds evaluated flags by key.
+type Flags map[string]bool
+
+type flagsKey struct{}
+
+// FromContext returns the flags evaluated by [Engine.Middleware].
+func FromContext(ctx context.Context) Flags {
+	f, _ := ctx.Value(flagsKey{}).(Flags)
+	return f
+}
+
+// Enabled returns whether the flag evaluated by [Engine.Middleware] is enabled.
+func Enabled(ctx context.Context, key string) bool { return FromContext(ctx)[key] }
+
+// Handler returns an [http.Handler] managing the flags of a [WritableStore] and reloading the engine on change.
+// It serves GET / to list flags, PUT /{key} to create or replace a flag, and DELETE /{key} to remove it.
+func (e *Engine) Handler(store WritableStore) http.Handler {
+	m := http.NewServeMux()
+
+	m.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		flags, err := store.Flags(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		hio.EncodeJSON(w, flags, http.StatusOK)
+	})
+
+	m.HandleFunc("PUT /{key}", func(w http.ResponseWriter, r *http.Request) {
+		var f Flag
+		if err := hio.DecodeJSON(hio.MaxBytesReader(w, r.Body, 1<<20), &f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.Key = r.PathValue("key")
+		if err := store.Put(r.Context(), f); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := e.Reload(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		hio.EncodeJSON(w, f, http.StatusOK)
+	})
+
+	m.HandleFunc("DELETE /{key}", func(w http.ResponseWriter, r *http.Request) {
+		err := store.Delete(r.Context(), r.PathValue("key"))
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err == nil {
+			err = e.Reload(r.Context())
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return m
+}
+
+// EngineOption applies options to an [Engine].
+type EngineOption interface{ apply(*Engine) }
+
+type reloadIntervalOption struct{ value time.Duration }
+
+// WithReloadInterval sets how often [Engine.Run] reloads flags.
+func WithReloadInterval(v time.Duration) EngineOption { return reloadIntervalOption{value: v} }
+
+func (o reloadIntervalOption) apply(e *Engine) {
+	if o.value > 0 {
+		e.interval = o.value
+	}
+}
@@ -0,0 +1,112 @@
+package hfeature
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when deleting a flag that does not exist.
+var ErrNotFound = errors.New("flag not found")
+
+// WritableStore is a [Store] whose flags can be modified.
+type WritableStore interface {
+	Store
+	Put(ctx context.Context, f Flag) error
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore is an in-memory [WritableStore].
+type MemoryStore struct {
+	mu    sync.RWMutex
+	flags map[string]Flag
+}
+
+// NewMemoryStore returns a [MemoryStore] holding the flags.
+func NewMemoryStore(flags ...Flag) *MemoryStore {
+	s := &MemoryStore{flags: make(map[string]Flag, len(flags))}
+	for _, f := range flags {
+		s.flags[f.Key] = f
+	}
+	return s
+}
+
+// Flags returns the stored flags sorted by key.
+func (s *MemoryStore) Flags(ctx context.Context) ([]Flag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.SortedFunc(maps.Values(s.flags), func(a, b Flag) int { return strings.Compare(a.Key, b.Key) }), nil
+}
+
+// Put creates or replaces a flag.
+func (s *MemoryStore) Put(ctx context.Context, f Flag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flags[f.Key] = f
+
+	return nil
+}
+
+// Delete removes a flag.
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.flags[key]; !ok {
+		return ErrNotFound
+	}
+
+	delete(s.flags, key)
+
+	return nil
+}
+
+// FileStore is a [Store] reading a JSON array of flags from a file, which is only
+// parsed again when its modification time changes.
+type FileStore struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	flags   []Flag
+}
+
+// NewFileStore returns a [FileStore] reading the file at path.
+func NewFileStore(path string) *FileStore { return &FileStore{path: path} }
+
+// Flags returns the flags in the file.
+func (s *FileStore) Flags(ctx context.Context) ([]Flag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading flags file: %w", err)
+	}
+
+	if fi.ModTime().Equal(s.modTime) {
+		return s.flags, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading flags file: %w", err)
+	}
+
+	var flags []Flag
+	if err := json.Unmarshal(data, &flags); err != nil {
+		return nil, fmt.Errorf("decoding flags file: %w", err)
+	}
+
+	s.flags, s.modTime = flags, fi.ModTime()
+
+	return flags, nil
+}
@@ -0,0 +1,220 @@
+package hio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/inmem"
+)
+
+const (
+	_apiKeyPrefix           = "hio_"
+	_defaultAPIKeyBodyLimit = 1 << 16
+	_apiKeyLastUsedInterval = 1 * time.Minute
+)
+
+// APIKey is an API key as stored, identified by the SHA-256 hash of the key so that the key itself is only
+// known to its holder. Keys with a zero Quota are not rate limited.
+type APIKey struct {
+	Hash     string    `json:"hash"`
+	Name     string    `json:"name"`
+	Scopes   []string  `json:"scopes,omitempty"`
+	Quota    Quota     `json:"quota,omitzero"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used,omitzero"`
+	Revoked  bool      `json:"revoked,omitzero"`
+}
+
+// ID returns the hash of the key.
+func (k APIKey) ID() string { return k.Hash }
+
+// HasScope reports whether the key was granted the scope.
+func (k APIKey) HasScope(scope string) bool { return slices.Contains(k.Scopes, scope) }
+
+// APIKeyStore stores the keys of [APIKeys], such as an [inmem.Repository].
+type APIKeyStore interface {
+	inmem.Storer[string, APIKey]
+	UpdateWhere(ctx context.Context, pred func(APIKey) bool, mutate func(APIKey) APIKey) (int, error)
+}
+
+// APIKeys authenticates requests with API keys kept hashed in a store, such as an [inmem.Repository].
+type APIKeys struct {
+	store APIKeyStore
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// NewAPIKeys returns [APIKeys] keeping the keys in the store.
+func NewAPIKeys(store APIKeyStore) *APIKeys {
+	return &APIKeys{store: store, buckets: make(map[string]*tokenBucket)}
+}
+
+// Mint creates a key with the name, scopes, and quota, and returns the key to hand out along with what is stored.
+func (ks *APIKeys) Mint(ctx context.Context, name string, scopes []string, q Quota) (string, APIKey, error) {
+	key := _apiKeyPrefix + randomToken()
+	k := APIKey{Hash: hashAPIKey(key), Name: name, Scopes: scopes, Quota: q, Created: time.Now()}
+	if err := ks.store.Save(ctx, &k); err != nil {
+		return "", APIKey{}, fmt.Errorf("saving api key: %w", err)
+	}
+	return key, k, nil
+}
+
+// Revoke revokes the key with the hash, so that requests made with it are rejected.
+func (ks *APIKeys) Revoke(ctx context.Context, hash string) error {
+	n, err := ks.store.UpdateWhere(ctx, func(k APIKey) bool { return k.Hash == hash }, func(k APIKey) APIKey {
+		k.Revoked = true
+		return k
+	})
+	if err != nil {
+		return fmt.Errorf("revoking api key: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("revoking api key: %w", inmem.ErrNotFound)
+	}
+
+	ks.mu.Lock()
+	delete(ks.buckets, hash)
+	ks.mu.Unlock()
+
+	return nil
+}
+
+// Middleware authenticates requests with the key sent as a bearer token or in the X-API-Key header, passing
+// them on with the key, read with [APIKeyFromContext]. Requests without a valid key get a 401 Unauthorized,
+// those whose key lacks one of the scopes a 403 Forbidden, and those over the quota of their key a 429 Too
+// Many Requests with Retry-After. The last use of keys is saved at most once a minute.
+func (ks *APIKeys) Middleware(scopes ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := apiKeyFromRequest(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			k := APIKey{Hash: hashAPIKey(key)}
+			if err := ks.store.Load(r.Context(), &k); err != nil || k.Revoked {
+				if err != nil && !errors.Is(err, inmem.ErrNotFound) {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			for _, scope := range scopes {
+				if !k.HasScope(scope) {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+			}
+
+			now := time.Now()
+			if k.Quota.Burst > 0 {
+				ks.mu.Lock()
+				b, ok := ks.buckets[k.Hash]
+				if !ok {
+					b = &tokenBucket{tokens: float64(k.Quota.Burst), last: now}
+					ks.buckets[k.Hash] = b
+				}
+				allowed, _, _, retry := b.take(k.Quota, now)
+				ks.mu.Unlock()
+
+				if !allowed {
+					w.Header().Set("Retry-After", strconv.Itoa(seconds(retry)))
+					http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+					return
+				}
+			}
+
+			if now.Sub(k.LastUsed) >= _apiKeyLastUsedInterval {
+				k.LastUsed = now
+				ks.store.UpdateWhere(r.Context(), func(v APIKey) bool { return v.Hash == k.Hash }, func(v APIKey) APIKey {
+					v.LastUsed = now
+					return v
+				})
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyKey{}, k)))
+		})
+	}
+}
+
+// Handler returns an [http.Handler] for an admin API serving POST / to mint a key from a JSON body with its
+// name, scopes, and quota, answering with the key once, and DELETE /{hash} to revoke a key.
+func (ks *APIKeys) Handler() http.Handler {
+	m := http.NewServeMux()
+
+	m.HandleFunc("POST /{$}", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Name   string   `json:"name"`
+			Scopes []string `json:"scopes"`
+			Quota  Quota    `json:"quota"`
+		}
+		if err := DecodeJSON(MaxBytesReader(w, r.Body, _defaultAPIKeyBodyLimit), &req); err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		key, k, err := ks.Mint(r.Context(), req.Name, req.Scopes, req.Quota)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		EncodeJSON(w, struct {
+			Key string `json:"key"`
+			APIKey
+		}{Key: key, APIKey: k}, http.StatusCreated)
+	})
+
+	m.HandleFunc("DELETE /{hash}", func(w http.ResponseWriter, r *http.Request) {
+		if err := ks.Revoke(r.Context(), r.PathValue("hash")); err != nil {
+			if errors.Is(err, inmem.ErrNotFound) {
+				http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return m
+}
+
+type apiKeyKey struct{}
+
+// APIKeyFromContext returns the key of a request passed on by [APIKeys.Middleware].
+func APIKeyFromContext(ctx context.Context) (APIKey, bool) {
+	k, ok := ctx.Value(apiKeyKey{}).(APIKey)
+	return k, ok
+}
+
+func apiKeyFromRequest(r *http.Request) (string, bool) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key, true
+	}
+	scheme, key, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || key == "" {
+		return "", false
+	}
+	return key, true
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
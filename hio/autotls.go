@@ -0,0 +1,59 @@
+package hio
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const _defaultAutoTLSCache = "autocert"
+
+type (
+	autoTLSOption      struct{ value []string }
+	autoTLSCacheOption struct{ value string }
+)
+
+// WithAutoTLS obtains and renews certificates for the domains from Let's Encrypt, accepting its terms of service.
+// The HTTP-01 challenges are answered on port 80 of the host, which redirects other requests to HTTPS and shuts
+// down along with the server.
+func WithAutoTLS(domains ...string) ServeOption { return autoTLSOption{value: domains} }
+
+// WithAutoTLSCache sets the directory the certificates of [WithAutoTLS] are cached in, which should persist across
+// restarts to stay within the rate limits of Let's Encrypt. Defaults to "autocert".
+func WithAutoTLSCache(dir string) ServeOption { return autoTLSCacheOption{value: dir} }
+
+func (o autoTLSOption) apply(cfg *ServeConfig)      { cfg.AutoTLSDomains = o.value }
+func (o autoTLSCacheOption) apply(cfg *ServeConfig) { cfg.AutoTLSCache = o.value }
+
+// autoTLS returns the TLS configuration serving the certificates of [WithAutoTLS] and the server answering
+// their challenges.
+func autoTLS(cfg ServeConfig) (*tls.Config, *http.Server) {
+	dir := cfg.AutoTLSCache
+	if dir == "" {
+		dir = _defaultAutoTLSCache
+	}
+
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.AutoTLSDomains...),
+		Cache:      autocert.DirCache(dir),
+	}
+
+	tlsCfg := m.TLSConfig()
+	tlsCfg.MinVersion = tls.VersionTLS12
+
+	challenge := &http.Server{
+		Addr:              net.JoinHostPort(cfg.Host, "80"),
+		Handler:           m.HTTPHandler(nil),
+		IdleTimeout:       cfg.IdleTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ErrorLog:          cfg.ErrorLog,
+	}
+
+	return tlsCfg, challenge
+}
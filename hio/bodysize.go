@@ -0,0 +1,81 @@
+package hio
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+type bodySizeKey struct{}
+
+type bodySize struct {
+	n           atomic.Int64
+	transformed atomic.Bool
+}
+
+// NewBodySizeContext returns a copy of ctx holding a counter [TransformResponse] adds the bytes of the response
+// body written by the handler to, before they are transformed such as by [GzipResponse]. Middleware wrapping it
+// reads the count with [BodySizeFromContext] after the request is served to tell it apart from the bytes sent.
+func NewBodySizeContext(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(bodySizeKey{}).(*bodySize); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, bodySizeKey{}, &bodySize{})
+}
+
+// BodySizeFromContext returns the bytes of the response body written by the handler before being transformed,
+// which is false when the response was not transformed.
+func BodySizeFromContext(ctx context.Context) (int64, bool) {
+	s, ok := ctx.Value(bodySizeKey{}).(*bodySize)
+	if !ok || !s.transformed.Load() {
+		return 0, false
+	}
+	return s.n.Load(), true
+}
+
+func addBodySize(ctx context.Context, n int) {
+	if s, ok := ctx.Value(bodySizeKey{}).(*bodySize); ok {
+		s.transformed.Store(true)
+		s.n.Add(int64(n))
+	}
+}
+
+// GzipResponse returns a [ResponseTransform] compressing bodies with gzip at the level for clients accepting it,
+// unless the handler set a Content-Encoding itself.
+func GzipResponse(level int) ResponseTransform {
+	return func(r *http.Request, _ int, h http.Header, w io.Writer) io.WriteCloser {
+		if h.Get("Content-Encoding") != "" || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			h.Add("Vary", "Accept-Encoding")
+			return nopWriteCloser{w}
+		}
+
+		gz, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return nopWriteCloser{w}
+		}
+		h.Set("Content-Encoding", "gzip")
+		h.Add("Vary", "Accept-Encoding")
+		return gz
+	}
+}
+
+// LimitBody returns a [RequestTransform] failing reads of bodies larger than n bytes with an [http.MaxBytesError],
+// for use after [DecompressRequest] to limit the size bodies take in memory rather than on the wire.
+func LimitBody(n int64) RequestTransform {
+	return func(_ *http.Request, body io.ReadCloser) (io.ReadCloser, error) {
+		return http.MaxBytesReader(nil, body, n), nil
+	}
+}
+
+func acceptsGzip(accept string) bool {
+	for enc := range strings.SplitSeq(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+		}
+	}
+	return false
+}
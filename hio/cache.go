@@ -0,0 +1,152 @@
+package hio
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheControl builds a Cache-Control header value such as
+//
+//	hio.CacheFor(5*time.Minute).Public().StaleWhileRevalidate(30*time.Second)
+//
+// It is applied to a route with [CacheControl.Middleware] or to responses with [Responder.WithCacheControl].
+type CacheControl struct {
+	maxAge, sMaxAge, swr, sie              time.Duration
+	public, private, noCache, noStore      bool
+	immutable, mustRevalidate, noTransform bool
+}
+
+// CacheFor returns a [CacheControl] allowing responses to be cached for d.
+func CacheFor(d time.Duration) CacheControl { return CacheControl{maxAge: d} }
+
+// NoCache returns a [CacheControl] requiring caches to revalidate responses before every use.
+func NoCache() CacheControl { return CacheControl{noCache: true} }
+
+// NoStore returns a [CacheControl] forbidding caches from storing responses.
+func NoStore() CacheControl { return CacheControl{noStore: true} }
+
+// Public allows shared caches to store responses, even to authenticated requests.
+func (c CacheControl) Public() CacheControl {
+	c.public, c.private = true, false
+	return c
+}
+
+// Private restricts storing responses to the cache of the client.
+func (c CacheControl) Private() CacheControl {
+	c.private, c.public = true, false
+	return c
+}
+
+// SharedFor allows shared caches to store responses for d, overriding the duration given to [CacheFor].
+func (c CacheControl) SharedFor(d time.Duration) CacheControl {
+	c.sMaxAge = d
+	return c
+}
+
+// StaleWhileRevalidate allows caches to serve stale responses for d while revalidating them in the background.
+func (c CacheControl) StaleWhileRevalidate(d time.Duration) CacheControl {
+	c.swr = d
+	return c
+}
+
+// StaleIfError allows caches to serve stale responses for d when revalidating them fails.
+func (c CacheControl) StaleIfError(d time.Duration) CacheControl {
+	c.sie = d
+	return c
+}
+
+// Immutable tells caches that responses never change while fresh.
+func (c CacheControl) Immutable() CacheControl {
+	c.immutable = true
+	return c
+}
+
+// MustRevalidate forbids caches from serving stale responses without revalidating them.
+func (c CacheControl) MustRevalidate() CacheControl {
+	c.mustRevalidate = true
+	return c
+}
+
+// NoTransform forbids intermediaries from transforming responses.
+func (c CacheControl) NoTransform() CacheControl {
+	c.noTransform = true
+	return c
+}
+
+// String returns the Cache-Control header value.
+func (c CacheControl) String() string {
+	var d []string
+
+	flag := func(ok bool, name string) {
+		if ok {
+			d = append(d, name)
+		}
+	}
+	age := func(v time.Duration, name string) {
+		if v > 0 {
+			d = append(d, name+"="+strconv.FormatInt(int64(v/time.Second), 10))
+		}
+	}
+
+	flag(c.noStore, "no-store")
+	flag(c.noCache, "no-cache")
+	flag(c.public, "public")
+	flag(c.private, "private")
+	age(c.maxAge, "max-age")
+	age(c.sMaxAge, "s-maxage")
+	age(c.swr, "stale-while-revalidate")
+	age(c.sie, "stale-if-error")
+	flag(c.immutable, "immutable")
+	flag(c.mustRevalidate, "must-revalidate")
+	flag(c.noTransform, "no-transform")
+
+	if len(d) == 0 {
+		return "max-age=0"
+	}
+
+	return strings.Join(d, ", ")
+}
+
+// Middleware sets the Cache-Control header on successful responses of the wrapped handler unless it sets its own.
+func (c CacheControl) Middleware() Middleware {
+	v := c.String()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&cacheWriter{ResponseWriter: w, value: v}, r)
+		})
+	}
+}
+
+type cacheWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func (w *cacheWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if (status >= 200 && status <= 299 || status == http.StatusNotModified) && w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", w.value)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *cacheWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// WithCacheControl returns a copy of the [Responder] whose successful responses carry the Cache-Control header.
+func (rs Responder) WithCacheControl(c CacheControl) Responder {
+	rs.cache = c.String()
+	return rs
+}
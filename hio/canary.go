@@ -0,0 +1,91 @@
+package hio
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+)
+
+// CanaryOption configures [Canary].
+type CanaryOption interface{ apply(*canaryConfig) }
+
+type canaryConfig struct {
+	cookie string
+	header string
+}
+
+type (
+	canaryCookieOption struct{ value string }
+	canaryHeaderOption struct{ value string }
+)
+
+// WithCanaryCookie makes the split sticky with the cookie, which is set with a random value on the first response
+// to clients without it.
+func WithCanaryCookie(name string) CanaryOption { return canaryCookieOption{value: name} }
+
+// WithCanaryHeader makes the split sticky with the header, such as one carrying a user or tenant ID.
+// It takes precedence over the cookie when both are present.
+func WithCanaryHeader(name string) CanaryOption { return canaryHeaderOption{value: name} }
+
+func (o canaryCookieOption) apply(cfg *canaryConfig) { cfg.cookie = o.value }
+func (o canaryHeaderOption) apply(cfg *canaryConfig) { cfg.header = o.value }
+
+// Canary is a middleware sending the percentage of requests, between 0 and 100, to the canary handler, such as a new
+// implementation of the route or an [net/http/httputil.ReverseProxy] to another upstream, and the others to the wrapped one.
+// Requests are assigned by hashing the sticky header or cookie so that a client keeps hitting the same handler,
+// and at random without either.
+func Canary(percent float64, canary http.Handler, opts ...CanaryOption) Middleware {
+	var cfg canaryConfig
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	threshold := uint64(percent * 100)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := cfg.stickyKey(w, r)
+
+			var bucket uint64
+			if key == "" {
+				bucket = rand.Uint64N(10000)
+			} else {
+				h := fnv.New64a()
+				h.Write([]byte(key))
+				bucket = h.Sum64() % 10000
+			}
+
+			if bucket < threshold {
+				canary.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (cfg canaryConfig) stickyKey(w http.ResponseWriter, r *http.Request) string {
+	if cfg.header != "" {
+		if v := r.Header.Get(cfg.header); v != "" {
+			return v
+		}
+	}
+
+	if cfg.cookie == "" {
+		return ""
+	}
+
+	if c, err := r.Cookie(cfg.cookie); err == nil && c.Value != "" {
+		return c.Value
+	}
+
+	var b [16]byte
+	crand.Read(b[:])
+	v := hex.EncodeToString(b[:])
+
+	http.SetCookie(w, &http.Cookie{Name: cfg.cookie, Value: v, Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode})
+
+	return v
+}
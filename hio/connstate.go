@@ -0,0 +1,21 @@
+package hio
+
+import (
+	"expvar"
+	"net"
+	"net/http"
+)
+
+// Connections counts the open connections of the servers by state, "new", "active", or "idle", so that a shutdown
+// can be seen draining them. It is published through expvar as "hio.connections".
+var Connections = expvar.NewMap("hio.connections")
+
+type connStateOption struct {
+	value func(net.Conn, http.ConnState)
+}
+
+// WithConnState calls fn when a connection changes state, after the server tracked it for [Connections] and
+// the [ShutdownReport]. See [http.Server.ConnState].
+func WithConnState(fn func(net.Conn, http.ConnState)) ServeOption { return connStateOption{value: fn} }
+
+func (o connStateOption) apply(cfg *ServeConfig) { cfg.ConnState = o.value }
@@ -0,0 +1,86 @@
+package hio
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentTypes is a middleware rejecting requests whose body has a Content-Type other than those consumed with
+// 415 Unsupported Media Type, and requests whose Accept header allows none of the types produced with 406 Not
+// Acceptable, so that handlers do not misparse bodies or answer in a type the client cannot read. Consumed types
+// may be ranges such as "text/*", and either list may be empty to accept anything. It applies to a route or
+// group by registering it with [Router.Group].
+func ContentTypes(consumes, produces []string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(consumes) > 0 && (r.ContentLength > 0 || len(r.TransferEncoding) > 0) {
+				mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if err != nil || !acceptsType(consumes, mt) {
+					w.Header().Set("Accept", strings.Join(consumes, ", "))
+					http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+					return
+				}
+			}
+
+			if accept := r.Header.Values("Accept"); len(produces) > 0 && len(accept) > 0 {
+				if !acceptable(strings.Join(accept, ","), produces) {
+					http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acceptsType reports whether the media type matches one of the types or ranges.
+func acceptsType(types []string, mt string) bool {
+	for _, t := range types {
+		if mediaRangeMatch(t, mt) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptable reports whether the Accept header allows one of the types, by the most specific range matching it.
+func acceptable(accept string, types []string) bool {
+	for _, t := range types {
+		best, q := -1, 0.0
+		for rng := range strings.SplitSeq(accept, ",") {
+			mr, params, err := mime.ParseMediaType(strings.TrimSpace(rng))
+			if err != nil {
+				continue
+			}
+			if s := mediaRangeMatch(mr, t); s > best {
+				best, q = s, 1
+				if v, ok := params["q"]; ok {
+					q, _ = strconv.ParseFloat(v, 64)
+				}
+			}
+		}
+		if best >= 0 && q > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaRangeMatch returns how specifically the range matches the media type, from 0 for */* to 2 for the type
+// itself, or -1 when it does not match.
+func mediaRangeMatch(rng, mt string) int {
+	rt, rs, _ := strings.Cut(strings.ToLower(rng), "/")
+	mtt, mts, _ := strings.Cut(strings.ToLower(mt), "/")
+	switch {
+	case rt == "*" && rs == "*":
+		return 0
+	case rt == mtt && rs == "*":
+		return 1
+	case rt == mtt && rs == mts:
+		return 2
+	}
+	return -1
+}
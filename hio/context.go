@@ -0,0 +1,31 @@
+package hio
+
+import (
+	"context"
+	"net"
+)
+
+type (
+	baseContextOption struct {
+		value func(net.Listener) context.Context
+	}
+	connContextOption struct {
+		value func(context.Context, net.Conn) context.Context
+	}
+)
+
+// WithBaseContext sets the function returning the base context of the requests of a listener.
+// See [http.Server.BaseContext].
+func WithBaseContext(fn func(net.Listener) context.Context) ServeOption {
+	return baseContextOption{value: fn}
+}
+
+// WithConnContext sets the function deriving the context of the requests of a connection from the base context,
+// such as to carry a connection ID. It is called before the TLS handshake, so peer certificates are read later
+// from the [crypto/tls.Conn] it is given. See [http.Server.ConnContext].
+func WithConnContext(fn func(context.Context, net.Conn) context.Context) ServeOption {
+	return connContextOption{value: fn}
+}
+
+func (o baseContextOption) apply(cfg *ServeConfig) { cfg.BaseContext = o.value }
+func (o connContextOption) apply(cfg *ServeConfig) { cfg.ConnContext = o.value }
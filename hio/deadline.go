@@ -0,0 +1,75 @@
+package hio
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+// RequestTimeout is a middleware applying the timeout sent by trusted callers to the request context, so that
+// timeout budgets propagate across service hops. The timeout is read from the X-Request-Timeout header as a
+// [time.Duration] such as "1.5s", or the grpc-timeout header such as "1500m". It is capped at limit, which also
+// applies to requests without a timeout or from callers whose remote address is not in trustedCallers. A limit
+// of zero means no cap.
+func RequestTimeout(limit time.Duration, trustedCallers []netip.Prefix) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout, ok := limit, limit > 0
+			if addr, parsed := ClientIP(r, nil); parsed && trusted(trustedCallers, addr) {
+				if d, sent := headerTimeout(r.Header); sent && (!ok || d < limit) {
+					timeout, ok = d, true
+				}
+			}
+
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// PropagateTimeout sets the X-Request-Timeout header of an outgoing request to the time left until the deadline
+// of ctx, if any, for [RequestTimeout] to apply it on the other end.
+func PropagateTimeout(ctx context.Context, h http.Header) {
+	if deadline, ok := ctx.Deadline(); ok {
+		h.Set("X-Request-Timeout", max(time.Until(deadline), 0).String())
+	}
+}
+
+func headerTimeout(h http.Header) (time.Duration, bool) {
+	if v := h.Get("X-Request-Timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		return d, err == nil && d >= 0
+	}
+
+	v := h.Get("Grpc-Timeout")
+	if len(v) < 2 || len(v) > 9 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(v[:len(v)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[v[len(v)-1]]
+	if !ok || n > uint64(math.MaxInt64/unit) {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
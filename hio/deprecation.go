@@ -0,0 +1,45 @@
+package hio
+
+import (
+	"expvar"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeprecatedHits counts the requests served by routes marked with [Router.Deprecate] by route.
+// It is published through expvar as "hio.deprecated_hits".
+var DeprecatedHits = expvar.NewMap("hio.deprecated_hits")
+
+// Deprecation describes a deprecated route. Since defaults to the time the route was marked deprecated,
+// Sunset is when the route is removed if not zero, Successor is the URL of its replacement, and Docs is
+// the URL of the documentation of the deprecation.
+type Deprecation struct {
+	Since     time.Time `json:"since"`
+	Sunset    time.Time `json:"sunset,omitzero"`
+	Successor string    `json:"successor,omitempty"`
+	Docs      string    `json:"docs,omitempty"`
+}
+
+// Deprecate marks the route deprecated so that its responses carry the Deprecation, Sunset, and Link headers,
+// its requests are counted in [DeprecatedHits], and its [Route] reports Deprecated to logging middleware.
+func (ro *Router) Deprecate(method, pattern string, d Deprecation) {
+	if d.Since.IsZero() {
+		d.Since = time.Now()
+	}
+	ro.routes.update(ro.route(strings.ToUpper(method), pattern), func(e *routeEntry) { e.deprecation = &d })
+}
+
+func (d Deprecation) apply(h http.Header) {
+	h.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	if !d.Sunset.IsZero() {
+		h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		h.Add("Link", "<"+d.Successor+`>; rel="successor-version"`)
+	}
+	if d.Docs != "" {
+		h.Add("Link", "<"+d.Docs+`>; rel="deprecation"; type="text/html"`)
+	}
+}
@@ -0,0 +1,232 @@
+package hio
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/hretry"
+	"github.com/drakelthedragon/bazaar/inmem"
+)
+
+const (
+	_defaultBreakerThreshold = 5
+	_defaultBreakerCooldown  = 1 * time.Minute
+)
+
+// WebhookEndpoint is a receiver of webhooks sent by a [WebhookDispatcher].
+// It receives the events listed in Events, or every event when empty.
+type WebhookEndpoint struct {
+	Key    string
+	URL    string
+	Secret []byte
+	Events []string
+}
+
+// WebhookDelivery records a single delivery attempt of an event to an endpoint.
+type WebhookDelivery struct {
+	Delivery string        `json:"delivery"`
+	Endpoint string        `json:"endpoint"`
+	Event    string        `json:"event"`
+	Attempt  int           `json:"attempt"`
+	Status   int           `json:"status,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration_ms,format:milli"`
+}
+
+// ID returns the key of the attempt derived from the delivery, endpoint, and attempt number.
+func (d WebhookDelivery) ID() string { return deliveryKey(d.Delivery, d.Endpoint, d.Attempt) }
+
+func deliveryKey(delivery, endpoint string, attempt int) string {
+	return delivery + "/" + endpoint + "/" + strconv.Itoa(attempt)
+}
+
+// WebhookDispatcher signs and delivers events to registered endpoints, retrying failed deliveries and
+// tripping a circuit breaker per endpoint. Every attempt is persisted to its store. Requests are signed
+// with [SignRequest] so receivers can use [ReplayProtection].
+type WebhookDispatcher struct {
+	mu        sync.RWMutex
+	endpoints map[string]*dispatchEndpoint
+	store     inmem.Storer[string, WebhookDelivery]
+	client    *http.Client
+	policy    hretry.Policy
+}
+
+type dispatchEndpoint struct {
+	WebhookEndpoint
+	breaker *hretry.CircuitBreaker
+}
+
+// NewWebhookDispatcher returns a [WebhookDispatcher] persisting attempts to store, sending requests with
+// client, and retrying with policy.
+func NewWebhookDispatcher(store inmem.Storer[string, WebhookDelivery], client *http.Client, policy hretry.Policy) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		endpoints: make(map[string]*dispatchEndpoint),
+		store:     store,
+		client:    client,
+		policy:    policy,
+	}
+}
+
+// Register adds or replaces the endpoint.
+func (d *WebhookDispatcher) Register(ep WebhookEndpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.endpoints[ep.Key] = &dispatchEndpoint{
+		WebhookEndpoint: ep,
+		breaker:         hretry.NewCircuitBreaker(_defaultBreakerThreshold, _defaultBreakerCooldown),
+	}
+}
+
+// Unregister removes the endpoint.
+func (d *WebhookDispatcher) Unregister(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.endpoints, key)
+}
+
+// Dispatch delivers the payload to every endpoint subscribed to the event concurrently and returns
+// the delivery key once all deliveries finished, along with the errors of failed deliveries.
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, event string, payload []byte) (string, error) {
+	var b [16]byte
+	rand.Read(b[:])
+	delivery := hex.EncodeToString(b[:])
+
+	d.mu.RLock()
+	var targets []*dispatchEndpoint
+	for _, ep := range d.endpoints {
+		if len(ep.Events) == 0 || slices.Contains(ep.Events, event) {
+			targets = append(targets, ep)
+		}
+	}
+	d.mu.RUnlock()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for _, ep := range targets {
+		wg.Go(func() {
+			if err := d.deliver(ctx, ep, delivery, event, payload); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("delivering to %s: %w", ep.Key, err))
+				mu.Unlock()
+			}
+		})
+	}
+
+	wg.Wait()
+
+	return delivery, errors.Join(errs...)
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, ep *dispatchEndpoint, delivery, event string, payload []byte) error {
+	attempt := 0
+
+	return hretry.Do(ctx, d.policy, func(ctx context.Context) error {
+		attempt++
+
+		rec := WebhookDelivery{Delivery: delivery, Endpoint: ep.Key, Event: event, Attempt: attempt, Time: time.Now()}
+
+		err := ep.breaker.Execute(func() error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(payload))
+			if err != nil {
+				return hretry.Permanent(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Webhook-Event", event)
+			req.Header.Set("X-Webhook-Delivery", delivery)
+
+			if err := SignRequest(req, ep.Secret, rec.ID(), rec.Time); err != nil {
+				return hretry.Permanent(err)
+			}
+
+			res, err := d.client.Do(req)
+			if err != nil {
+				return err
+			}
+			res.Body.Close()
+
+			rec.Status = res.StatusCode
+			if res.StatusCode < 200 || res.StatusCode > 299 {
+				return errors.New(res.Status)
+			}
+
+			return nil
+		})
+
+		rec.Duration = time.Since(rec.Time)
+		if err != nil {
+			rec.Error = err.Error()
+		}
+
+		if serr := d.store.Save(ctx, &rec); serr != nil {
+			return errors.Join(err, fmt.Errorf("saving attempt: %w", serr))
+		}
+
+		return err
+	})
+}
+
+// Attempts returns the persisted attempts of a delivery to an endpoint in order.
+func (d *WebhookDispatcher) Attempts(ctx context.Context, delivery, endpoint string) []WebhookDelivery {
+	var attempts []WebhookDelivery
+
+	for attempt := 1; ; attempt++ {
+		rec := WebhookDelivery{Delivery: delivery, Endpoint: endpoint, Attempt: attempt}
+		if err := d.store.Load(ctx, &rec); err != nil {
+			return attempts
+		}
+		attempts = append(attempts, rec)
+	}
+}
+
+// Handler returns an [http.Handler] for an admin API serving GET / to list endpoints with their circuit
+// breaker state and GET /{delivery}/{endpoint} to list the attempts of a delivery.
+func (d *WebhookDispatcher) Handler() http.Handler {
+	m := http.NewServeMux()
+
+	m.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		type endpoint struct {
+			Key     string   `json:"key"`
+			URL     string   `json:"url"`
+			Events  []string `json:"events,omitempty"`
+			Breaker string   `json:"breaker"`
+		}
+
+		d.mu.RLock()
+		eps := make([]endpoint, 0, len(d.endpoints))
+		for _, k := range slices.Sorted(maps.Keys(d.endpoints)) {
+			ep := d.endpoints[k]
+			eps = append(eps, endpoint{Key: ep.Key, URL: ep.URL, Events: ep.Events, Breaker: ep.breaker.State().String()})
+		}
+		d.mu.RUnlock()
+
+		EncodeJSON(w, eps, http.StatusOK)
+	})
+
+	m.HandleFunc("GET /{delivery}/{endpoint}", func(w http.ResponseWriter, r *http.Request) {
+		attempts := d.Attempts(r.Context(), r.PathValue("delivery"), r.PathValue("endpoint"))
+		if len(attempts) == 0 {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		EncodeJSON(w, attempts, http.StatusOK)
+	})
+
+	return m
+}
@@ -0,0 +1,106 @@
+package hio
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Doc attaches documentation to the route, rendered by [Router.RoutesHandler].
+func (ro *Router) Doc(method, pattern, doc string) {
+	ro.routes.update(ro.route(strings.ToUpper(method), pattern), func(e *routeEntry) { e.doc = doc })
+}
+
+// RouteInfo describes a registered route as rendered by [Router.RoutesHandler].
+type RouteInfo struct {
+	Method      string       `json:"method"`
+	Pattern     string       `json:"pattern"`
+	Group       string       `json:"group,omitempty"`
+	Middlewares []string     `json:"middlewares,omitempty"`
+	Doc         string       `json:"doc,omitempty"`
+	Schema      bool         `json:"schema,omitzero"`
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
+}
+
+// Routes returns the routes registered on the [Router] and its groups sorted by pattern and method.
+func (ro *Router) Routes() []RouteInfo {
+	var infos []RouteInfo
+
+	for _, e := range ro.routes.all() {
+		if e.route.Method == "" || e.route.Pattern == "" {
+			continue
+		}
+		infos = append(infos, RouteInfo{
+			Method:      e.route.Method,
+			Pattern:     e.route.Pattern,
+			Group:       e.route.Group,
+			Middlewares: e.middlewares,
+			Doc:         e.doc,
+			Schema:      e.schemas != nil,
+			Deprecation: e.deprecation,
+		})
+	}
+
+	slices.SortFunc(infos, func(a, b RouteInfo) int {
+		return cmp.Or(cmp.Compare(a.Pattern, b.Pattern), cmp.Compare(a.Method, b.Method))
+	})
+
+	return infos
+}
+
+// RoutesHandler returns an [http.Handler] rendering the route table with the methods, middlewares, and docs
+// of every route, as JSON when requested with an Accept header naming application/json and as a text table
+// otherwise. It is meant to be mounted on an admin server, such as at "/_routes".
+func (ro *Router) RoutesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routes := ro.Routes()
+
+		if strings.Contains(r.Header.Get("Accept"), "application/json") {
+			EncodeJSON(w, routes, http.StatusOK)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "METHOD\tPATTERN\tMIDDLEWARES\tDOC")
+		for _, rt := range routes {
+			doc := rt.Doc
+			if rt.Deprecation != nil {
+				doc = strings.TrimSpace("(deprecated) " + doc)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", rt.Method, rt.Pattern, strings.Join(rt.Middlewares, ", "), doc)
+		}
+		tw.Flush()
+	})
+}
+
+// middlewareNames names the middlewares after the functions that created them, such as "hio.TenantQuota".
+func middlewareNames(mws []Middleware) []string {
+	names := make([]string, 0, len(mws))
+
+	for _, mw := range mws {
+		name := "unknown"
+		if fn := runtime.FuncForPC(reflect.ValueOf(mw).Pointer()); fn != nil {
+			name = fn.Name()
+		}
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		for {
+			i := strings.LastIndex(name, ".func")
+			if i < 0 {
+				break
+			}
+			name = name[:i]
+		}
+		names = append(names, name)
+	}
+
+	return names
+}
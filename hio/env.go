@@ -0,0 +1,48 @@
+package hio
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/drakelthedragon/bazaar/hcfg"
+)
+
+// ServeConfigFromEnv returns the [DefaultServeConfig] overridden by the environment variables named after the cfg
+// tags of [ServeConfig] with the prefix, such as APP_PORT and APP_READ_TIMEOUT for the prefix APP, so that
+// services run in containers are configured without flags. TLS is configured from the files named by
+// APP_TLS_CERT_FILE and APP_TLS_KEY_FILE, verifying client certificates against APP_TLS_CA_FILE when it is set.
+func ServeConfigFromEnv(prefix string) (ServeConfig, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+
+	var files struct {
+		CAFile   string `cfg:"tls.ca_file"`
+		CertFile string `cfg:"tls.cert_file"`
+		KeyFile  string `cfg:"tls.key_file"`
+	}
+	if err := hcfg.Load(&files, hcfg.WithEnvPrefix(prefix)); err != nil {
+		return ServeConfig{}, err
+	}
+
+	cfg := DefaultServeConfig()
+	if err := hcfg.Load(&cfg, hcfg.WithEnvPrefix(prefix)); err != nil {
+		return ServeConfig{}, err
+	}
+
+	switch {
+	case files.CertFile == "" && files.KeyFile == "" && files.CAFile == "":
+	case files.CertFile == "" || files.KeyFile == "":
+		return ServeConfig{}, errors.New("loading tls config: cert and key files must both be set")
+	case files.CAFile != "":
+		WithTLS(files.CAFile, files.CertFile, files.KeyFile).apply(&cfg)
+	default:
+		WithServerTLS(files.CertFile, files.KeyFile).apply(&cfg)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return ServeConfig{}, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,87 @@
+package hio
+
+import (
+	"maps"
+	"net/http"
+)
+
+// RequestIDHeader is the header read for the request ID of enveloped responses.
+const RequestIDHeader = "X-Request-Id"
+
+// Envelope configures the envelope wrapping responses written with [Responder.JSON] as
+// {"data": ..., "error": null, "meta": {...}}. Responses with an error status code carry the
+// payload in error instead of data. The meta object holds the request ID, the [Page] given to
+// [Responder.WithPage], and the entries returned by Meta.
+type Envelope struct {
+	// RequestID returns the ID of the request, defaulting to the X-Request-Id header of the response or request.
+	RequestID func(http.ResponseWriter, *http.Request) string
+	// Meta returns extra entries of the meta object, it may be nil.
+	Meta func(*http.Request) map[string]any
+}
+
+// Page is the pagination metadata of an enveloped response.
+type Page struct {
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Limit int    `json:"limit,omitzero"`
+	Total *int   `json:"total,omitempty"`
+}
+
+type envelope struct {
+	Data  any            `json:"data"`
+	Error any            `json:"error"`
+	Meta  map[string]any `json:"meta,omitempty"`
+}
+
+// WithEnvelope returns a copy of the [Responder] wrapping JSON responses in the envelope.
+func (rs Responder) WithEnvelope(e Envelope) Responder {
+	if e.RequestID == nil {
+		e.RequestID = requestID
+	}
+	rs.envelope = &e
+	return rs
+}
+
+// WithPage returns a copy of the [Responder] adding the pagination metadata to enveloped responses.
+func (rs Responder) WithPage(p Page) Responder {
+	rs.page = &p
+	return rs
+}
+
+// Envelope wraps the JSON responses of the routes registered on the [Router] afterwards, including
+// those of groups created afterwards, in the envelope. Groups created before keep their own setting.
+func (ro *Router) Envelope(e Envelope) { ro.r = ro.r.WithEnvelope(e) }
+
+func (rs Responder) wrap(w http.ResponseWriter, r *http.Request, code int, from any) any {
+	if rs.envelope == nil {
+		return from
+	}
+
+	env := envelope{Data: from}
+	if code >= 400 {
+		env = envelope{Error: from}
+	}
+
+	meta := make(map[string]any)
+	if rs.envelope.Meta != nil {
+		maps.Copy(meta, rs.envelope.Meta(r))
+	}
+	if id := rs.envelope.RequestID(w, r); id != "" {
+		meta["request_id"] = id
+	}
+	if rs.page != nil {
+		meta["page"] = rs.page
+	}
+	if len(meta) > 0 {
+		env.Meta = meta
+	}
+
+	return env
+}
+
+func requestID(w http.ResponseWriter, r *http.Request) string {
+	if id := w.Header().Get(RequestIDHeader); id != "" {
+		return id
+	}
+	return r.Header.Get(RequestIDHeader)
+}
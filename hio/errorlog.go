@@ -0,0 +1,54 @@
+package hio
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// ServerErrors counts the errors logged by servers started with [WithErrorLogger] by kind.
+// It is published through expvar as "hio.server_errors".
+var ServerErrors = expvar.NewMap("hio.server_errors")
+
+// WithErrorLogger routes the errors the [http.Server] logs outside of handlers, such as failed TLS handshakes,
+// accept errors, and recovered panics, to l as structured records and counts them in [ServerErrors].
+func WithErrorLogger(l *slog.Logger) ServeOption {
+	return errorLogOption{value: log.New(serverErrorWriter{l: l}, "", 0)}
+}
+
+type errorLogOption struct{ value *log.Logger }
+
+func (o errorLogOption) apply(cfg *ServeConfig) { cfg.ErrorLog = o.value }
+
+type serverErrorWriter struct{ l *slog.Logger }
+
+func (w serverErrorWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+	kind, attrs := classifyServerError(msg)
+
+	ServerErrors.Add(kind, 1)
+
+	w.l.LogAttrs(context.Background(), slog.LevelWarn, "server error", append(attrs, slog.String("kind", kind), slog.String("error", msg))...)
+
+	return len(p), nil
+}
+
+func classifyServerError(msg string) (string, []slog.Attr) {
+	switch {
+	case strings.HasPrefix(msg, "http: TLS handshake error from "):
+		addr, _, _ := strings.Cut(strings.TrimPrefix(msg, "http: TLS handshake error from "), ": ")
+		return "tls_handshake", []slog.Attr{slog.String("remote_addr", addr)}
+	case strings.HasPrefix(msg, "http: panic serving "):
+		addr, _, _ := strings.Cut(strings.TrimPrefix(msg, "http: panic serving "), ": ")
+		return "panic", []slog.Attr{slog.String("remote_addr", addr)}
+	case strings.HasPrefix(msg, "http: Accept error"):
+		return "accept", nil
+	case strings.HasPrefix(msg, "http: superfluous response.WriteHeader"):
+		return "superfluous_write_header", nil
+	case strings.HasPrefix(msg, "http2:"):
+		return "http2", nil
+	}
+	return "other", nil
+}
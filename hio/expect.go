@@ -0,0 +1,43 @@
+package hio
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ExpectContinue is a middleware rejecting requests before their body is sent. Clients sending Expect: 100-continue
+// wait for the [http.Server] to answer 100 Continue, which it does once the body is first read, so rejecting the
+// request before reading spares them from uploading a body that would be discarded. Requests declaring a
+// Content-Length above maxBytes are rejected with 413 Request Entity Too Large and longer bodies are cut at
+// maxBytes, and requests for which authorize, when not nil, returns an error are rejected with 401 Unauthorized.
+func ExpectContinue(maxBytes int64, authorize func(*http.Request) error) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			expect := strings.EqualFold(r.Header.Get("Expect"), "100-continue")
+
+			if maxBytes > 0 && r.ContentLength > maxBytes {
+				if expect {
+					w.Header().Set("Connection", "close")
+				}
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			if authorize != nil {
+				if err := authorize(r); err != nil {
+					if expect {
+						w.Header().Set("Connection", "close")
+					}
+					http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+					return
+				}
+			}
+
+			if maxBytes > 0 {
+				r.Body = MaxBytesReader(w, r.Body, maxBytes)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
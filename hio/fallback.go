@@ -0,0 +1,81 @@
+package hio
+
+import (
+	"maps"
+	"net/http"
+)
+
+// Fallback returns an [http.Handler] trying the handlers in order until one responds with another status than
+// 404 Not Found, such as to serve a static site under the same prefix as an API. The headers and body of the 404
+// responses of all but the last handler are discarded. Handlers after the first may read the request body only
+// if those before them did not.
+func Fallback(hs ...http.Handler) http.Handler {
+	if len(hs) == 0 {
+		return http.NotFoundHandler()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, h := range hs[:len(hs)-1] {
+			fw := &fallbackWriter{ResponseWriter: w, header: w.Header().Clone()}
+			h.ServeHTTP(fw, r)
+			if !fw.notFound {
+				if !fw.wroteHeader {
+					fw.WriteHeader(http.StatusOK)
+				}
+				return
+			}
+		}
+		hs[len(hs)-1].ServeHTTP(w, r)
+	})
+}
+
+// Fallback sets handlers tried in order, as with [Fallback], when no route matches the request, before responding
+// with the NotFound error of the [Router]. Routes responding with 404 Not Found themselves are not passed on.
+func (ro *Router) Fallback(hs ...http.Handler) { ro.fallbacks = hs }
+
+// fallbackWriter holds the headers written by a handler until it writes the status code, which is passed on
+// unless it is 404 Not Found, for the next handler to be tried instead.
+type fallbackWriter struct {
+	http.ResponseWriter
+	header      http.Header
+	wroteHeader bool
+	notFound    bool
+}
+
+func (fw *fallbackWriter) Header() http.Header { return fw.header }
+
+func (fw *fallbackWriter) WriteHeader(status int) {
+	if fw.wroteHeader {
+		return
+	}
+	fw.wroteHeader = true
+
+	if status == http.StatusNotFound {
+		fw.notFound = true
+		return
+	}
+	h := fw.ResponseWriter.Header()
+	clear(h)
+	maps.Copy(h, fw.header)
+	fw.ResponseWriter.WriteHeader(status)
+}
+
+func (fw *fallbackWriter) Write(p []byte) (int, error) {
+	if !fw.wroteHeader {
+		fw.WriteHeader(http.StatusOK)
+	}
+	if fw.notFound {
+		return len(p), nil
+	}
+	return fw.ResponseWriter.Write(p)
+}
+
+func (fw *fallbackWriter) Flush() {
+	if !fw.wroteHeader {
+		fw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := fw.ResponseWriter.(http.Flusher); ok && !fw.notFound {
+		f.Flush()
+	}
+}
+
+func (fw *fallbackWriter) Unwrap() http.ResponseWriter { return fw.ResponseWriter }
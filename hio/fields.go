@@ -0,0 +1,143 @@
+package hio
+
+import (
+	"bytes"
+	"encoding/json/jsontext"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// FieldFilter returns a [ResponseTransform] pruning successful JSON responses to the comma separated fields
+// listed in the query parameter, such as ?fields=id,name,owner.email, for use with [TransformResponse].
+// Responses to requests without the parameter are passed through unchanged.
+func FieldFilter(param string) ResponseTransform {
+	return func(r *http.Request, status int, h http.Header, w io.Writer) io.WriteCloser {
+		fields := r.URL.Query().Get(param)
+		mt, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+		if fields == "" || status < 200 || status > 299 || (mt != "application/json" && !strings.HasSuffix(mt, "+json")) {
+			return nopWriteCloser{w}
+		}
+		return BufferResponse(func(_ *http.Request, _ int, _ http.Header, body []byte) []byte {
+			if pruned, err := FilterFields(body, fields); err == nil {
+				return pruned
+			}
+			return body
+		})(r, status, h, w)
+	}
+}
+
+// FilterFields prunes the JSON document to the comma separated fields. Nested fields are selected with dots,
+// and fields of arrays apply to each of their elements. The order of the remaining members is preserved.
+func FilterFields(data []byte, fields string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	dec := jsontext.NewDecoder(bytes.NewReader(data))
+	enc := jsontext.NewEncoder(&buf)
+
+	if err := pruneJSON(dec, enc, parseFields(fields)); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// fieldTree selects the members of an object, a nil subtree selects the whole member.
+type fieldTree map[string]fieldTree
+
+func parseFields(fields string) fieldTree {
+	root := fieldTree{}
+
+	for path := range strings.SplitSeq(fields, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		node := root
+		names := strings.Split(path, ".")
+		for i, name := range names {
+			sub, ok := node[name]
+			if ok && sub == nil {
+				break
+			}
+			if i == len(names)-1 {
+				node[name] = nil
+				break
+			}
+			if !ok {
+				sub = fieldTree{}
+				node[name] = sub
+			}
+			node = sub
+		}
+	}
+
+	return root
+}
+
+func pruneJSON(dec *jsontext.Decoder, enc *jsontext.Encoder, t fieldTree) error {
+	switch dec.PeekKind() {
+	case '{':
+		if err := copyToken(dec, enc); err != nil {
+			return err
+		}
+		for dec.PeekKind() != '}' {
+			name, err := dec.ReadToken()
+			if err != nil {
+				return err
+			}
+			sub, ok := t[name.String()]
+			if !ok {
+				if err := dec.SkipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := enc.WriteToken(name); err != nil {
+				return err
+			}
+			if sub == nil {
+				err = copyValue(dec, enc)
+			} else {
+				err = pruneJSON(dec, enc, sub)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return copyToken(dec, enc)
+	case '[':
+		if err := copyToken(dec, enc); err != nil {
+			return err
+		}
+		for dec.PeekKind() != ']' {
+			if err := pruneJSON(dec, enc, t); err != nil {
+				return err
+			}
+		}
+		return copyToken(dec, enc)
+	}
+	return copyValue(dec, enc)
+}
+
+func copyToken(dec *jsontext.Decoder, enc *jsontext.Encoder) error {
+	tok, err := dec.ReadToken()
+	if err != nil {
+		return err
+	}
+	return enc.WriteToken(tok)
+}
+
+func copyValue(dec *jsontext.Decoder, enc *jsontext.Encoder) error {
+	v, err := dec.ReadValue()
+	if err != nil {
+		return err
+	}
+	return enc.WriteValue(v)
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
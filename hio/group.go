@@ -0,0 +1,41 @@
+package hio
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ServeGroup runs several servers together, such as the application on one port and an admin handler on another,
+// shutting them all down once the context is done, a signal is received, or one of them fails.
+type ServeGroup struct {
+	opts    []ServeOption
+	servers []groupServer
+}
+
+type groupServer struct {
+	h    http.Handler
+	opts []ServeOption
+}
+
+// NewServeGroup returns an empty [ServeGroup] whose servers share the options, such as [WithShutdownTimeout].
+func NewServeGroup(opts ...ServeOption) *ServeGroup { return &ServeGroup{opts: opts} }
+
+// Add adds a server of h configured with the shared options followed by opts, such as [WithPort].
+func (g *ServeGroup) Add(h http.Handler, opts ...ServeOption) *ServeGroup {
+	g.servers = append(g.servers, groupServer{h: h, opts: opts})
+	return g
+}
+
+// Serve runs the servers with [Serve] until all of them returned, and returns the first error.
+func (g *ServeGroup) Serve(ctx context.Context) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	for _, s := range g.servers {
+		opts := append([]ServeOption{WithOptions(g.opts...)}, s.opts...)
+		eg.Go(func() error { return Serve(egCtx, s.h, opts...) })
+	}
+
+	return eg.Wait()
+}
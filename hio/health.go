@@ -0,0 +1,88 @@
+package hio
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+const (
+	_defaultLivenessPath  = "/healthz"
+	_defaultReadinessPath = "/readyz"
+)
+
+// HealthChecks are the checks served by [WithHealthChecks]. A nil check always passes.
+type HealthChecks struct {
+	Live  func(context.Context) error
+	Ready func(context.Context) error
+}
+
+type (
+	healthChecksOption struct{ value *HealthChecks }
+	healthPathsOption  struct{ live, ready string }
+)
+
+// WithHealthChecks serves the liveness and readiness checks on /healthz and /readyz, answering 503 Service
+// Unavailable with the error of a failing check. Readiness fails as soon as the server starts shutting down,
+// including during the [WithDrainDelay], so that load balancers stop sending traffic before requests drain.
+func WithHealthChecks(live, ready func(context.Context) error) ServeOption {
+	return healthChecksOption{value: &HealthChecks{Live: live, Ready: ready}}
+}
+
+// WithHealthPaths sets the paths of [WithHealthChecks]. Defaults to /healthz and /readyz.
+func WithHealthPaths(live, ready string) ServeOption {
+	return healthPathsOption{live: live, ready: ready}
+}
+
+func (o healthChecksOption) apply(cfg *ServeConfig) { cfg.HealthChecks = o.value }
+func (o healthPathsOption) apply(cfg *ServeConfig) {
+	cfg.LivenessPath, cfg.ReadinessPath = o.live, o.ready
+}
+
+// health serves the [HealthChecks] in front of a handler.
+type health struct {
+	checks      HealthChecks
+	live, ready string
+	draining    atomic.Bool
+}
+
+func newHealth(cfg ServeConfig) *health {
+	hc := &health{checks: *cfg.HealthChecks, live: cfg.LivenessPath, ready: cfg.ReadinessPath}
+	if hc.live == "" {
+		hc.live = _defaultLivenessPath
+	}
+	if hc.ready == "" {
+		hc.ready = _defaultReadinessPath
+	}
+	return hc
+}
+
+func (hc *health) handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case hc.live:
+			hc.serve(w, r, hc.checks.Live)
+		case hc.ready:
+			if hc.draining.Load() {
+				http.Error(w, "shutting down", http.StatusServiceUnavailable)
+				return
+			}
+			hc.serve(w, r, hc.checks.Ready)
+		default:
+			h.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (hc *health) serve(w http.ResponseWriter, r *http.Request, check func(context.Context) error) {
+	if check != nil {
+		if err := check(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte("ok\n"))
+}
@@ -3,12 +3,19 @@ package hio
 
 import (
 	"encoding/json/v2"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"slices"
 	"strings"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/hval"
 )
 
 // Handler is a chainable [http.Handler] implementation.
@@ -21,41 +28,108 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Then returns a [Handler] running the handlers in order while they return nil, so that steps such as
+// an authorization check, a fetch, and a render compose into one handler. A step returning a [Handler],
+// such as an error response, short-circuits the remaining steps to continue with it instead.
+func Then(hs ...Handler) Handler {
+	return func(w http.ResponseWriter, r *http.Request) Handler {
+		for _, h := range hs {
+			if next := h(w, r); next != nil {
+				return next
+			}
+		}
+		return nil
+	}
+}
+
+// If returns a [Handler] continuing with a when pred reports true for the request and with b otherwise.
+// Either may be nil to do nothing.
+func If(pred func(*http.Request) bool, a, b Handler) Handler {
+	return func(w http.ResponseWriter, r *http.Request) Handler {
+		if pred(r) {
+			return a
+		}
+		return b
+	}
+}
+
+// FromHTTP adapts an [http.Handler] to a [Handler] ending the chain once it served the request.
+func FromHTTP(h http.Handler) Handler {
+	return func(w http.ResponseWriter, r *http.Request) Handler {
+		h.ServeHTTP(w, r)
+		return nil
+	}
+}
+
 // Middleware is an alias for a function that takes and returns an [http.Handler].
 type Middleware = func(http.Handler) http.Handler
 
 // Router adapts [http.ServeMux] to use [Handler] with an error logging [Responder].
 type Router struct {
 	m                *http.ServeMux
+	routes           *routeTable
 	r                Responder
 	prefix           string
 	mws              []Middleware
+	fallbacks        []http.Handler
 	NotFound         error
 	MethodNotAllowed error
+
+	// PreflightMaxAge is how long browsers cache the answers to CORS preflight requests, 10 minutes if zero.
+	// A negative duration leaves Access-Control-Max-Age unset.
+	PreflightMaxAge time.Duration
 }
 
 // NewRouter returns a new [Router] that logs errors using the provided logger and function.
 func NewRouter(l *slog.Logger, fn func(http.ResponseWriter, *http.Request, *slog.Logger, error)) *Router {
 	return &Router{
-		m: http.NewServeMux(),
-		r: NewErrorLoggingResponder(l, fn),
+		m:      http.NewServeMux(),
+		routes: &routeTable{m: make(map[string]*routeEntry)},
+		r:      NewErrorLoggingResponder(l, fn),
 	}
 }
 
 // ServeHTTP dispatches the request to the handler whose pattern most closely matches the request URL.
+// OPTIONS requests to a path without an OPTIONS route are answered with 204 No Content and an Allow header
+// listing the methods of the routes matching the path, and CORS preflight requests additionally with the
+// Access-Control-Allow-Methods and Access-Control-Max-Age headers, see [Router.PreflightMaxAge].
 func (ro *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if h, p := ro.m.Handler(r); p == "" {
+		if r.Method == http.MethodOptions {
+			if methods := ro.allowedMethods(r); methods != nil {
+				ro.wrap(ro.preflight(methods)).ServeHTTP(w, r)
+				return
+			}
+		}
 		si := &statusInterceptor{ResponseWriter: w}
 		h.ServeHTTP(si, r)
 		switch si.status {
 		case http.StatusNotFound:
 			h = ro.r.Errorf("The requested path was %w", ro.NotFound)
 		case http.StatusMethodNotAllowed:
+			if allow, ok := si.header["Allow"]; ok {
+				w.Header()["Allow"] = allow
+			}
 			h = ro.r.Errorf("The requested method was %w", ro.MethodNotAllowed)
 		}
-		ro.wrap(h).ServeHTTP(w, r)
+		h = ro.wrap(h)
+		if si.status == http.StatusNotFound && len(ro.fallbacks) > 0 {
+			h = Fallback(append(slices.Clip(ro.fallbacks), h)...)
+		}
+		h.ServeHTTP(w, r)
+		return
+	} else if e, ok := ro.routes.get(p); ok {
+		route := e.route
+		if e.deprecation != nil {
+			route.Deprecated = true
+			e.deprecation.apply(w.Header())
+			DeprecatedHits.Add(route.String(), 1)
+		}
+		r = setRoute(r, route)
+		serveLabeled(w, r, route, ro.m)
 		return
 	}
+
 	ro.m.ServeHTTP(w, r)
 }
 
@@ -93,6 +167,7 @@ func (ro *Router) Patch(pattern string, handler func(Responder) Handler) {
 func (ro *Router) Group(prefix string, mws ...Middleware) *Router {
 	r := &Router{
 		m:      ro.m,
+		routes: ro.routes,
 		r:      ro.r,
 		prefix: ro.prefix + "/" + strings.Trim(prefix, "/"),
 		mws:    make([]Middleware, len(ro.mws), len(ro.mws)+len(mws)),
@@ -117,12 +192,33 @@ func (ro *Router) wrap(h http.Handler) http.Handler {
 	return h
 }
 
+func (ro *Router) route(method, pattern string) Route {
+	return Route{
+		Method:  method,
+		Pattern: strings.TrimRight(ro.prefix+"/"+strings.Trim(pattern, "/"), "/"),
+		Group:   ro.prefix,
+	}
+}
+
 func (ro *Router) handle(method, pattern string, handler func(Responder) Handler) {
-	ro.m.Handle(method+" "+strings.TrimRight(ro.prefix+"/"+strings.Trim(pattern, "/"), "/"), ro.wrap(handler(ro.r)))
+	route := ro.route(method, pattern)
+	ro.m.Handle(route.String(), ro.wrap(ro.r.Recover(handler(ro.r))))
+	ro.routes.update(route, func(e *routeEntry) {
+		e.route = route
+		e.middlewares = middlewareNames(ro.mws)
+	})
 }
 
 // Responder provides helpers to write HTTP responses.
-type Responder struct{ err func(error) Handler }
+type Responder struct {
+	err      func(error) Handler
+	writeErr func(*http.Request, error)
+	panic    func(*PanicError) Handler
+	etag     string
+	cache    string
+	envelope *Envelope
+	page     *Page
+}
 
 // NewResponder returns a new [Responder].
 // err is called when an error occurs during response writing.
@@ -137,11 +233,37 @@ func NewErrorLoggingResponder(l *slog.Logger, fn func(http.ResponseWriter, *http
 				return nil
 			}
 		},
+		writeErr: func(r *http.Request, err error) {
+			l.LogAttrs(r.Context(), slog.LevelWarn, "writing response", slog.Any("error", err))
+		},
+	}
+}
+
+// OnWriteError returns a copy of the [Responder] calling fn when writing a response body fails after
+// the status code was sent, which can no longer be reported to the client.
+func (rs Responder) OnWriteError(fn func(*http.Request, error)) Responder {
+	rs.writeErr = fn
+	return rs
+}
+
+func (rs Responder) write(w http.ResponseWriter, r *http.Request, data []byte) {
+	if _, err := w.Write(data); err != nil && rs.writeErr != nil {
+		rs.writeErr(r, err)
 	}
 }
 
-// Error responds with a error message.
-func (rs Responder) Error(err error) Handler { return rs.err(err) }
+// Error responds with a error message, or with 422 Unprocessable Entity listing the failing fields when err
+// wraps [hval.Errors], such as the error of [DecodeJSON] for a value validated with hval.
+func (rs Responder) Error(err error) Handler {
+	var fields hval.Errors
+	if errors.As(err, &fields) {
+		return rs.JSON(http.StatusUnprocessableEntity, struct {
+			Error  string      `json:"error"`
+			Errors hval.Errors `json:"errors"`
+		}{Error: err.Error(), Errors: fields})
+	}
+	return rs.err(err)
+}
 
 // Errorf responds with a formatted error message.
 func (rs Responder) Errorf(format string, args ...any) Handler {
@@ -156,31 +278,130 @@ func (rs Responder) Redirect(code int, url string) Handler {
 	}
 }
 
-// Text writes a text response with the status code.
+// WithETag returns a copy of the [Responder] whose successful responses carry the entity tag,
+// responding with 304 Not Modified instead when it matches the If-None-Match request header.
+// Unquoted tags are quoted, pass a tag such as W/"v1" for weak validation.
+func (rs Responder) WithETag(etag string) Responder {
+	if !strings.HasSuffix(etag, `"`) {
+		etag = `"` + etag + `"`
+	}
+	rs.etag = etag
+	return rs
+}
+
+// Text writes a text response with the status code. The body is omitted for HEAD requests.
 func (rs Responder) Text(code int, message string) Handler {
 	return func(w http.ResponseWriter, r *http.Request) Handler {
+		if rs.notModified(w, r, code) {
+			return nil
+		}
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(code)
-		fmt.Fprint(w, message)
+		if r.Method != http.MethodHead {
+			rs.write(w, r, []byte(message))
+		}
 		return nil
 	}
 }
 
-// JSON writes a JSON response with the status code.
+// JSON writes a JSON response with the status code. The value is only encoded once the response
+// is known to need a body, so HEAD requests and 304 Not Modified responses skip encoding.
+// It is wrapped in an envelope when one is set with [Responder.WithEnvelope].
 func (rs Responder) JSON(code int, from any) Handler {
-	data, err := json.Marshal(from)
-	if err != nil {
-		return rs.Errorf("encoding JSON: %w", err)
-	}
 	return func(w http.ResponseWriter, r *http.Request) Handler {
+		if rs.notModified(w, r, code) {
+			return nil
+		}
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			return nil
+		}
+		data, err := json.Marshal(rs.wrap(w, r, code, from))
+		if err != nil {
+			return rs.Errorf("encoding JSON: %w", err)
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(code)
-		w.Write(data)
+		rs.write(w, r, data)
+		return nil
+	}
+}
+
+// Multipart streams a multipart/mixed response with the status code, such as JSON metadata along with a binary
+// attachment, calling fn to write the parts. The final boundary is only written when fn succeeds so that
+// clients detect truncated responses, and errors are reported to the write error function since the status
+// code was already sent. The body is omitted for HEAD requests.
+func (rs Responder) Multipart(code int, fn func(*multipart.Writer) error) Handler {
+	return rs.multipart(code, "mixed", nil, fn)
+}
+
+// MultipartRelated streams a multipart/related response like [Responder.Multipart] whose first part has the root type.
+func (rs Responder) MultipartRelated(code int, rootType string, fn func(*multipart.Writer) error) Handler {
+	return rs.multipart(code, "related", map[string]string{"type": rootType}, fn)
+}
+
+func (rs Responder) multipart(code int, subtype string, params map[string]string, fn func(*multipart.Writer) error) Handler {
+	return func(w http.ResponseWriter, r *http.Request) Handler {
+		if rs.notModified(w, r, code) {
+			return nil
+		}
+		mw := multipart.NewWriter(w)
+		params = maps.Clone(params)
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params["boundary"] = mw.Boundary()
+		w.Header().Set("Content-Type", mime.FormatMediaType("multipart/"+subtype, params))
+		w.WriteHeader(code)
+		if r.Method == http.MethodHead {
+			return nil
+		}
+		err := fn(mw)
+		if err == nil {
+			err = mw.Close()
+		}
+		if err != nil && rs.writeErr != nil {
+			rs.writeErr(r, err)
+		}
 		return nil
 	}
 }
 
-// DecodeJSON reads and decodes JSON.
+func (rs Responder) notModified(w http.ResponseWriter, r *http.Request, code int) bool {
+	if code < 200 || code > 299 {
+		return false
+	}
+	if rs.cache != "" {
+		w.Header().Set("Cache-Control", rs.cache)
+	}
+	if rs.etag == "" {
+		return false
+	}
+	w.Header().Set("ETag", rs.etag)
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if !etagMatch(r.Header.Get("If-None-Match"), rs.etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+func etagMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeJSON reads and decodes JSON, then validates the value with its Validate() error method when it has one.
+// Validation errors wrapping [hval.Errors] are answered with 422 Unprocessable Entity by [Responder.Error].
 func DecodeJSON(from io.Reader, to any) error {
 	if err := json.UnmarshalRead(from, to); err != nil {
 		return fmt.Errorf("unmarshaling json: %w", err)
@@ -195,12 +416,28 @@ func DecodeJSON(from io.Reader, to any) error {
 }
 
 // EncodeJSON writes JSON to the [http.ResponseWriter] with the status code.
+// Encoding errors occur after the status code is sent, use [EncodeJSONBuffered] to report them before.
 func EncodeJSON(to http.ResponseWriter, from any, status int) error {
 	to.Header().Set("Content-Type", "application/json")
 	to.WriteHeader(status)
 	return json.MarshalWrite(to, from)
 }
 
+// EncodeJSONBuffered encodes JSON before writing anything so that encoding errors are returned
+// while the caller can still respond with an error instead.
+func EncodeJSONBuffered(to http.ResponseWriter, from any, status int) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return fmt.Errorf("encoding JSON: %w", err)
+	}
+	to.Header().Set("Content-Type", "application/json")
+	to.WriteHeader(status)
+	if _, err := to.Write(data); err != nil {
+		return fmt.Errorf("writing JSON: %w", err)
+	}
+	return nil
+}
+
 // MaxBytesReader wraps [http.MaxBytesReader] to ensure the original [http.ResponseWriter] is unwrapped
 // so that it can instruct the [http.Server] to disconnect clients when they reach the max bytes limit.
 func MaxBytesReader(w http.ResponseWriter, rc io.ReadCloser, max int64) io.ReadCloser {
@@ -238,8 +475,16 @@ func TrailingSlashRedirector(next http.Handler) http.Handler {
 
 type statusInterceptor struct {
 	http.ResponseWriter
+	header http.Header
 	status int
 }
 
+func (i *statusInterceptor) Header() http.Header {
+	if i.header == nil {
+		i.header = make(http.Header)
+	}
+	return i.header
+}
+
 func (i *statusInterceptor) WriteHeader(status int)      { i.status = status }
 func (i *statusInterceptor) Write(p []byte) (int, error) { return len(p), nil }
@@ -0,0 +1,62 @@
+package hio
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+)
+
+// HTTP3Server serves HTTP/3 over QUIC, implemented by *http3.Server of github.com/quic-go/quic-go so that this
+// package does not depend on it.
+type HTTP3Server interface {
+	ListenAndServe() error
+	// SetQUICHeaders sets the Alt-Svc header advertising the server.
+	SetQUICHeaders(http.Header) error
+	Shutdown(context.Context) error
+	Close() error
+}
+
+// HTTP3Creator returns an [HTTP3Server] listening on the UDP address with the handler and TLS configuration,
+// such as:
+//
+//	func(addr string, h http.Handler, cfg *tls.Config) hio.HTTP3Server {
+//		return &http3.Server{Addr: addr, Handler: h, TLSConfig: http3.ConfigureTLSConfig(cfg)}
+//	}
+type HTTP3Creator func(addr string, h http.Handler, cfg *tls.Config) HTTP3Server
+
+type http3Option struct{ value HTTP3Creator }
+
+// WithHTTP3 serves HTTP/3 with the server of create on the UDP port of the same number as the TCP one, and
+// advertises it to clients with the Alt-Svc header of the responses served over TCP. The HTTP/3 server shuts
+// down along with the server. It requires TLS.
+func WithHTTP3(create HTTP3Creator) ServeOption { return http3Option{value: create} }
+
+func (o http3Option) apply(cfg *ServeConfig) { cfg.HTTP3 = o.value }
+
+// companion is a server running along the main one, such as the one answering ACME challenges.
+type companion interface {
+	ListenAndServe() error
+	Shutdown(context.Context) error
+	Close() error
+}
+
+// http3Addr returns the UDP address of [WithHTTP3], which is the address of the TCP listener.
+func http3Addr(cfg ServeConfig) string {
+	if cfg.Listener != nil {
+		if addr, ok := cfg.Listener.Addr().(*net.TCPAddr); ok {
+			return addr.String()
+		}
+	}
+	return cfg.Addr()
+}
+
+// altSvc sets the Alt-Svc header advertising the HTTP/3 server on the responses of h.
+func altSvc(s HTTP3Server, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			s.SetQUICHeaders(w.Header())
+		}
+		h.ServeHTTP(w, r)
+	})
+}
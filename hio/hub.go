@@ -0,0 +1,241 @@
+package hio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const _defaultSendBuffer = 16
+
+var (
+	// ErrHubClosed is returned when attaching a connection to a [Hub] that is draining.
+	ErrHubClosed = errors.New("hub closed")
+	// ErrEvicted is returned by [Hub.Attach] for a connection that fell behind its send buffer.
+	ErrEvicted = errors.New("evicted slow connection")
+)
+
+// HubEvictions counts the connections evicted from a [Hub] for falling behind.
+// It is published through expvar as "hio.hub_evictions".
+var HubEvictions = expvar.NewInt("hio.hub_evictions")
+
+// Message is sent to the connections of a [Hub], as an event for server-sent events.
+type Message struct {
+	ID    string
+	Event string
+	Data  []byte
+}
+
+// HubOption configures a [Hub].
+type HubOption interface{ apply(*Hub) }
+
+type sendBufferOption struct{ value int }
+
+// WithSendBuffer sets how many messages are queued per connection before it is evicted as too slow. Defaults to 16.
+func WithSendBuffer(n int) HubOption { return sendBufferOption{value: n} }
+
+func (o sendBufferOption) apply(h *Hub) { h.buffer = o.value }
+
+// Hub tracks streaming connections, such as server-sent events served by [Hub.Handler] or WebSockets attached
+// with [Hub.Attach], and sends messages to all of them or to those of a key such as a user ID. Each connection
+// queues messages in its own buffer, so that a slow client is evicted instead of delaying the others.
+type Hub struct {
+	buffer int
+
+	mu     sync.RWMutex
+	conns  map[string]map[*hubConn]struct{}
+	closed bool
+}
+
+type hubConn struct {
+	send  chan Message
+	evict chan struct{}
+	drain chan struct{}
+	once  sync.Once
+}
+
+// NewHub returns an empty [Hub].
+func NewHub(opts ...HubOption) *Hub {
+	h := &Hub{buffer: _defaultSendBuffer, conns: make(map[string]map[*hubConn]struct{})}
+	for _, opt := range opts {
+		opt.apply(h)
+	}
+	return h
+}
+
+// WithHub drains the hub when the server shuts down, so that its connections are flushed and closed instead
+// of holding the shutdown until it times out.
+func WithHub(h *Hub) ServeOption { return hubOption{value: h} }
+
+type hubOption struct{ value *Hub }
+
+func (o hubOption) apply(cfg *ServeConfig) { cfg.Hubs = append(cfg.Hubs, o.value) }
+
+// Len returns the number of connections.
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	n := 0
+	for _, conns := range h.conns {
+		n += len(conns)
+	}
+	return n
+}
+
+// Broadcast queues the message to every connection and returns how many it was queued to.
+func (h *Hub) Broadcast(msg Message) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	n := 0
+	for _, conns := range h.conns {
+		n += h.queue(conns, msg)
+	}
+	return n
+}
+
+// Send queues the message to the connections of the key and returns how many it was queued to.
+func (h *Hub) Send(key string, msg Message) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.queue(h.conns[key], msg)
+}
+
+func (h *Hub) queue(conns map[*hubConn]struct{}, msg Message) int {
+	n := 0
+	for c := range conns {
+		select {
+		case c.send <- msg:
+			n++
+		default:
+			c.once.Do(func() {
+				HubEvictions.Add(1)
+				close(c.evict)
+			})
+		}
+	}
+	return n
+}
+
+// Attach registers a connection of the key and writes its messages with send until ctx is done, send fails,
+// the connection is evicted with [ErrEvicted], or the hub drains, after which queued messages are still sent.
+func (h *Hub) Attach(ctx context.Context, key string, send func(context.Context, Message) error) error {
+	c := &hubConn{send: make(chan Message, h.buffer), evict: make(chan struct{}), drain: make(chan struct{})}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return ErrHubClosed
+	}
+	if h.conns[key] == nil {
+		h.conns[key] = make(map[*hubConn]struct{})
+	}
+	h.conns[key][c] = struct{}{}
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.conns[key], c)
+		if len(h.conns[key]) == 0 {
+			delete(h.conns, key)
+		}
+		h.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case msg := <-c.send:
+			if err := send(ctx, msg); err != nil {
+				return err
+			}
+		case <-c.evict:
+			return ErrEvicted
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.drain:
+			for {
+				select {
+				case msg := <-c.send:
+					if err := send(ctx, msg); err != nil {
+						return err
+					}
+				default:
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// Close stops accepting connections and makes the attached ones return once their queued messages are sent.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+
+	for _, conns := range h.conns {
+		for c := range conns {
+			close(c.drain)
+		}
+	}
+}
+
+// Handler returns an [http.Handler] streaming the messages of the connection of the key returned for the request
+// as server-sent events. Requests with an empty key are rejected with 403 Forbidden.
+func (h *Hub) Handler(key func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k := key(r)
+		if k == "" {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		rc.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		h.Attach(r.Context(), k, func(_ context.Context, msg Message) error {
+			if err := writeEvent(w, msg); err != nil {
+				return err
+			}
+			return rc.Flush()
+		})
+	})
+}
+
+func writeEvent(w http.ResponseWriter, msg Message) error {
+	var b bytes.Buffer
+	if msg.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", msg.ID)
+	}
+	if msg.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", msg.Event)
+	}
+	for line := range bytes.Lines(msg.Data) {
+		fmt.Fprintf(&b, "data: %s\n", bytes.TrimSuffix(line, []byte("\n")))
+	}
+	if len(msg.Data) == 0 {
+		b.WriteString("data:\n")
+	}
+	b.WriteString("\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,152 @@
+package hio
+
+import (
+	"context"
+	"encoding/json/v2"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/inmem"
+)
+
+// IPRules are the networks allowed and denied by [IPFilter]. Denied networks take precedence over allowed ones,
+// and every address that is not denied is allowed when Allow is empty. Key identifies the rules in a store.
+type IPRules struct {
+	Key   string         `json:"key,omitempty"`
+	Allow []netip.Prefix `json:"allow,omitempty"`
+	Deny  []netip.Prefix `json:"deny,omitempty"`
+}
+
+// ID returns the key of the rules.
+func (r IPRules) ID() string { return r.Key }
+
+// Allowed reports whether the rules allow the address.
+func (r IPRules) Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if trusted(r.Deny, addr) {
+		return false
+	}
+	return len(r.Allow) == 0 || trusted(r.Allow, addr)
+}
+
+// IPRuleSource provides the [IPRules] of [IPFilter], which asks for them on every request.
+type IPRuleSource interface {
+	IPRules(ctx context.Context) (IPRules, error)
+}
+
+// IPRules returns the rules themselves so that they are a static [IPRuleSource].
+func (r IPRules) IPRules(context.Context) (IPRules, error) { return r, nil }
+
+// IPRulesFile is an [IPRuleSource] reading JSON encoded [IPRules] from a file, which is only parsed again
+// when its modification time changes.
+type IPRulesFile struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	rules   IPRules
+}
+
+// NewIPRulesFile returns an [IPRulesFile] reading the file at path.
+func NewIPRulesFile(path string) *IPRulesFile { return &IPRulesFile{path: path} }
+
+// IPRules returns the rules in the file.
+func (f *IPRulesFile) IPRules(context.Context) (IPRules, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return IPRules{}, fmt.Errorf("reading ip rules file: %w", err)
+	}
+
+	if fi.ModTime().Equal(f.modTime) {
+		return f.rules, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return IPRules{}, fmt.Errorf("reading ip rules file: %w", err)
+	}
+
+	var rules IPRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return IPRules{}, fmt.Errorf("decoding ip rules file: %w", err)
+	}
+
+	f.rules, f.modTime = rules, fi.ModTime()
+
+	return rules, nil
+}
+
+// IPRulesStore returns an [IPRuleSource] loading the rules with the key from the store.
+func IPRulesStore(store inmem.Storer[string, IPRules], key string) IPRuleSource {
+	return storedIPRules{store: store, key: key}
+}
+
+type storedIPRules struct {
+	store inmem.Storer[string, IPRules]
+	key   string
+}
+
+func (s storedIPRules) IPRules(ctx context.Context) (IPRules, error) {
+	rules := IPRules{Key: s.key}
+	if err := s.store.Load(ctx, &rules); err != nil {
+		return IPRules{}, fmt.Errorf("loading ip rules: %w", err)
+	}
+	return rules, nil
+}
+
+// IPFilter is a middleware responding with 403 Forbidden to clients whose address the rules do not allow, such
+// as to restrict an admin router to office and VPN ranges. The address of requests coming from a trusted proxy is
+// read from the X-Forwarded-For header. Requests are rejected as well while the rules cannot be read.
+func IPFilter(src IPRuleSource, trustedProxies []netip.Prefix) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rules, err := src.IPRules(r.Context())
+			addr, ok := ClientIP(r, trustedProxies)
+			if err != nil || !ok || !rules.Allowed(addr) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the address of the client that sent the request. When the request comes from one of the trusted
+// proxies, it is the right-most address of the X-Forwarded-For header that is not a trusted proxy itself.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr = addr.Unmap()
+
+	if !trusted(trustedProxies, addr) || r.Header.Get("X-Forwarded-For") == "" {
+		return addr, true
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		if addr = hop.Unmap(); !trusted(trustedProxies, addr) {
+			return addr, true
+		}
+	}
+
+	return addr, true
+}
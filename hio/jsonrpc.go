@@ -0,0 +1,175 @@
+package hio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+const _defaultRPCBodyLimit = 1 << 20
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+)
+
+// RPCError is a JSON-RPC error. Methods return one to answer with its code, message, and data, other errors
+// being answered as internal errors without their message.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string { return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message) }
+
+type rpcRequest struct {
+	JSONRPC string         `json:"jsonrpc"`
+	Method  string         `json:"method"`
+	Params  jsontext.Value `json:"params,omitzero"`
+	ID      jsontext.Value `json:"id,omitzero"`
+}
+
+type rpcResponse struct {
+	JSONRPC string         `json:"jsonrpc"`
+	Result  jsontext.Value `json:"result,omitzero"`
+	Error   *RPCError      `json:"error,omitempty"`
+	ID      jsontext.Value `json:"id"`
+}
+
+type rpcMethod func(ctx context.Context, params jsontext.Value) (any, error)
+
+// RPCServer is an [http.Handler] serving JSON-RPC 2.0 requests, including batches and notifications, over POST
+// on a single route, such as with [FromHTTP].
+type RPCServer struct {
+	l       *slog.Logger
+	methods map[string]rpcMethod
+}
+
+// NewRPCServer returns an [RPCServer] without methods, logging the errors answered as internal errors with l.
+func NewRPCServer(l *slog.Logger) *RPCServer {
+	return &RPCServer{l: l, methods: make(map[string]rpcMethod)}
+}
+
+// RegisterRPC registers the method of the server, decoding its params into P, by name or position when P is a
+// slice, and encoding its result R. Params that fail to decode are answered with [RPCInvalidParams].
+func RegisterRPC[P, R any](s *RPCServer, method string, fn func(context.Context, P) (R, error)) {
+	s.methods[method] = func(ctx context.Context, params jsontext.Value) (any, error) {
+		var p P
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, &RPCError{Code: RPCInvalidParams, Message: "Invalid params", Data: err.Error()}
+			}
+		}
+		return fn(ctx, p)
+	}
+}
+
+// ServeHTTP answers the request or batch of requests, with 204 No Content when they are all notifications.
+func (s *RPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(MaxBytesReader(w, r.Body, _defaultRPCBodyLimit))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if bytes.HasPrefix(body, []byte("[")) {
+		var reqs []jsontext.Value
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			s.write(w, r, rpcFailure(nil, RPCParseError, "Parse error"))
+			return
+		}
+		if len(reqs) == 0 {
+			s.write(w, r, rpcFailure(nil, RPCInvalidRequest, "Invalid Request"))
+			return
+		}
+
+		var resps []rpcResponse
+		for _, req := range reqs {
+			if resp, ok := s.call(r.Context(), req); ok {
+				resps = append(resps, resp)
+			}
+		}
+		if len(resps) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.write(w, r, resps)
+		return
+	}
+
+	if !jsontext.Value(body).IsValid() {
+		s.write(w, r, rpcFailure(nil, RPCParseError, "Parse error"))
+		return
+	}
+	if resp, ok := s.call(r.Context(), body); ok {
+		s.write(w, r, resp)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// call runs the method of the request, and reports false for notifications, which are not answered.
+func (s *RPCServer) call(ctx context.Context, data jsontext.Value) (rpcResponse, bool) {
+	var req rpcRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcFailure(req.ID, RPCInvalidRequest, "Invalid Request"), true
+	}
+
+	method, ok := s.methods[req.Method]
+	if !ok {
+		return rpcFailure(req.ID, RPCMethodNotFound, "Method not found"), req.ID != nil
+	}
+
+	result, err := method(ctx, req.Params)
+	if err != nil {
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			s.l.LogAttrs(ctx, slog.LevelError, "calling rpc method", slog.String("method", req.Method), slog.Any("error", err))
+			rpcErr = &RPCError{Code: RPCInternalError, Message: "Internal error"}
+		}
+		return rpcResponse{JSONRPC: "2.0", Error: rpcErr, ID: rpcID(req.ID)}, req.ID != nil
+	}
+
+	data, err = json.Marshal(result)
+	if err != nil {
+		s.l.LogAttrs(ctx, slog.LevelError, "encoding rpc result", slog.String("method", req.Method), slog.Any("error", err))
+		return rpcFailure(req.ID, RPCInternalError, "Internal error"), req.ID != nil
+	}
+	return rpcResponse{JSONRPC: "2.0", Result: data, ID: rpcID(req.ID)}, req.ID != nil
+}
+
+func (s *RPCServer) write(w http.ResponseWriter, r *http.Request, v any) {
+	if err := EncodeJSONBuffered(w, v, http.StatusOK); err != nil {
+		s.l.LogAttrs(r.Context(), slog.LevelWarn, "writing rpc response", slog.Any("error", err))
+	}
+}
+
+func rpcFailure(id jsontext.Value, code int, message string) rpcResponse {
+	return rpcResponse{JSONRPC: "2.0", Error: &RPCError{Code: code, Message: message}, ID: rpcID(id)}
+}
+
+// rpcID returns the ID of a response, which is null when the request has none.
+func rpcID(id jsontext.Value) jsontext.Value {
+	if id == nil {
+		return jsontext.Value("null")
+	}
+	return id
+}
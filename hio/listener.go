@@ -0,0 +1,143 @@
+package hio
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
+)
+
+type (
+	listenerOption   struct{ value net.Listener }
+	onListenOption   struct{ value func(net.Addr) }
+	unixSocketOption struct {
+		path string
+		perm os.FileMode
+	}
+	maxConnsPerIPOption  struct{ value int }
+	trustedProxiesOption struct{ value []netip.Prefix }
+)
+
+// WithListener serves on a listener that is already bound, such as one passed by systemd socket activation,
+// instead of listening on the host and port. The server closes it when it shuts down.
+func WithListener(ln net.Listener) ServeOption { return listenerOption{value: ln} }
+
+// WithOnListen calls fn with the address the server listens on once it is bound, before requests are served,
+// such as for an integration test to wait until connections are accepted. A port of 0 defaults to 8080, so
+// ephemeral ports are served with [WithListener] and a listener bound to port 0.
+func WithOnListen(fn func(net.Addr)) ServeOption { return onListenOption{value: fn} }
+
+// WithMaxConnsPerIP limits the concurrent connections of every remote IP to n, closing excess connections as soon
+// as they are accepted so that they never reach a handler. Connections from trusted proxies are not limited.
+func WithMaxConnsPerIP(n int) ServeOption { return maxConnsPerIPOption{value: n} }
+
+// WithTrustedProxies sets the networks of the proxies in front of the server, such as load balancers,
+// whose connections carry the traffic of many clients.
+func WithTrustedProxies(v ...netip.Prefix) ServeOption { return trustedProxiesOption{value: v} }
+
+// WithUnixSocket listens on a unix socket at path with the permissions perm, such as 0o660, instead of the host and
+// port. A stale socket file left by a previous process is removed first, and the socket is removed on shutdown.
+func WithUnixSocket(path string, perm os.FileMode) ServeOption {
+	return unixSocketOption{path: path, perm: perm}
+}
+
+func (o listenerOption) apply(cfg *ServeConfig) { cfg.Listener = o.value }
+func (o onListenOption) apply(cfg *ServeConfig) { cfg.OnListen = o.value }
+func (o unixSocketOption) apply(cfg *ServeConfig) {
+	cfg.UnixSocket, cfg.UnixSocketPerm = o.path, o.perm
+}
+func (o maxConnsPerIPOption) apply(cfg *ServeConfig)  { cfg.MaxConnsPerIP = o.value }
+func (o trustedProxiesOption) apply(cfg *ServeConfig) { cfg.TrustedProxies = o.value }
+
+// listenUnix listens on the unix socket at path, removing a socket file no process listens on anymore.
+func listenUnix(path string, perm os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, &ServeError{Kind: ErrBindFailed, Err: fmt.Errorf("listening on unix socket %s: already in use", path)}
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale unix socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(true)
+
+	if perm != 0 {
+		if err := os.Chmod(path, perm); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("setting unix socket permissions: %w", err)
+		}
+	}
+
+	return ln, nil
+}
+
+func trusted(proxies []netip.Prefix, addr netip.Addr) bool {
+	return slices.ContainsFunc(proxies, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
+
+// limitListener limits the concurrent connections accepted per remote IP.
+type limitListener struct {
+	net.Listener
+	max     int
+	proxies []netip.Prefix
+
+	mu    sync.Mutex
+	conns map[netip.Addr]int
+}
+
+func newLimitListener(ln net.Listener, max int, proxies []netip.Prefix) *limitListener {
+	return &limitListener{Listener: ln, max: max, proxies: proxies, conns: make(map[netip.Addr]int)}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		ap, err := netip.ParseAddrPort(c.RemoteAddr().String())
+		if err != nil || trusted(l.proxies, ap.Addr().Unmap()) {
+			return c, nil
+		}
+		addr := ap.Addr().Unmap()
+
+		l.mu.Lock()
+		if l.conns[addr] >= l.max {
+			l.mu.Unlock()
+			c.Close()
+			continue
+		}
+		l.conns[addr]++
+		l.mu.Unlock()
+
+		return &limitConn{Conn: c, release: sync.OnceFunc(func() { l.release(addr) })}, nil
+	}
+}
+
+func (l *limitListener) release(addr netip.Addr) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conns[addr]--; l.conns[addr] <= 0 {
+		delete(l.conns, addr)
+	}
+}
+
+type limitConn struct {
+	net.Conn
+	release func()
+}
+
+func (c *limitConn) Close() error {
+	c.release()
+	return c.Conn.Close()
+}
@@ -0,0 +1,109 @@
+package hio
+
+import (
+	"bytes"
+	"context"
+	"expvar"
+	"io"
+	"net/http"
+)
+
+const (
+	_defaultMirrorQueue   = 100
+	_defaultMirrorWorkers = 4
+	_defaultMirrorMaxBody = 1 << 20
+)
+
+// MirroredRequests counts the requests handled by [Mirror] as "sent", "dropped" when the queue was full,
+// and "skipped" when the body exceeded the limit. It is published through expvar as "hio.mirrored_requests".
+var MirroredRequests = expvar.NewMap("hio.mirrored_requests")
+
+// MirrorOption configures [Mirror].
+type MirrorOption interface{ apply(*mirrorConfig) }
+
+type mirrorConfig struct {
+	queue   int
+	workers int
+	maxBody int64
+}
+
+type (
+	mirrorQueueOption   struct{ value int }
+	mirrorWorkersOption struct{ value int }
+	mirrorMaxBodyOption struct{ value int64 }
+)
+
+// WithMirrorQueue sets how many requests wait for a worker before further requests are dropped. Defaults to 100.
+func WithMirrorQueue(n int) MirrorOption { return mirrorQueueOption{value: n} }
+
+// WithMirrorWorkers sets how many requests are sent to the shadow concurrently. Defaults to 4.
+func WithMirrorWorkers(n int) MirrorOption { return mirrorWorkersOption{value: n} }
+
+// WithMirrorMaxBody sets the largest request body buffered to be mirrored, requests with larger bodies are not
+// mirrored. Defaults to 1MiB.
+func WithMirrorMaxBody(n int64) MirrorOption { return mirrorMaxBodyOption{value: n} }
+
+func (o mirrorQueueOption) apply(cfg *mirrorConfig)   { cfg.queue = o.value }
+func (o mirrorWorkersOption) apply(cfg *mirrorConfig) { cfg.workers = o.value }
+func (o mirrorMaxBodyOption) apply(cfg *mirrorConfig) { cfg.maxBody = o.value }
+
+// Mirror is a middleware duplicating requests to the shadow handler, such as a rewrite of the service or an
+// [net/http/httputil.ReverseProxy] to it, in the background and discarding its responses. Requests are dropped
+// rather than delaying the wrapped handler when the queue is full. Workers stop once ctx is done.
+func Mirror(ctx context.Context, shadow http.Handler, opts ...MirrorOption) Middleware {
+	cfg := mirrorConfig{queue: _defaultMirrorQueue, workers: _defaultMirrorWorkers, maxBody: _defaultMirrorMaxBody}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	queue := make(chan *http.Request, cfg.queue)
+
+	for range cfg.workers {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case r := <-queue:
+					shadow.ServeHTTP(&discardWriter{header: make(http.Header)}, r)
+				}
+			}
+		}()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body []byte
+
+			if r.Body != nil && r.Body != http.NoBody {
+				buf, err := io.ReadAll(io.LimitReader(r.Body, cfg.maxBody+1))
+				r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(buf), r.Body), close: r.Body.Close}
+				if err != nil || int64(len(buf)) > cfg.maxBody {
+					MirroredRequests.Add("skipped", 1)
+					next.ServeHTTP(w, r)
+					return
+				}
+				body = buf
+			}
+
+			shadowReq := r.Clone(ctx)
+			shadowReq.Body = io.NopCloser(bytes.NewReader(body))
+			shadowReq.ContentLength = int64(len(body))
+
+			select {
+			case queue <- shadowReq:
+				MirroredRequests.Add("sent", 1)
+			default:
+				MirroredRequests.Add("dropped", 1)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type discardWriter struct{ header http.Header }
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(int)             {}
@@ -0,0 +1,324 @@
+package hio
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// RevocationPolicy decides how client certificates are checked for revocation.
+type RevocationPolicy int
+
+const (
+	// RevocationOff skips revocation checks.
+	RevocationOff RevocationPolicy = iota
+	// RevocationSoftFail rejects revoked certificates but accepts certificates whose status cannot be determined.
+	RevocationSoftFail
+	// RevocationHardFail rejects revoked certificates and certificates whose status cannot be determined.
+	RevocationHardFail
+)
+
+const _revocationFetchTimeout = 5 * time.Second
+
+type (
+	ocspStaplingOption struct{ value time.Duration }
+	revocationOption   struct{ value RevocationPolicy }
+)
+
+// WithOCSPStapling staples OCSP responses fetched from the responder of the server certificate to TLS handshakes,
+// refreshing them on the interval. Handshakes proceed without a staple while none could be fetched.
+func WithOCSPStapling(refresh time.Duration) ServeOption { return ocspStaplingOption{value: refresh} }
+
+// WithClientRevocation checks verified client certificates against the OCSP responder or CRL distribution points
+// they name, caching results until their next update, and handles unknown statuses according to the policy.
+// The VerifyConnection callback of the TLS configuration, if any, still runs before the revocation checks.
+func WithClientRevocation(policy RevocationPolicy) ServeOption {
+	return revocationOption{value: policy}
+}
+
+func (o ocspStaplingOption) apply(cfg *ServeConfig) { cfg.OCSPStapleRefresh = o.value }
+func (o revocationOption) apply(cfg *ServeConfig)   { cfg.ClientRevocation = o.value }
+
+type ocspStapler struct {
+	certs []atomic.Pointer[tls.Certificate]
+	kick  chan struct{}
+}
+
+// newOCSPStapler serves the certificates of cfg through GetCertificate so that their staples can be replaced safely.
+// The certificates are moved out of cfg, since crypto/tls only calls GetCertificate for handshakes without a
+// server name when cfg has none.
+func newOCSPStapler(cfg *tls.Config) *ocspStapler {
+	s := &ocspStapler{
+		certs: make([]atomic.Pointer[tls.Certificate], len(cfg.Certificates)),
+		kick:  make(chan struct{}, 1),
+	}
+
+	for i := range cfg.Certificates {
+		c := cfg.Certificates[i]
+		s.certs[i].Store(&c)
+	}
+	cfg.Certificates = nil
+
+	cfg.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		for i := range s.certs {
+			if c := s.certs[i].Load(); hello.SupportsCertificate(c) == nil {
+				return c, nil
+			}
+		}
+		return s.certs[0].Load(), nil
+	}
+
+	return s
+}
+
+// replace serves c in place of the certificate i, such as one reloaded by [WithTLSReload], without a staple
+// until run fetches one for it.
+func (s *ocspStapler) replace(i int, c tls.Certificate) {
+	c.OCSPStaple = nil
+	s.certs[i].Store(&c)
+
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (s *ocspStapler) run(ctx context.Context, refresh time.Duration) error {
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		for i := range s.certs {
+			old := s.certs[i].Load()
+			if staple, err := fetchStaple(ctx, *old); err == nil {
+				c := *old
+				c.OCSPStaple = staple
+				s.certs[i].CompareAndSwap(old, &c)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-s.kick:
+		}
+	}
+}
+
+func fetchStaple(ctx context.Context, c tls.Certificate) ([]byte, error) {
+	if len(c.Certificate) < 2 {
+		return nil, errors.New("certificate chain has no issuer")
+	}
+
+	leaf, err := x509.ParseCertificate(c.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, err := x509.ParseCertificate(c.Certificate[1])
+	if err != nil {
+		return nil, err
+	}
+
+	raw, res, err := queryOCSP(ctx, leaf, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Status != ocsp.Good {
+		return nil, fmt.Errorf("certificate status %d", res.Status)
+	}
+
+	return raw, nil
+}
+
+func queryOCSP(ctx context.Context, leaf, issuer *x509.Certificate) ([]byte, *ocsp.Response, error) {
+	if len(leaf.OCSPServer) == 0 {
+		return nil, nil, errors.New("certificate names no OCSP responder")
+	}
+
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, err := fetch(ctx, http.MethodPost, leaf.OCSPServer[0], req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return raw, res, nil
+}
+
+type revocationChecker struct {
+	policy RevocationPolicy
+	mu     sync.Mutex
+	ocsp   map[string]*ocsp.Response
+	crls   map[string]*x509.RevocationList
+}
+
+func newRevocationChecker(policy RevocationPolicy) *revocationChecker {
+	return &revocationChecker{
+		policy: policy,
+		ocsp:   make(map[string]*ocsp.Response),
+		crls:   make(map[string]*x509.RevocationList),
+	}
+}
+
+// chain returns a [tls.Config.VerifyConnection] callback running verify, such as the callback already set by the
+// caller, before checking the revocation of the client chains, and failing when either fails.
+func (c *revocationChecker) chain(verify func(tls.ConnectionState) error) func(tls.ConnectionState) error {
+	if verify == nil {
+		return c.verifyConnection
+	}
+	return func(cs tls.ConnectionState) error {
+		if err := verify(cs); err != nil {
+			return err
+		}
+		return c.verifyConnection(cs)
+	}
+}
+
+// verifyConnection is a [tls.Config.VerifyConnection] callback checking every verified client chain.
+func (c *revocationChecker) verifyConnection(cs tls.ConnectionState) error {
+	for _, chain := range cs.VerifiedChains {
+		if len(chain) < 2 {
+			continue
+		}
+
+		revoked, err := c.revoked(chain[0], chain[1])
+		if revoked {
+			return fmt.Errorf("client certificate %s is revoked", chain[0].SerialNumber)
+		}
+		if err != nil && c.policy == RevocationHardFail {
+			return fmt.Errorf("checking client certificate revocation: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *revocationChecker) revoked(leaf, issuer *x509.Certificate) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), _revocationFetchTimeout)
+	defer cancel()
+
+	if len(leaf.OCSPServer) > 0 {
+		res, err := c.ocspResponse(ctx, leaf, issuer)
+		if err != nil {
+			return false, err
+		}
+		switch res.Status {
+		case ocsp.Good:
+			return false, nil
+		case ocsp.Revoked:
+			return true, nil
+		}
+		return false, errors.New("unknown OCSP status")
+	}
+
+	if len(leaf.CRLDistributionPoints) > 0 {
+		crl, err := c.crl(ctx, leaf.CRLDistributionPoints[0], issuer)
+		if err != nil {
+			return false, err
+		}
+		for _, e := range crl.RevokedCertificateEntries {
+			if e.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return false, errors.New("certificate names no OCSP responder or CRL distribution point")
+}
+
+func (c *revocationChecker) ocspResponse(ctx context.Context, leaf, issuer *x509.Certificate) (*ocsp.Response, error) {
+	key := issuer.SerialNumber.String() + "/" + leaf.SerialNumber.String()
+
+	c.mu.Lock()
+	res, ok := c.ocsp[key]
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(res.NextUpdate) {
+		return res, nil
+	}
+
+	_, res, err := queryOCSP(ctx, leaf, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.ocsp[key] = res
+	c.mu.Unlock()
+
+	return res, nil
+}
+
+func (c *revocationChecker) crl(ctx context.Context, url string, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	c.mu.Lock()
+	crl, ok := c.crls[url]
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(crl.NextUpdate) {
+		return crl, nil
+	}
+
+	raw, err := fetch(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	crl, err = x509.ParseRevocationList(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.crls[url] = crl
+	c.mu.Unlock()
+
+	return crl, nil
+}
+
+func fetch(ctx context.Context, method, url string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/ocsp-request")
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", url, res.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(res.Body, 10<<20))
+}
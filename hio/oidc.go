@@ -0,0 +1,582 @@
+package hio
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/inmem"
+)
+
+const (
+	_defaultOIDCCookie     = "hio_session"
+	_defaultOIDCLoginPath  = "/login"
+	_defaultOIDCSessionTTL = 8 * time.Hour
+	_defaultOIDCFlowTTL    = 10 * time.Minute
+	_oidcClockSkew         = 1 * time.Minute
+	_oidcJWKSRefresh       = 1 * time.Minute
+)
+
+// ErrInvalidIDToken is returned when an ID token fails verification.
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+// OIDCConfig configures an [OIDC] relying party.
+type OIDCConfig struct {
+	// Issuer is the URL of the provider, whose configuration is discovered under /.well-known/openid-configuration.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the absolute URL of the [OIDC.Callback] handler registered with the provider.
+	RedirectURL string
+	// PostLogoutRedirectURL is where the provider sends users back after [OIDC.Logout], if it supports it.
+	PostLogoutRedirectURL string
+	// Scopes default to openid, profile, and email.
+	Scopes []string
+	// CookieName names the session cookie. Defaults to hio_session.
+	CookieName string
+	// LoginPath is where [OIDC.RequireLogin] redirects to, serving [OIDC.Login]. Defaults to /login.
+	LoginPath string
+	// SessionTTL is how long a session lasts after logging in. Defaults to 8 hours.
+	SessionTTL time.Duration
+	// Client sends the requests to the provider. Defaults to [http.DefaultClient].
+	Client *http.Client
+	// OnError responds to failed logins. Defaults to 401 Unauthorized.
+	OnError func(http.ResponseWriter, *http.Request, error)
+}
+
+// OIDCSession is the session of a user, stored while logging in and once logged in.
+type OIDCSession struct {
+	Key          string         `json:"key"`
+	Subject      string         `json:"subject,omitempty"`
+	Claims       jsontext.Value `json:"claims,omitzero"`
+	IDToken      string         `json:"id_token,omitempty"`
+	AccessToken  string         `json:"access_token,omitempty"`
+	RefreshToken string         `json:"refresh_token,omitempty"`
+	Expiry       time.Time      `json:"expiry"`
+
+	// State, Nonce, Verifier, and ReturnTo are set while logging in.
+	State    string `json:"state,omitempty"`
+	Nonce    string `json:"nonce,omitempty"`
+	Verifier string `json:"verifier,omitempty"`
+	ReturnTo string `json:"return_to,omitempty"`
+}
+
+// ID returns the key of the session.
+func (s OIDCSession) ID() string { return s.Key }
+
+// LoggedIn reports whether the session belongs to a logged in user and has not expired.
+func (s OIDCSession) LoggedIn() bool { return s.Subject != "" && time.Now().Before(s.Expiry) }
+
+// OIDCSessionStore stores the sessions of an [OIDC] relying party, such as an [inmem.Repository].
+type OIDCSessionStore interface {
+	inmem.Storer[string, OIDCSession]
+	DeleteWhere(ctx context.Context, pred func(OIDCSession) bool) (int, error)
+}
+
+// OIDC is an OpenID Connect relying party logging users in with the authorization code flow and PKCE, and keeping
+// their sessions in a store behind a cookie.
+type OIDC struct {
+	cfg      OIDCConfig
+	provider oidcProvider
+	sessions OIDCSessionStore
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// NewOIDC returns an [OIDC] relying party keeping sessions in the store, once the configuration of the provider
+// is discovered.
+func NewOIDC(ctx context.Context, cfg OIDCConfig, sessions OIDCSessionStore) (*OIDC, error) {
+	if cfg.Scopes == nil {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = _defaultOIDCCookie
+	}
+	if cfg.LoginPath == "" {
+		cfg.LoginPath = _defaultOIDCLoginPath
+	}
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = _defaultOIDCSessionTTL
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(w http.ResponseWriter, _ *http.Request, _ error) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}
+	}
+
+	o := &OIDC{cfg: cfg, sessions: sessions}
+
+	issuer := strings.TrimSuffix(cfg.Issuer, "/")
+	if err := o.getJSON(ctx, issuer+"/.well-known/openid-configuration", &o.provider); err != nil {
+		return nil, fmt.Errorf("discovering oidc provider: %w", err)
+	}
+	if strings.TrimSuffix(o.provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovering oidc provider: issuer %q does not match %q", o.provider.Issuer, cfg.Issuer)
+	}
+
+	return o, nil
+}
+
+// Login starts logging in, redirecting to the provider. The path given in the return_to query parameter is
+// where [OIDC.Callback] redirects to once logged in.
+func (o *OIDC) Login() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		returnTo := r.URL.Query().Get("return_to")
+		if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+			returnTo = "/"
+		}
+
+		s := OIDCSession{
+			Key:      randomToken(),
+			State:    randomToken(),
+			Nonce:    randomToken(),
+			Verifier: randomToken(),
+			ReturnTo: returnTo,
+			Expiry:   time.Now().Add(_defaultOIDCFlowTTL),
+		}
+		if err := o.sessions.Save(r.Context(), &s); err != nil {
+			o.cfg.OnError(w, r, fmt.Errorf("saving session: %w", err))
+			return
+		}
+		o.setCookie(w, s)
+
+		challenge := sha256.Sum256([]byte(s.Verifier))
+		q := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {o.cfg.ClientID},
+			"redirect_uri":          {o.cfg.RedirectURL},
+			"scope":                 {strings.Join(o.cfg.Scopes, " ")},
+			"state":                 {s.State},
+			"nonce":                 {s.Nonce},
+			"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+			"code_challenge_method": {"S256"},
+		}
+		http.Redirect(w, r, o.provider.AuthorizationEndpoint+"?"+q.Encode(), http.StatusFound)
+	})
+}
+
+// Callback completes logging in once the provider redirects back, exchanging the code for tokens and verifying
+// the ID token against the keys of the provider, and starts the session under a new key.
+func (o *OIDC) Callback() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flow, ok := o.session(r)
+		if !ok || flow.State == "" || time.Now().After(flow.Expiry) {
+			o.cfg.OnError(w, r, errors.New("login not started or expired"))
+			return
+		}
+
+		q := r.URL.Query()
+		if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(flow.State)) != 1 {
+			o.cfg.OnError(w, r, errors.New("login state mismatch"))
+			return
+		}
+		if e := q.Get("error"); e != "" {
+			o.cfg.OnError(w, r, fmt.Errorf("login failed: %s: %s", e, q.Get("error_description")))
+			return
+		}
+
+		tok, err := o.exchange(r.Context(), q.Get("code"), flow.Verifier)
+		if err != nil {
+			o.cfg.OnError(w, r, err)
+			return
+		}
+
+		claims, err := o.verify(r.Context(), tok.IDToken, flow.Nonce)
+		if err != nil {
+			o.cfg.OnError(w, r, err)
+			return
+		}
+
+		var sub struct {
+			Subject string `json:"sub"`
+		}
+		json.Unmarshal(claims, &sub)
+
+		s := OIDCSession{
+			Key:          randomToken(),
+			Subject:      sub.Subject,
+			Claims:       claims,
+			IDToken:      tok.IDToken,
+			AccessToken:  tok.AccessToken,
+			RefreshToken: tok.RefreshToken,
+			Expiry:       time.Now().Add(o.cfg.SessionTTL),
+		}
+		if err := o.sessions.Save(r.Context(), &s); err != nil {
+			o.cfg.OnError(w, r, fmt.Errorf("saving session: %w", err))
+			return
+		}
+		o.end(r.Context(), flow)
+		o.setCookie(w, s)
+
+		http.Redirect(w, r, flow.ReturnTo, http.StatusFound)
+	})
+}
+
+// Logout ends the session and redirects to the provider to log out there as well when it supports it, or to /.
+func (o *OIDC) Logout() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := o.session(r)
+		if ok {
+			o.end(r.Context(), s)
+		}
+		http.SetCookie(w, &http.Cookie{Name: o.cfg.CookieName, Path: "/", MaxAge: -1, HttpOnly: true, Secure: o.secure()})
+
+		if o.provider.EndSessionEndpoint == "" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		q := url.Values{"client_id": {o.cfg.ClientID}}
+		if ok && s.IDToken != "" {
+			q.Set("id_token_hint", s.IDToken)
+		}
+		if o.cfg.PostLogoutRedirectURL != "" {
+			q.Set("post_logout_redirect_uri", o.cfg.PostLogoutRedirectURL)
+		}
+		http.Redirect(w, r, o.provider.EndSessionEndpoint+"?"+q.Encode(), http.StatusFound)
+	})
+}
+
+// RequireLogin is a middleware passing requests of logged in users on with their session, read with
+// [OIDCSessionFromContext]. Other GET requests are redirected to log in and come back, and the rest are
+// answered with 401 Unauthorized.
+func (o *OIDC) RequireLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := o.session(r)
+		if ok && s.LoggedIn() {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), oidcSessionKey{}, s)))
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		q := url.Values{"return_to": {r.URL.RequestURI()}}
+		http.Redirect(w, r, o.cfg.LoginPath+"?"+q.Encode(), http.StatusFound)
+	})
+}
+
+type oidcSessionKey struct{}
+
+// OIDCSessionFromContext returns the session of a request passed on by [OIDC.RequireLogin].
+func OIDCSessionFromContext(ctx context.Context) (OIDCSession, bool) {
+	s, ok := ctx.Value(oidcSessionKey{}).(OIDCSession)
+	return s, ok
+}
+
+func (o *OIDC) session(r *http.Request) (OIDCSession, bool) {
+	c, err := r.Cookie(o.cfg.CookieName)
+	if err != nil || c.Value == "" {
+		return OIDCSession{}, false
+	}
+	s := OIDCSession{Key: c.Value}
+	if err := o.sessions.Load(r.Context(), &s); err != nil {
+		return OIDCSession{}, false
+	}
+	return s, true
+}
+
+// end deletes the session along with any that expired.
+func (o *OIDC) end(ctx context.Context, s OIDCSession) {
+	now := time.Now()
+	o.sessions.DeleteWhere(ctx, func(v OIDCSession) bool { return v.Key == s.Key || now.After(v.Expiry) })
+}
+
+func (o *OIDC) setCookie(w http.ResponseWriter, s OIDCSession) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     o.cfg.CookieName,
+		Value:    s.Key,
+		Path:     "/",
+		Expires:  s.Expiry,
+		HttpOnly: true,
+		Secure:   o.secure(),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (o *OIDC) secure() bool { return strings.HasPrefix(o.cfg.RedirectURL, "https://") }
+
+type oidcToken struct {
+	IDToken      string `json:"id_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (o *OIDC) exchange(ctx context.Context, code, verifier string) (oidcToken, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {o.cfg.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oidcToken{}, fmt.Errorf("exchanging code: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(o.cfg.ClientSecret))
+	}
+
+	var tok oidcToken
+	if err := o.doJSON(req, &tok); err != nil {
+		return oidcToken{}, fmt.Errorf("exchanging code: %w", err)
+	}
+	if tok.IDToken == "" {
+		return oidcToken{}, errors.New("exchanging code: no id token")
+	}
+	return tok, nil
+}
+
+// verify checks the signature, issuer, audience, expiry, and nonce of the ID token, and returns its claims.
+func (o *OIDC) verify(ctx context.Context, token, nonce string) (jsontext.Value, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidIDToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	key, err := o.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	var c struct {
+		Issuer   string         `json:"iss"`
+		Audience jsontext.Value `json:"aud"`
+		Expiry   int64          `json:"exp"`
+		Nonce    string         `json:"nonce"`
+	}
+	if err := json.Unmarshal(claims, &c); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	var aud []string
+	if err := json.Unmarshal(c.Audience, &aud); err != nil {
+		aud = []string{""}
+		json.Unmarshal(c.Audience, &aud[0])
+	}
+
+	switch {
+	case c.Issuer != o.provider.Issuer:
+		return nil, fmt.Errorf("%w: issuer %q", ErrInvalidIDToken, c.Issuer)
+	case !slices.Contains(aud, o.cfg.ClientID):
+		return nil, fmt.Errorf("%w: audience %s", ErrInvalidIDToken, c.Audience)
+	case time.Now().Add(-_oidcClockSkew).After(time.Unix(c.Expiry, 0)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	case subtle.ConstantTimeCompare([]byte(c.Nonce), []byte(nonce)) != 1:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+// key returns the signing key of the provider with the ID, fetching the keys again when it is unknown, such as
+// after a rotation, at most once a minute.
+func (o *OIDC) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(o.fetched) < _oidcJWKSRefresh {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidIDToken, kid)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := o.getJSON(ctx, o.provider.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetching oidc keys: %w", err)
+	}
+	o.fetched = time.Now()
+
+	o.keys = make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if key, err := k.publicKey(); err == nil && k.Use != "enc" {
+			o.keys[k.Kid] = key
+		}
+	}
+
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidIDToken, kid)
+}
+
+func (o *OIDC) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return o.doJSON(req, v)
+}
+
+func (o *OIDC) doJSON(req *http.Request, v any) error {
+	resp, err := o.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, req.URL.Redacted())
+	}
+	return json.UnmarshalRead(resp.Body, v)
+}
+
+// jwk is a JSON Web Key of a signing key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+// verifySignature verifies the signature of a JSON Web Token signed with RSA or ECDSA.
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	var h crypto.Hash
+	switch alg[2:] {
+	case "256":
+		h = crypto.SHA256
+	case "384":
+		h = crypto.SHA384
+	case "512":
+		h = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %s", alg)
+	}
+	hasher := h.New()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(key, h, digest, sig)
+		case "PS":
+			return rsa.VerifyPSS(key, h, digest, sig, nil)
+		}
+	case *ecdsa.PublicKey:
+		if alg[:2] == "ES" && len(sig)%2 == 0 {
+			r, s := new(big.Int).SetBytes(sig[:len(sig)/2]), new(big.Int).SetBytes(sig[len(sig)/2:])
+			if ecdsa.Verify(key, digest, r, s) {
+				return nil
+			}
+			return errors.New("invalid signature")
+		}
+	}
+	return fmt.Errorf("algorithm %s does not match the key", alg)
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func randomToken() string {
+	var b [32]byte
+	rand.Read(b[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
@@ -0,0 +1,71 @@
+package hio
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicsRecovered counts the panics recovered from [Handler] chains.
+// It is published through expvar as "hio.panics_recovered".
+var PanicsRecovered = expvar.NewInt("hio.panics_recovered")
+
+// PanicError is the error a [Handler] chain continues with when one of its steps panics.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// Error returns the panic value.
+func (e *PanicError) Error() string { return fmt.Sprintf("handler panicked: %v", e.Value) }
+
+// Unwrap returns the panic value when it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// OnPanic returns a copy of the [Responder] continuing with the [Handler] returned by fn when a step of a chain
+// run by [Responder.Recover] panics, instead of the error path of the [Responder].
+func (rs Responder) OnPanic(fn func(*PanicError) Handler) Responder {
+	rs.panic = fn
+	return rs
+}
+
+// OnPanic sets the handler the routes registered afterwards continue with when they panic, as with
+// [Responder.OnPanic].
+func (ro *Router) OnPanic(fn func(*PanicError) Handler) { ro.r = ro.r.OnPanic(fn) }
+
+// Recover returns a [Handler] running the chain of h, as the [Router] does for every route. When a step panics,
+// the remaining steps are aborted and the chain continues with the error path of the [Responder] for a
+// [PanicError], or the handler set with [Responder.OnPanic]. A step may have written part of the response
+// before panicking, in which case the error response cannot change its status code. Panics with
+// [http.ErrAbortHandler] are not recovered, so that they still abort the response.
+func (rs Responder) Recover(h Handler) Handler {
+	return func(w http.ResponseWriter, r *http.Request) (next Handler) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(v)
+			}
+
+			PanicsRecovered.Add(1)
+			pe := &PanicError{Value: v, Stack: debug.Stack()}
+			if rs.panic != nil {
+				next = rs.panic(pe)
+				return
+			}
+			next = rs.err(pe)
+		}()
+
+		for step := h; step != nil; {
+			step = step(w, r)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,54 @@
+package hio
+
+import (
+	"context"
+	"net/http"
+	"runtime/pprof"
+)
+
+// ProfileLabels is a middleware setting the route, method, and tenant pprof labels on the goroutine serving the
+// request and those it starts, so that profiles can be sliced by endpoint, such as with go tool pprof -tagfocus.
+// The route is the one matched by the [Router], which adds the label once it matched the request when the
+// middleware wraps it, or the pattern matched by an [http.ServeMux], which is only known when the middleware wraps
+// the handlers registered on the mux rather than the mux itself. The tenant label is only set when tenant is not
+// nil and returns a tenant for the request.
+func ProfileLabels(tenant func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := r.Pattern
+			if rt, ok := RouteFromContext(r.Context()); ok {
+				route = rt.String()
+			}
+
+			labels := []string{"method", r.Method}
+			if route != "" {
+				labels = append(labels, "route", route)
+			} else {
+				r = r.WithContext(context.WithValue(r.Context(), profileLabelsKey{}, true))
+			}
+			if tenant != nil {
+				if t := tenant(r); t != "" {
+					labels = append(labels, "tenant", t)
+				}
+			}
+
+			pprof.Do(r.Context(), pprof.Labels(labels...), func(ctx context.Context) {
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+		})
+	}
+}
+
+type profileLabelsKey struct{}
+
+// serveLabeled serves the request matched to the route with h, adding the route pprof label when [ProfileLabels]
+// wraps the [Router] and could not set it before the request was matched.
+func serveLabeled(w http.ResponseWriter, r *http.Request, route Route, h http.Handler) {
+	if r.Context().Value(profileLabelsKey{}) == nil {
+		h.ServeHTTP(w, r)
+		return
+	}
+	pprof.Do(r.Context(), pprof.Labels("route", route.String()), func(ctx context.Context) {
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,65 @@
+package hio
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const _defaultPreflightMaxAge = 10 * time.Minute
+
+// allowedMethods returns the methods of the routes matching the request path, including HEAD when GET is
+// allowed and OPTIONS, or nil when no route matches the path.
+func (ro *Router) allowedMethods(r *http.Request) []string {
+	var methods []string
+
+	for _, e := range ro.routes.all() {
+		method := e.route.Method
+		if method == "" || slices.Contains(methods, method) {
+			continue
+		}
+		probe := *r
+		probe.Method = method
+		if _, p := ro.m.Handler(&probe); strings.HasPrefix(p, method+" ") {
+			methods = append(methods, method)
+		}
+	}
+
+	if len(methods) == 0 {
+		return nil
+	}
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if !slices.Contains(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	slices.Sort(methods)
+
+	return methods
+}
+
+// preflight answers an OPTIONS request with the allowed methods, and a CORS preflight request with the
+// Access-Control-Allow-Methods and Access-Control-Max-Age headers letting browsers cache the result.
+// The allowed origins and headers are left to the middlewares of the [Router].
+func (ro *Router) preflight(methods []string) http.Handler {
+	allow := strings.Join(methods, ", ")
+
+	maxAge := cmp.Or(ro.PreflightMaxAge, _defaultPreflightMaxAge)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Allow", allow)
+		if r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", allow)
+			if maxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.FormatInt(int64(maxAge/time.Second), 10))
+			}
+			h.Add("Vary", "Origin, Access-Control-Request-Method")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
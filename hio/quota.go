@@ -0,0 +1,136 @@
+package hio
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const _quotaSweepInterval = time.Minute
+
+// Quota is the rate limit of a tenant, allowing Burst requests at once refilled at Rate requests per second.
+type Quota struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// QuotaStore looks up the [Quota] of a tenant.
+type QuotaStore interface {
+	Quota(ctx context.Context, tenant string) (Quota, bool, error)
+}
+
+// QuotaMap is a static [QuotaStore]. The "*" entry applies to tenants without their own entry.
+type QuotaMap map[string]Quota
+
+// Quota returns the quota of the tenant or the "*" entry.
+func (m QuotaMap) Quota(_ context.Context, tenant string) (Quota, bool, error) {
+	if q, ok := m[tenant]; ok {
+		return q, true, nil
+	}
+	q, ok := m["*"]
+	return q, ok, nil
+}
+
+// TenantQuota is a middleware limiting requests per tenant, as returned by the tenant function, using token buckets
+// sized by the quota from the store. Requests from tenants without a quota are not limited. Responses carry the
+// RateLimit-Limit, RateLimit-Remaining, and RateLimit-Reset headers, and rejected requests get a 429 Too Many
+// Requests with Retry-After. The buckets of idle tenants are evicted once refilled, since a full bucket limits
+// like a new one, so that requests with made-up tenants do not grow memory; buckets of quotas with a zero Rate
+// never refill and are kept.
+func TenantQuota(tenant func(*http.Request) string, store QuotaStore) Middleware {
+	var (
+		mu      sync.Mutex
+		buckets = make(map[string]*tokenBucket)
+		swept   = time.Now()
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t := tenant(r)
+
+			q, ok, err := store.Quota(r.Context(), t)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			now := time.Now()
+
+			mu.Lock()
+			if now.Sub(swept) >= _quotaSweepInterval {
+				for t, b := range buckets {
+					if b.full(now) {
+						delete(buckets, t)
+					}
+				}
+				swept = now
+			}
+			b, ok := buckets[t]
+			if !ok {
+				b = &tokenBucket{tokens: float64(q.Burst), last: now}
+				buckets[t] = b
+			}
+			allowed, remaining, reset, retry := b.take(q, now)
+			mu.Unlock()
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(q.Burst))
+			h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(reset)))
+
+			if !allowed {
+				h.Set("Retry-After", strconv.Itoa(seconds(retry)))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type tokenBucket struct {
+	quota  Quota
+	tokens float64
+	last   time.Time
+}
+
+// full reports whether the bucket has refilled to the burst of its last quota by now.
+func (b *tokenBucket) full(now time.Time) bool {
+	return b.quota.Rate > 0 && b.tokens+now.Sub(b.last).Seconds()*b.quota.Rate >= float64(b.quota.Burst)
+}
+
+// take refills the bucket for the elapsed time and consumes a token if available. It returns whether a token
+// was consumed, the whole tokens remaining, the time until the bucket is full, and the time until the next token.
+func (b *tokenBucket) take(q Quota, now time.Time) (bool, int, time.Duration, time.Duration) {
+	b.quota = q
+	burst := float64(q.Burst)
+
+	if q.Rate > 0 {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*q.Rate)
+	}
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	var reset, retry time.Duration
+	if q.Rate > 0 {
+		reset = time.Duration((burst - b.tokens) / q.Rate * float64(time.Second))
+		retry = time.Duration(math.Max(0, 1-b.tokens) / q.Rate * float64(time.Second))
+	}
+
+	return allowed, int(b.tokens), reset, retry
+}
+
+func seconds(d time.Duration) int { return int(math.Ceil(d.Seconds())) }
@@ -0,0 +1,57 @@
+package hio
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type httpRedirectOption struct{ value int }
+
+// WithHTTPRedirect serves plain HTTP on the port when TLS is enabled, permanently redirecting every request to the
+// same URL over HTTPS. The redirecting server shuts down along with the server.
+func WithHTTPRedirect(port int) ServeOption { return httpRedirectOption{value: port} }
+
+func (o httpRedirectOption) apply(cfg *ServeConfig) { cfg.HTTPRedirectPort = o.value }
+
+// httpRedirect returns the server of [WithHTTPRedirect].
+func httpRedirect(cfg ServeConfig) *http.Server {
+	port := strconv.Itoa(cfg.Port)
+	if cfg.Listener != nil {
+		if addr, ok := cfg.Listener.Addr().(*net.TCPAddr); ok {
+			port = strconv.Itoa(addr.Port)
+		}
+	}
+
+	return &http.Server{
+		Addr:              net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.HTTPRedirectPort)),
+		Handler:           redirectHTTPS(port),
+		IdleTimeout:       cfg.IdleTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ErrorLog:          cfg.ErrorLog,
+	}
+}
+
+// redirectHTTPS redirects requests to the HTTPS port of their host, which is left out when it is 443.
+func redirectHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.Trim(r.Host, "[]")
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		switch {
+		case port != "443":
+			host = net.JoinHostPort(host, port)
+		case strings.Contains(host, ":"):
+			host = "[" + host + "]"
+		}
+
+		u := *r.URL
+		u.Scheme, u.Host = "https", host
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+	})
+}
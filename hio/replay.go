@@ -0,0 +1,153 @@
+package hio
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/inmem"
+)
+
+// Headers used by [ReplayProtection] and [SignRequest].
+const (
+	SignatureHeader = "X-Signature"
+	TimestampHeader = "X-Timestamp"
+	NonceHeader     = "X-Nonce"
+)
+
+const _maxSignedBodyBytes = 1 << 20
+
+// NonceStore remembers nonces until they expire.
+type NonceStore interface {
+	// Remember records the nonce until expires and reports false if it is already recorded.
+	Remember(ctx context.Context, nonce string, expires time.Time) (bool, error)
+	// Forget removes the nonce, such as when the request it was recorded for failed and may be retried.
+	Forget(ctx context.Context, nonce string) error
+}
+
+// NonceCache is an in-memory [NonceStore] keeping nonces in an [inmem.Repository] created [inmem.WithExpiry],
+// which removes them once expired.
+type NonceCache struct {
+	nonces *inmem.Repository[string, nonceEntry]
+}
+
+type nonceEntry struct {
+	Nonce   string
+	Expires time.Time
+}
+
+func (e nonceEntry) ID() string { return e.Nonce }
+
+// NewNonceCache returns a new [NonceCache] whose repository is also configured with opts, such as
+// [inmem.WithMemoryLimit].
+func NewNonceCache(opts ...inmem.RepositoryOption) *NonceCache {
+	opts = append(opts, inmem.WithExpiry(func(e nonceEntry) time.Time { return e.Expires }))
+	return &NonceCache{nonces: inmem.NewRepository[string, nonceEntry](opts...)}
+}
+
+// Remember records the nonce until expires and reports false if it is already recorded.
+func (c *NonceCache) Remember(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	err := c.nonces.Save(ctx, &nonceEntry{Nonce: nonce, Expires: expires})
+	if errors.Is(err, inmem.ErrAlreadyExists) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Forget removes the nonce.
+func (c *NonceCache) Forget(ctx context.Context, nonce string) error {
+	_, err := c.nonces.DeleteWhere(ctx, func(e nonceEntry) bool { return e.Nonce == nonce })
+	return err
+}
+
+// Stats returns the number and estimated size of the nonces recorded.
+func (c *NonceCache) Stats() inmem.Stats { return c.nonces.Stats() }
+
+// SignRequest signs the request for [ReplayProtection] with the secret, nonce, and time.
+// The request body is read and replaced so that it can still be sent.
+func SignRequest(r *http.Request, secret []byte, nonce string, t time.Time) error {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return err
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	ts := strconv.FormatInt(t.Unix(), 10)
+
+	r.Header.Set(TimestampHeader, ts)
+	r.Header.Set(NonceHeader, nonce)
+	r.Header.Set(SignatureHeader, hex.EncodeToString(sign(secret, ts, nonce, r, body)))
+
+	return nil
+}
+
+// ReplayProtection is a middleware rejecting requests with 401 Unauthorized unless they carry a valid
+// HMAC-SHA256 signature, as created by [SignRequest], over their timestamp, nonce, method, path, and body,
+// a timestamp within tolerance of the current time, and a nonce that was not used before.
+func ReplayProtection(secret []byte, store NonceStore, tolerance time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := verifySigned(w, r, secret, store, tolerance); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func verifySigned(w http.ResponseWriter, r *http.Request, secret []byte, store NonceStore, tolerance time.Duration) error {
+	ts, nonce := r.Header.Get(TimestampHeader), r.Header.Get(NonceHeader)
+
+	sig, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+	if err != nil || len(sig) == 0 || ts == "" || nonce == "" {
+		return errors.New("missing or malformed signature")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("malformed timestamp")
+	}
+
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return errors.New("stale timestamp")
+	}
+
+	body, err := io.ReadAll(MaxBytesReader(w, r.Body, _maxSignedBodyBytes))
+	if err != nil {
+		return errors.New("unreadable body")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if !hmac.Equal(sig, sign(secret, ts, nonce, r, body)) {
+		return errors.New("invalid signature")
+	}
+
+	ok, err := store.Remember(r.Context(), nonce, time.Unix(unix, 0).Add(tolerance))
+	if err != nil {
+		return errors.New("unable to verify nonce")
+	}
+	if !ok {
+		return errors.New("replayed request")
+	}
+
+	return nil
+}
+
+func sign(secret []byte, ts, nonce string, r *http.Request, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	io.WriteString(mac, ts+"."+nonce+"."+r.Method+" "+r.URL.RequestURI()+".")
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,98 @@
+package hio
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Route describes a route registered on a [Router].
+type Route struct {
+	Method     string
+	Pattern    string
+	Group      string
+	Deprecated bool
+}
+
+// String returns the route as an [http.ServeMux] pattern such as "GET /users/{id}".
+func (rt Route) String() string { return rt.Method + " " + rt.Pattern }
+
+type routeKey struct{}
+
+// NewRouteContext returns a copy of ctx holding a slot the [Router] fills with the matched [Route] at dispatch.
+// Middleware wrapping the [Router] uses it to read the route with [RouteFromContext] after the request is served.
+func NewRouteContext(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(routeKey{}).(*Route); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, routeKey{}, &Route{})
+}
+
+// RouteFromContext returns the [Route] matched by the [Router].
+func RouteFromContext(ctx context.Context) (Route, bool) {
+	rt, ok := ctx.Value(routeKey{}).(*Route)
+	if !ok || rt.Method == "" {
+		return Route{}, false
+	}
+	return *rt, true
+}
+
+func setRoute(r *http.Request, route Route) *http.Request {
+	rt, ok := r.Context().Value(routeKey{}).(*Route)
+	if !ok {
+		rt = &Route{}
+		r = r.WithContext(context.WithValue(r.Context(), routeKey{}, rt))
+	}
+	*rt = route
+	return r
+}
+
+type routeTable struct {
+	mu sync.RWMutex
+	m  map[string]*routeEntry
+}
+
+// routeEntry holds a registered route with the metadata attached to it after registration.
+type routeEntry struct {
+	route       Route
+	middlewares []string
+	doc         string
+	schemas     *routeSchemas
+	deprecation *Deprecation
+}
+
+func (t *routeTable) get(pattern string) (routeEntry, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	e, ok := t.m[pattern]
+	if !ok {
+		return routeEntry{}, false
+	}
+
+	return *e, true
+}
+
+func (t *routeTable) update(rt Route, fn func(*routeEntry)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.m[rt.String()]
+	if !ok {
+		e = &routeEntry{route: rt}
+		t.m[rt.String()] = e
+	}
+	fn(e)
+}
+
+func (t *routeTable) all() []routeEntry {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	entries := make([]routeEntry, 0, len(t.m))
+	for _, e := range t.m {
+		entries = append(entries, *e)
+	}
+
+	return entries
+}
@@ -0,0 +1,364 @@
+package hio
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const _defaultSchemaMaxBytes = 1 << 20
+
+// Schema is the subset of JSON Schema enforced by [Router.EnforceSchemas].
+// Schemas are either unmarshaled from JSON or derived from Go types with [SchemaFor].
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+
+	once sync.Once
+	re   *regexp.Regexp
+}
+
+// SchemaViolation is a value failing its [Schema], located by a JSON Pointer into the document.
+type SchemaViolation struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// SchemaError lists the violations of a document validated by [Schema.Validate].
+type SchemaError []SchemaViolation
+
+func (e SchemaError) Error() string {
+	msgs := make([]string, len(e))
+	for i, v := range e {
+		msgs[i] = v.Pointer + ": " + v.Message
+	}
+	return "schema violations: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks the JSON document against the schema and returns a [SchemaError] listing its violations.
+func (s *Schema) Validate(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return SchemaError{{Pointer: "", Message: "malformed JSON"}}
+	}
+	var errs SchemaError
+	s.validate("", v, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *Schema) validate(ptr string, v any, errs *SchemaError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, SchemaViolation{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if v == nil {
+		if !s.Nullable && s.Type != "" && s.Type != "null" {
+			fail("expected %s, got null", s.Type)
+		}
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		fail("value is not one of the allowed values")
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		if !s.allows("object") {
+			fail("expected %s, got object", s.Type)
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			pv := v[name]
+			if ps, ok := s.Properties[name]; ok {
+				ps.validate(ptr+"/"+escapePointer(name), pv, errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*errs = append(*errs, SchemaViolation{Pointer: ptr + "/" + escapePointer(name), Message: "unknown property"})
+			}
+		}
+	case []any:
+		if !s.allows("array") {
+			fail("expected %s, got array", s.Type)
+			return
+		}
+		if s.Items != nil {
+			for i, iv := range v {
+				s.Items.validate(ptr+"/"+strconv.Itoa(i), iv, errs)
+			}
+		}
+	case string:
+		if !s.allows("string") {
+			fail("expected %s, got string", s.Type)
+			return
+		}
+		if n := len([]rune(v)); s.MinLength != nil && n < *s.MinLength {
+			fail("length must be at least %d", *s.MinLength)
+		} else if s.MaxLength != nil && n > *s.MaxLength {
+			fail("length must be at most %d", *s.MaxLength)
+		}
+		if re := s.regexp(); re != nil && !re.MatchString(v) {
+			fail("value does not match pattern %q", s.Pattern)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				fail("value is not a date-time")
+			}
+		}
+	case float64:
+		if !s.allows("number") && !(s.Type == "integer" && v == math.Trunc(v)) {
+			fail("expected %s, got number", s.Type)
+			return
+		}
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("value must be at least %v", *s.Minimum)
+		} else if s.Maximum != nil && v > *s.Maximum {
+			fail("value must be at most %v", *s.Maximum)
+		}
+	case bool:
+		if !s.allows("boolean") {
+			fail("expected %s, got boolean", s.Type)
+		}
+	}
+}
+
+func (s *Schema) allows(typ string) bool { return s.Type == "" || s.Type == typ }
+
+func (s *Schema) regexp() *regexp.Regexp {
+	s.once.Do(func() {
+		if s.Pattern != "" {
+			s.re, _ = regexp.Compile(s.Pattern)
+		}
+	})
+	return s.re
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// SchemaFor derives a [Schema] from the Go type T as encoded by encoding/json.
+// Struct fields are named by their json tag and required unless they are pointers or tagged omitempty or omitzero.
+// The fields of embedded structs without a json name are inlined, and not required when embedded by pointer.
+func SchemaFor[T any]() *Schema {
+	return schemaOf(reflect.TypeFor[T](), make(map[reflect.Type]bool))
+}
+
+var (
+	_timeType          = reflect.TypeFor[time.Time]()
+	_textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	if t == _timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schemaOf(t.Elem(), seen)
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), seen), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", Nullable: true}
+	case reflect.Struct:
+		if seen[t] {
+			return &Schema{Type: "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		var embedded []*Schema
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" && opts == "" {
+				continue
+			}
+			if et, ok := embeddedStruct(f, name, opts); ok {
+				e := schemaOf(et, seen)
+				if f.Type.Kind() == reflect.Pointer {
+					e.Required = nil
+				}
+				embedded = append(embedded, e)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			s.Properties[name] = schemaOf(f.Type, seen)
+			if f.Type.Kind() != reflect.Pointer && !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+				s.Required = append(s.Required, name)
+			}
+		}
+
+		// The fields of embedded structs are inlined unless a shallower field has the same name.
+		for _, e := range embedded {
+			for name, p := range e.Properties {
+				if _, ok := s.Properties[name]; !ok {
+					s.Properties[name] = p
+					if slices.Contains(e.Required, name) {
+						s.Required = append(s.Required, name)
+					}
+				}
+			}
+		}
+		return s
+	}
+	return &Schema{}
+}
+
+// embeddedStruct returns the struct type of the field when encoding/json inlines its fields into the parent,
+// which it does for embedded structs without a JSON name or methods of their own.
+func embeddedStruct(f reflect.StructField, name, opts string) (reflect.Type, bool) {
+	if !f.Anonymous && !slices.Contains(strings.Split(opts, ","), "embed") || name != "" {
+		return nil, false
+	}
+	t := f.Type
+	if t.Kind() == reflect.Pointer {
+		if !f.IsExported() {
+			return nil, false
+		}
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == _timeType || t.Implements(_textMarshalerType) ||
+		reflect.PointerTo(t).Implements(_textMarshalerType) {
+		return nil, false
+	}
+	return t, true
+}
+
+type routeSchemas struct {
+	req, res *Schema
+}
+
+// Schema registers the JSON Schemas of the request and response bodies of the route, either of which may be nil.
+// They are enforced by the middleware returned by [Router.EnforceSchemas].
+func (ro *Router) Schema(method, pattern string, req, res *Schema) {
+	ro.routes.update(ro.route(strings.ToUpper(method), pattern), func(e *routeEntry) {
+		e.schemas = &routeSchemas{req: req, res: res}
+	})
+}
+
+// EnforceSchemas is a middleware validating JSON request bodies against the schemas registered with
+// [Router.Schema] for the matched route, responding with 422 Unprocessable Entity and the JSON Pointers
+// of the violations. In debug mode successful response bodies are buffered and validated as well, replacing
+// responses that drifted from their contract with a 422 so that it is caught during development.
+func (ro *Router) EnforceSchemas(debug bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rt, ok := RouteFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			e, ok := ro.routes.get(rt.String())
+			if !ok || e.schemas == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			s := e.schemas
+
+			if s.req != nil {
+				body, err := io.ReadAll(MaxBytesReader(w, r.Body, _defaultSchemaMaxBytes))
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+					return
+				}
+				if err := s.req.Validate(body); err != nil {
+					writeSchemaError(w, err)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			if !debug || s.res == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bw := &bufferedWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(bw, r)
+
+			if bw.status >= 200 && bw.status <= 299 && bw.buf.Len() > 0 && r.Method != http.MethodHead {
+				if err := s.res.Validate(bw.buf.Bytes()); err != nil {
+					w.Header().Del("Content-Length")
+					writeSchemaError(w, fmt.Errorf("response %w", err))
+					return
+				}
+			}
+
+			w.WriteHeader(bw.status)
+			w.Write(bw.buf.Bytes())
+		})
+	}
+}
+
+func writeSchemaError(w http.ResponseWriter, err error) {
+	var errs SchemaError
+	errors.As(err, &errs)
+	EncodeJSON(w, struct {
+		Error  string            `json:"error"`
+		Errors []SchemaViolation `json:"errors"`
+	}{Error: err.Error(), Errors: errs}, http.StatusUnprocessableEntity)
+}
+
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int)      { w.status = status }
+func (w *bufferedWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
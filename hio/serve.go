@@ -9,21 +9,24 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"net/netip"
 	"os"
-	"os/signal"
 	"strconv"
-	"syscall"
+	"strings"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 )
 
 const (
-	_defaultPort            = 8080
-	_defaultIdleTimeout     = 1 * time.Minute
-	_defaultReadTimeout     = 5 * time.Second
-	_defaultWriteTimeout    = 10 * time.Second
-	_defaultShutdownTimeout = 10 * time.Second
+	_defaultPort              = 8080
+	_defaultIdleTimeout       = 1 * time.Minute
+	_defaultReadTimeout       = 5 * time.Second
+	_defaultWriteTimeout      = 10 * time.Second
+	_defaultReadHeaderTimeout = 2 * time.Second
+	_defaultMaxHeaderBytes    = http.DefaultMaxHeaderBytes
+	_defaultShutdownTimeout   = 10 * time.Second
+	_defaultTicketKeys        = 3
 )
 
 func Serve(ctx context.Context, h http.Handler, opts ...ServeOption) error {
@@ -34,64 +37,263 @@ func Serve(ctx context.Context, h http.Handler, opts ...ServeOption) error {
 	}
 
 	if err := cfg.Validate(); err != nil {
-		return err
+		if cfg.tlsErr != nil && errors.Is(err, cfg.tlsErr) {
+			return &ServeError{Kind: ErrTLSSetup, Err: err}
+		}
+		return &ServeError{Kind: ErrInvalidConfig, Err: err}
+	}
+
+	if cfg.GRPC != nil {
+		h = grpcMux(cfg.GRPC, h)
+	}
+
+	var hc *health
+	if cfg.HealthChecks != nil {
+		hc = newHealth(cfg)
+		h = hc.handler(h)
+	}
+
+	var companions []companion
+
+	if len(cfg.AutoTLSDomains) > 0 {
+		var challenge *http.Server
+		cfg.TLS, challenge = autoTLS(cfg)
+		companions = append(companions, challenge)
 	}
 
 	srv := &http.Server{
-		Addr:         cfg.Addr(),
-		Handler:      h,
-		IdleTimeout:  cfg.IdleTimeout,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
-		TLSConfig:    cfg.TLS,
+		Addr:              cfg.Addr(),
+		Handler:           h,
+		IdleTimeout:       cfg.IdleTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		TLSConfig:         cfg.TLS,
+		ErrorLog:          cfg.ErrorLog,
+		HTTP2:             cfg.HTTP2,
+		BaseContext:       cfg.BaseContext,
+		ConnContext:       cfg.ConnContext,
+	}
+
+	var stapler *ocspStapler
+
+	if cfg.TLS != nil {
+		srv.TLSConfig = cfg.TLS.Clone()
+		srv.TLSConfig.SessionTicketsDisabled = cfg.DisableSessionTickets
+
+		if cfg.ClientAuth != nil {
+			srv.TLSConfig.ClientAuth = *cfg.ClientAuth
+		}
+
+		if cfg.ClientRevocation != RevocationOff {
+			srv.TLSConfig.VerifyConnection = newRevocationChecker(cfg.ClientRevocation).chain(srv.TLSConfig.VerifyConnection)
+		}
+
+		if cfg.OCSPStapleRefresh > 0 && len(srv.TLSConfig.Certificates) > 0 {
+			stapler = newOCSPStapler(srv.TLSConfig)
+		}
+
+		if cfg.tlsReload != nil {
+			srv.TLSConfig.GetConfigForClient = cfg.tlsReload.configForClient(srv.TLSConfig, stapler)
+		}
 	}
 
-	eg, egCtx, stop := withErrGroupNotifyContext(ctx)
+	if cfg.HTTPRedirectPort > 0 && srv.TLSConfig != nil {
+		companions = append(companions, httpRedirect(cfg))
+	}
+
+	if cfg.HTTP3 != nil {
+		h3 := cfg.HTTP3(http3Addr(cfg), srv.Handler, srv.TLSConfig)
+		srv.Handler = altSvc(h3, srv.Handler)
+		companions = append(companions, h3)
+	}
+
+	if (cfg.GRPC != nil || cfg.H2C) && cfg.TLS == nil {
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	var conns connTracker
+	srv.ConnState = conns.track
+	if cfg.ConnState != nil {
+		srv.ConnState = func(c net.Conn, s http.ConnState) {
+			conns.track(c, s)
+			cfg.ConnState(c, s)
+		}
+	}
+
+	for _, hub := range cfg.Hubs {
+		srv.RegisterOnShutdown(hub.Close)
+	}
+
+	sigCtx, stop := notifyContext(ctx, cfg)
 	defer stop()
 
-	eg.Go(func() error {
-		if err := open(srv); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	eg, egCtx := errgroup.WithContext(sigCtx)
+
+	// run runs fn in the group, passing its error to the callback of [WithOnError] as soon as it fails.
+	run := func(fn func() error) {
+		eg.Go(func() error {
+			err := fn()
+			if err != nil && cfg.OnError != nil {
+				cfg.OnError(err)
+			}
+			return err
+		})
+	}
+
+	run(func() error {
+		if err := open(srv, cfg); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			return err
 		}
 		return nil
 	})
 
-	eg.Go(func() error {
+	for _, c := range companions {
+		run(func() error {
+			if err := c.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("serving companion server: %w", bindError(err))
+			}
+			return nil
+		})
+	}
+
+	if srv.TLSConfig != nil && !cfg.DisableSessionTickets && cfg.SessionTicketRotation > 0 {
+		run(func() error {
+			return rotateSessionTicketKeys(egCtx, srv.TLSConfig, cfg.SessionTicketRotation, cfg.SessionTicketKeys)
+		})
+	}
+
+	if stapler != nil {
+		run(func() error { return stapler.run(egCtx, cfg.OCSPStapleRefresh) })
+	}
+
+	run(func() error {
 		<-egCtx.Done()
-		shutdownCtx, cancel := context.WithTimeout(ctx, cfg.ShutdownTimeout)
+
+		var report ShutdownReport
+		report.Trigger, report.Cause = shutdownTrigger(ctx, sigCtx, egCtx)
+
+		if hc != nil {
+			hc.draining.Store(true)
+		}
+		if cfg.DrainDelay > 0 && report.Trigger != ShutdownError {
+			srv.SetKeepAlivesEnabled(false)
+			time.Sleep(cfg.DrainDelay)
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.ShutdownTimeout)
 		defer cancel()
-		return srv.Shutdown(shutdownCtx)
+
+		start := time.Now()
+		err := srv.Shutdown(shutdownCtx)
+		report.Drain = time.Since(start)
+
+		if err != nil {
+			report.ForceClosed = conns.open()
+			srv.Close()
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = &ServeError{Kind: ErrShutdownTimeout, Err: err}
+			}
+		}
+
+		for _, c := range companions {
+			if c.Shutdown(shutdownCtx) != nil {
+				c.Close()
+			}
+		}
+
+		for _, hook := range cfg.ShutdownHooks {
+			if herr := hook(shutdownCtx); herr != nil {
+				report.HookErrors = append(report.HookErrors, herr)
+			}
+		}
+
+		if cfg.OnShutdown != nil {
+			cfg.OnShutdown(report)
+		}
+
+		return errors.Join(append([]error{err}, report.HookErrors...)...)
 	})
 
 	return eg.Wait()
 }
 
 // ServeConfig configures an HTTP server.
+// Its cfg tags allow it to be loaded with the hcfg package.
 type ServeConfig struct {
-	Host            string
-	Port            int
-	IdleTimeout     time.Duration
-	ReadTimeout     time.Duration
-	WriteTimeout    time.Duration
-	ShutdownTimeout time.Duration
-	ErrorLog        *log.Logger
-	TLS             *tls.Config
-	tlsErr          error
+	Host                  string        `cfg:"host"`
+	Port                  int           `cfg:"port"`
+	IdleTimeout           time.Duration `cfg:"idle_timeout"`
+	ReadTimeout           time.Duration `cfg:"read_timeout"`
+	WriteTimeout          time.Duration `cfg:"write_timeout"`
+	ReadHeaderTimeout     time.Duration `cfg:"read_header_timeout"`
+	MaxHeaderBytes        int           `cfg:"max_header_bytes"`
+	ShutdownTimeout       time.Duration `cfg:"shutdown_timeout"`
+	SessionTicketRotation time.Duration `cfg:"session_ticket_rotation"`
+	SessionTicketKeys     int           `cfg:"session_ticket_keys"`
+	DisableSessionTickets bool          `cfg:"disable_session_tickets"`
+	DisableSignalHandling bool          `cfg:"disable_signal_handling"`
+	H2C                   bool          `cfg:"h2c"`
+	OCSPStapleRefresh     time.Duration `cfg:"ocsp_staple_refresh"`
+	MaxConnsPerIP         int           `cfg:"max_conns_per_ip"`
+	UnixSocket            string        `cfg:"unix_socket"`
+	AutoTLSDomains        []string      `cfg:"auto_tls_domains"`
+	AutoTLSCache          string        `cfg:"auto_tls_cache"`
+	HTTPRedirectPort      int           `cfg:"http_redirect_port"`
+	LivenessPath          string        `cfg:"liveness_path"`
+	ReadinessPath         string        `cfg:"readiness_path"`
+	DrainDelay            time.Duration `cfg:"drain_delay"`
+	UnixSocketPerm        os.FileMode
+	ClientRevocation      RevocationPolicy
+	ClientAuth            *tls.ClientAuthType
+	ShutdownSignals       []os.Signal
+	TrustedProxies        []netip.Prefix
+	ErrorLog              *log.Logger
+	TLS                   *tls.Config
+	HTTP2                 *http.HTTP2Config
+	GRPC                  http.Handler
+	HTTP3                 HTTP3Creator
+	BaseContext           func(net.Listener) context.Context
+	ConnContext           func(context.Context, net.Conn) context.Context
+	OnListen              func(net.Addr)
+	ConnState             func(net.Conn, http.ConnState)
+	OnShutdown            func(ShutdownReport)
+	OnError               func(error)
+	ShutdownHooks         []func(context.Context) error
+	Hubs                  []*Hub
+	HealthChecks          *HealthChecks
+	Listener              net.Listener
+	tlsErr                error
+	tlsReload             *tlsReloader
 }
 
 // DefaultServeConfig returns a [ServeConfig] with default values.
 func DefaultServeConfig() ServeConfig {
 	return ServeConfig{
-		Port:            _defaultPort,
-		IdleTimeout:     _defaultIdleTimeout,
-		ReadTimeout:     _defaultReadTimeout,
-		WriteTimeout:    _defaultWriteTimeout,
-		ShutdownTimeout: _defaultShutdownTimeout,
+		Port:              _defaultPort,
+		IdleTimeout:       _defaultIdleTimeout,
+		ReadTimeout:       _defaultReadTimeout,
+		WriteTimeout:      _defaultWriteTimeout,
+		ReadHeaderTimeout: _defaultReadHeaderTimeout,
+		MaxHeaderBytes:    _defaultMaxHeaderBytes,
+		ShutdownTimeout:   _defaultShutdownTimeout,
 	}
 }
 
-// Addr returns the address the server listens on.
-func (c ServeConfig) Addr() string { return net.JoinHostPort(c.Host, strconv.Itoa(c.Port)) }
+// Addr returns the address the server listens on, which is the one of the listener or the unix socket path
+// if one is set.
+func (c ServeConfig) Addr() string {
+	if c.Listener != nil {
+		return c.Listener.Addr().String()
+	}
+	if c.UnixSocket != "" {
+		return c.UnixSocket
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
 
 // Override replaces field values with non-zero values from other.
 func (c *ServeConfig) Override(other ServeConfig) {
@@ -115,9 +317,153 @@ func (c *ServeConfig) Override(other ServeConfig) {
 		c.WriteTimeout = other.WriteTimeout
 	}
 
+	if other.ReadHeaderTimeout != 0 {
+		c.ReadHeaderTimeout = other.ReadHeaderTimeout
+	}
+
+	if other.MaxHeaderBytes != 0 {
+		c.MaxHeaderBytes = other.MaxHeaderBytes
+	}
+
 	if other.ShutdownTimeout != 0 {
 		c.ShutdownTimeout = other.ShutdownTimeout
 	}
+
+	if other.SessionTicketRotation != 0 {
+		c.SessionTicketRotation = other.SessionTicketRotation
+	}
+
+	if other.SessionTicketKeys != 0 {
+		c.SessionTicketKeys = other.SessionTicketKeys
+	}
+
+	if other.DisableSessionTickets {
+		c.DisableSessionTickets = true
+	}
+
+	if other.H2C {
+		c.H2C = true
+	}
+
+	if other.DisableSignalHandling {
+		c.DisableSignalHandling = true
+	}
+
+	if other.ShutdownSignals != nil {
+		c.ShutdownSignals = other.ShutdownSignals
+	}
+
+	if other.OCSPStapleRefresh != 0 {
+		c.OCSPStapleRefresh = other.OCSPStapleRefresh
+	}
+
+	if other.ClientRevocation != RevocationOff {
+		c.ClientRevocation = other.ClientRevocation
+	}
+
+	if other.ClientAuth != nil {
+		c.ClientAuth = other.ClientAuth
+	}
+
+	if other.MaxConnsPerIP != 0 {
+		c.MaxConnsPerIP = other.MaxConnsPerIP
+	}
+
+	if other.TrustedProxies != nil {
+		c.TrustedProxies = other.TrustedProxies
+	}
+
+	if other.HTTP2 != nil {
+		c.HTTP2 = other.HTTP2
+	}
+
+	if other.HTTP3 != nil {
+		c.HTTP3 = other.HTTP3
+	}
+
+	if other.ConnState != nil {
+		c.ConnState = other.ConnState
+	}
+
+	if other.OnError != nil {
+		c.OnError = other.OnError
+	}
+
+	if other.OnListen != nil {
+		c.OnListen = other.OnListen
+	}
+
+	if other.BaseContext != nil {
+		c.BaseContext = other.BaseContext
+	}
+
+	if other.ConnContext != nil {
+		c.ConnContext = other.ConnContext
+	}
+
+	if other.Listener != nil {
+		c.Listener = other.Listener
+	}
+
+	if other.UnixSocket != "" {
+		c.UnixSocket = other.UnixSocket
+	}
+
+	if other.UnixSocketPerm != 0 {
+		c.UnixSocketPerm = other.UnixSocketPerm
+	}
+
+	if other.AutoTLSDomains != nil {
+		c.AutoTLSDomains = other.AutoTLSDomains
+	}
+
+	if other.AutoTLSCache != "" {
+		c.AutoTLSCache = other.AutoTLSCache
+	}
+
+	if other.HTTPRedirectPort != 0 {
+		c.HTTPRedirectPort = other.HTTPRedirectPort
+	}
+
+	if other.LivenessPath != "" {
+		c.LivenessPath = other.LivenessPath
+	}
+
+	if other.ReadinessPath != "" {
+		c.ReadinessPath = other.ReadinessPath
+	}
+
+	if other.DrainDelay != 0 {
+		c.DrainDelay = other.DrainDelay
+	}
+
+	if other.HealthChecks != nil {
+		c.HealthChecks = other.HealthChecks
+	}
+
+	if other.ErrorLog != nil {
+		c.ErrorLog = other.ErrorLog
+	}
+
+	if other.TLS != nil || other.tlsErr != nil {
+		c.TLS, c.tlsErr, c.tlsReload = other.TLS, other.tlsErr, other.tlsReload
+	}
+
+	if other.GRPC != nil {
+		c.GRPC = other.GRPC
+	}
+
+	if other.OnShutdown != nil {
+		c.OnShutdown = other.OnShutdown
+	}
+
+	if other.ShutdownHooks != nil {
+		c.ShutdownHooks = other.ShutdownHooks
+	}
+
+	if other.Hubs != nil {
+		c.Hubs = other.Hubs
+	}
 }
 
 // Validate checks that the configuration is valid.
@@ -140,10 +486,70 @@ func (c *ServeConfig) Validate() error {
 		return errors.New("write timeout must be greater than 0")
 	}
 
+	if c.ReadHeaderTimeout <= 0 {
+		return errors.New("read header timeout must be greater than 0")
+	}
+
+	if c.ReadHeaderTimeout > c.ReadTimeout {
+		return errors.New("read header timeout must not exceed the read timeout")
+	}
+
+	if c.MaxHeaderBytes <= 0 {
+		return errors.New("max header bytes must be greater than 0")
+	}
+
 	if c.ShutdownTimeout <= 0 {
 		return errors.New("shutdown timeout must be greater than 0")
 	}
 
+	if c.SessionTicketRotation < 0 {
+		return errors.New("session ticket rotation must not be negative")
+	}
+
+	if c.OCSPStapleRefresh < 0 {
+		return errors.New("ocsp staple refresh must not be negative")
+	}
+
+	if c.MaxConnsPerIP < 0 {
+		return errors.New("max connections per ip must not be negative")
+	}
+
+	if c.DrainDelay < 0 {
+		return errors.New("drain delay must not be negative")
+	}
+
+	if c.HTTPRedirectPort < 0 {
+		return errors.New("http redirect port must not be negative")
+	}
+
+	if c.Listener != nil && c.UnixSocket != "" {
+		return errors.New("listener and unix socket must not both be set")
+	}
+
+	if len(c.AutoTLSDomains) > 0 && (c.TLS != nil || c.tlsErr != nil) {
+		return errors.New("auto tls and tls must not both be set")
+	}
+
+	if c.HTTP3 != nil && c.TLS == nil && c.tlsErr == nil && len(c.AutoTLSDomains) == 0 {
+		return errors.New("http3 requires tls")
+	}
+
+	if c.HTTP3 != nil && c.UnixSocket != "" {
+		return errors.New("http3 and unix socket must not both be set")
+	}
+
+	if c.HTTP2 != nil && c.HTTP2.MaxReadFrameSize != 0 && (c.HTTP2.MaxReadFrameSize < 16<<10 || c.HTTP2.MaxReadFrameSize > 16<<20-1) {
+		return errors.New("http2 max read frame size must be between 16KiB and 16MiB")
+	}
+
+	if c.ClientAuth != nil && c.TLS == nil && c.tlsErr == nil && len(c.AutoTLSDomains) == 0 {
+		return errors.New("client auth requires tls")
+	}
+
+	if c.ClientAuth != nil && *c.ClientAuth >= tls.VerifyClientCertIfGiven && c.TLS != nil && c.TLS.ClientCAs == nil {
+		return fmt.Errorf("client auth %s requires a certificate authority", *c.ClientAuth)
+	}
+
 	if c.tlsErr != nil {
 		return fmt.Errorf("tls must be configured correctly if provided: %w", c.tlsErr)
 	}
@@ -168,21 +574,45 @@ func (c *ServeConfig) setDefaultZeroValues() {
 		c.WriteTimeout = _defaultWriteTimeout
 	}
 
+	if c.ReadHeaderTimeout <= 0 {
+		c.ReadHeaderTimeout = min(_defaultReadHeaderTimeout, c.ReadTimeout)
+	}
+
+	if c.MaxHeaderBytes <= 0 {
+		c.MaxHeaderBytes = _defaultMaxHeaderBytes
+	}
+
 	if c.ShutdownTimeout <= 0 {
 		c.ShutdownTimeout = _defaultShutdownTimeout
 	}
+
+	if c.SessionTicketKeys <= 0 {
+		c.SessionTicketKeys = _defaultTicketKeys
+	}
 }
 
 // ServeOption applies options to a [ServeConfig].
 type ServeOption interface{ apply(*ServeConfig) }
 
 type (
-	hostOption            struct{ value string }
-	portOption            struct{ value int }
-	idleTimeoutOption     struct{ value time.Duration }
-	readTimeoutOption     struct{ value time.Duration }
-	writeTimeoutOption    struct{ value time.Duration }
-	shutdownTimeoutOption struct{ value time.Duration }
+	hostOption              struct{ value string }
+	portOption              struct{ value int }
+	idleTimeoutOption       struct{ value time.Duration }
+	readTimeoutOption       struct{ value time.Duration }
+	writeTimeoutOption      struct{ value time.Duration }
+	readHeaderTimeoutOption struct{ value time.Duration }
+	maxHeaderBytesOption    struct{ value int }
+	shutdownTimeoutOption   struct{ value time.Duration }
+	grpcOption              struct{ value http.Handler }
+	http2Option             struct{ value *http.HTTP2Config }
+	disableTicketsOption    struct{}
+	h2cOption               struct{}
+	clientAuthOption        struct{ value tls.ClientAuthType }
+
+	ticketRotationOption struct {
+		interval time.Duration
+		keys     int
+	}
 
 	tlsOption struct {
 		value *tls.Config
@@ -208,16 +638,69 @@ func WithReadTimeout(v time.Duration) ServeOption { return readTimeoutOption{val
 // WithWriteTimeout sets the write timeout.
 func WithWriteTimeout(v time.Duration) ServeOption { return writeTimeoutOption{value: v} }
 
+// WithReadHeaderTimeout sets the time allowed to read the request headers, guarding against slow clients holding
+// connections open. Defaults to 2 seconds, or the read timeout when it is shorter.
+func WithReadHeaderTimeout(v time.Duration) ServeOption { return readHeaderTimeoutOption{value: v} }
+
+// WithMaxHeaderBytes sets the maximum size of the request headers. Defaults to 1MiB.
+func WithMaxHeaderBytes(v int) ServeOption { return maxHeaderBytesOption{value: v} }
+
 // WithShutdownTimeout sets the shutdown timeout.
 func WithShutdownTimeout(v time.Duration) ServeOption { return shutdownTimeoutOption{value: v} }
 
+// WithGRPC serves gRPC requests, which are HTTP/2 requests with an application/grpc content type,
+// with the handler on the same port as regular HTTP traffic. A *grpc.Server can be used as the handler.
+// Without TLS, HTTP/2 is accepted over cleartext connections.
+func WithGRPC(v http.Handler) ServeOption { return grpcOption{value: v} }
+
+// WithHTTP2 tunes HTTP/2 connections, such as the maximum number of concurrent streams per connection, the
+// maximum frame size, the flow control windows, and the ping timeouts detecting dead connections.
+// Idle HTTP/2 connections are closed after the idle timeout.
+func WithHTTP2(v *http.HTTP2Config) ServeOption { return http2Option{value: v} }
+
+// WithH2C accepts HTTP/2 over cleartext connections when TLS is not configured, for clients with prior knowledge
+// of it such as gRPC clients inside a mesh. HTTP/1.1 is still served on the same port.
+func WithH2C() ServeOption { return h2cOption{} }
+
+// WithSessionTicketRotation rotates TLS session ticket keys on the interval, keeping the given number of keys
+// so that tickets issued with recent keys can still resume sessions.
+func WithSessionTicketRotation(interval time.Duration, keys int) ServeOption {
+	return ticketRotationOption{interval: interval, keys: keys}
+}
+
+// WithoutSessionTickets disables TLS session resumption with tickets.
+func WithoutSessionTickets() ServeOption { return disableTicketsOption{} }
+
 // WithConfig applies the provided configuration, replacing any existing values.
 func WithConfig(v ServeConfig) ServeOption { return configOption{value: v} }
 
 // WithOptions applies multiple [ServeOption]s.
 func WithOptions(v ...ServeOption) ServeOption { return configOptions{value: v} }
 
-// WithTLS configures TLS with the provided certificate authority, certificate, and key files.
+// WithServerTLS configures TLS with the provided certificate and key files without requesting client
+// certificates, such as behind a load balancer terminating them.
+func WithServerTLS(ceFile, keyFile string) ServeOption {
+	ce, err := tls.LoadX509KeyPair(ceFile, keyFile)
+	if err != nil {
+		return tlsOption{err: err}
+	}
+
+	return tlsOption{
+		value: &tls.Config{
+			Certificates: []tls.Certificate{ce},
+			MinVersion:   tls.VersionTLS12,
+			NextProtos:   []string{"h2", "http/1.1"},
+		},
+	}
+}
+
+// WithClientAuth sets the policy for client certificates of the TLS configuration, such as
+// [tls.VerifyClientCertIfGiven] to accept clients without one. [WithTLS] and [WithTLSReload] default to
+// [tls.RequireAndVerifyClientCert] and [WithServerTLS] to [tls.NoClientCert].
+func WithClientAuth(v tls.ClientAuthType) ServeOption { return clientAuthOption{value: v} }
+
+// WithTLS configures mutual TLS with the provided certificate authority, certificate, and key files, requiring
+// clients to present a certificate signed by the authority.
 func WithTLS(caFile, ceFile, keyFile string) ServeOption {
 	ce, err := tls.LoadX509KeyPair(ceFile, keyFile)
 	if err != nil {
@@ -245,29 +728,66 @@ func WithTLS(caFile, ceFile, keyFile string) ServeOption {
 	}
 }
 
-func (o hostOption) apply(cfg *ServeConfig)            { cfg.Host = o.value }
-func (o portOption) apply(cfg *ServeConfig)            { cfg.Port = o.value }
-func (o idleTimeoutOption) apply(cfg *ServeConfig)     { cfg.IdleTimeout = o.value }
-func (o readTimeoutOption) apply(cfg *ServeConfig)     { cfg.ReadTimeout = o.value }
-func (o writeTimeoutOption) apply(cfg *ServeConfig)    { cfg.WriteTimeout = o.value }
-func (o shutdownTimeoutOption) apply(cfg *ServeConfig) { cfg.ShutdownTimeout = o.value }
-func (o tlsOption) apply(cfg *ServeConfig)             { cfg.TLS, cfg.tlsErr = o.value, o.err }
-func (o configOption) apply(cfg *ServeConfig)          { cfg.Override(o.value) }
+func (o hostOption) apply(cfg *ServeConfig)              { cfg.Host = o.value }
+func (o portOption) apply(cfg *ServeConfig)              { cfg.Port = o.value }
+func (o idleTimeoutOption) apply(cfg *ServeConfig)       { cfg.IdleTimeout = o.value }
+func (o readTimeoutOption) apply(cfg *ServeConfig)       { cfg.ReadTimeout = o.value }
+func (o writeTimeoutOption) apply(cfg *ServeConfig)      { cfg.WriteTimeout = o.value }
+func (o shutdownTimeoutOption) apply(cfg *ServeConfig)   { cfg.ShutdownTimeout = o.value }
+func (o readHeaderTimeoutOption) apply(cfg *ServeConfig) { cfg.ReadHeaderTimeout = o.value }
+func (o maxHeaderBytesOption) apply(cfg *ServeConfig)    { cfg.MaxHeaderBytes = o.value }
+func (o grpcOption) apply(cfg *ServeConfig)              { cfg.GRPC = o.value }
+func (o http2Option) apply(cfg *ServeConfig)             { cfg.HTTP2 = o.value }
+func (o tlsOption) apply(cfg *ServeConfig)               { cfg.TLS, cfg.tlsErr, cfg.tlsReload = o.value, o.err, nil }
+func (o disableTicketsOption) apply(cfg *ServeConfig)    { cfg.DisableSessionTickets = true }
+func (o h2cOption) apply(cfg *ServeConfig)               { cfg.H2C = true }
+func (o clientAuthOption) apply(cfg *ServeConfig)        { cfg.ClientAuth = &o.value }
+func (o configOption) apply(cfg *ServeConfig)            { cfg.Override(o.value) }
+func (o ticketRotationOption) apply(cfg *ServeConfig) {
+	cfg.SessionTicketRotation, cfg.SessionTicketKeys = o.interval, o.keys
+}
 func (o configOptions) apply(cfg *ServeConfig) {
 	for _, opt := range o.value {
 		opt.apply(cfg)
 	}
 }
 
-func withErrGroupNotifyContext(ctx context.Context) (*errgroup.Group, context.Context, context.CancelFunc) {
-	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
-	eg, ctx := errgroup.WithContext(ctx)
-	return eg, ctx, cancel
-}
+func open(srv *http.Server, cfg ServeConfig) error {
+	ln := cfg.Listener
+	switch {
+	case ln != nil:
+	case cfg.UnixSocket != "":
+		var err error
+		if ln, err = listenUnix(cfg.UnixSocket, cfg.UnixSocketPerm); err != nil {
+			return bindError(err)
+		}
+	default:
+		var err error
+		if ln, err = net.Listen("tcp", srv.Addr); err != nil {
+			return bindError(err)
+		}
+	}
+
+	if cfg.OnListen != nil {
+		cfg.OnListen(ln.Addr())
+	}
+
+	if cfg.MaxConnsPerIP > 0 {
+		ln = newLimitListener(ln, cfg.MaxConnsPerIP, cfg.TrustedProxies)
+	}
 
-func open(srv *http.Server) error {
 	if srv.TLSConfig != nil {
-		return srv.ListenAndServeTLS("", "")
+		return srv.ServeTLS(ln, "", "")
 	}
-	return srv.ListenAndServe()
+	return srv.Serve(ln)
+}
+
+func grpcMux(grpc, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpc.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
 }
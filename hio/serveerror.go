@@ -0,0 +1,46 @@
+package hio
+
+import (
+	"errors"
+	"net"
+)
+
+var (
+	// ErrInvalidConfig classifies a [ServeError] of a configuration rejected by [ServeConfig.Validate].
+	ErrInvalidConfig = errors.New("invalid config")
+	// ErrTLSSetup classifies a [ServeError] of TLS failing to be configured, such as unreadable certificate files.
+	ErrTLSSetup = errors.New("tls setup failed")
+	// ErrBindFailed classifies a [ServeError] of a listener failing to bind, such as to a port already in use.
+	ErrBindFailed = errors.New("bind failed")
+	// ErrShutdownTimeout classifies a [ServeError] of requests still running at the end of the shutdown timeout.
+	ErrShutdownTimeout = errors.New("shutdown timed out")
+)
+
+// ServeError is an error of [Serve] classified by Kind, one of [ErrInvalidConfig], [ErrTLSSetup], [ErrBindFailed],
+// or [ErrShutdownTimeout], so that errors.Is matches both the kind and the underlying error.
+type ServeError struct {
+	Kind error
+	Err  error
+}
+
+func (e *ServeError) Error() string { return e.Kind.Error() + ": " + e.Err.Error() }
+
+// Unwrap returns the kind and the underlying error.
+func (e *ServeError) Unwrap() []error { return []error{e.Kind, e.Err} }
+
+type onErrorOption struct{ value func(error) }
+
+// WithOnError calls fn with each error failing the server or one of its background tasks as it happens, such as
+// to alert on it, while [Serve] shuts down before returning the errors.
+func WithOnError(fn func(error)) ServeOption { return onErrorOption{value: fn} }
+
+func (o onErrorOption) apply(cfg *ServeConfig) { cfg.OnError = o.value }
+
+// bindError classifies the errors of a listener failing to bind.
+func bindError(err error) error {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "listen" {
+		return &ServeError{Kind: ErrBindFailed, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,163 @@
+package hio
+
+import (
+	"expvar"
+	"math"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	_defaultShedRetryAfter = 1 * time.Second
+	_shedLatencyDecay      = 5 * time.Second
+)
+
+// ShedRequests counts the requests rejected by a [LoadShedder] by priority.
+// It is published through expvar as "hio.shed_requests".
+var ShedRequests = expvar.NewMap("hio.shed_requests")
+
+// Priority ranks routes for a [LoadShedder], which rejects the lowest priorities first.
+type Priority int
+
+// Priorities of routes. Routes of PriorityLow are shed as soon as a limit is reached, those of PriorityNormal
+// and PriorityHigh once the pressure is a quarter and a half over it, and those of PriorityCritical never.
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical
+)
+
+// String returns the name of the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	}
+	return strconv.Itoa(int(p))
+}
+
+// ShedOption configures a [LoadShedder].
+type ShedOption interface{ apply(*LoadShedder) }
+
+type (
+	maxInFlightOption   struct{ value int64 }
+	maxGoroutinesOption struct{ value int }
+	maxLatencyOption    struct{ value time.Duration }
+	shedRetryOption     struct{ value time.Duration }
+)
+
+// WithMaxInFlight sets the number of requests served at once through the [LoadShedder] past which it sheds.
+func WithMaxInFlight(n int64) ShedOption { return maxInFlightOption{value: n} }
+
+// WithMaxGoroutines sets the number of goroutines of the process past which the [LoadShedder] sheds.
+func WithMaxGoroutines(n int) ShedOption { return maxGoroutinesOption{value: n} }
+
+// WithMaxLatency sets the moving average of response latency past which the [LoadShedder] sheds.
+func WithMaxLatency(d time.Duration) ShedOption { return maxLatencyOption{value: d} }
+
+// WithShedRetryAfter sets the Retry-After of rejected requests. Defaults to 1s.
+func WithShedRetryAfter(d time.Duration) ShedOption { return shedRetryOption{value: d} }
+
+func (o maxInFlightOption) apply(s *LoadShedder)   { s.maxInFlight = o.value }
+func (o maxGoroutinesOption) apply(s *LoadShedder) { s.maxGoroutines = o.value }
+func (o maxLatencyOption) apply(s *LoadShedder)    { s.maxLatency = o.value }
+func (o shedRetryOption) apply(s *LoadShedder)     { s.retryAfter = o.value }
+
+// LoadShedder rejects requests with 503 Service Unavailable and Retry-After while the process is under pressure,
+// as measured by the requests in flight, the goroutines, and the moving average of response latency against
+// their limits, starting with the routes of the lowest [Priority]. Limits left unset are not monitored.
+type LoadShedder struct {
+	maxInFlight   int64
+	maxGoroutines int
+	maxLatency    time.Duration
+	retryAfter    time.Duration
+
+	inFlight atomic.Int64
+
+	mu      sync.Mutex
+	latency float64
+	sampled time.Time
+}
+
+// NewLoadShedder returns a [LoadShedder] with the limits.
+func NewLoadShedder(opts ...ShedOption) *LoadShedder {
+	s := &LoadShedder{retryAfter: _defaultShedRetryAfter}
+	for _, opt := range opts {
+		opt.apply(s)
+	}
+	return s
+}
+
+// Middleware returns a middleware shedding requests at the priority, such as for a route group registered with
+// [Router.Group]. Requests of every priority count towards the pressure.
+func (s *LoadShedder) Middleware(p Priority) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p < PriorityCritical && s.Pressure() >= 1+float64(p)/4 {
+				ShedRequests.Add(p.String(), 1)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds(s.retryAfter)))
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+
+			s.inFlight.Add(1)
+			start := time.Now()
+			defer func() {
+				s.inFlight.Add(-1)
+				s.observe(time.Since(start))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Pressure returns the highest ratio of the measures to their limits, 1 meaning a limit is reached.
+func (s *LoadShedder) Pressure() float64 {
+	var pressure float64
+	if s.maxInFlight > 0 {
+		pressure = max(pressure, float64(s.inFlight.Load())/float64(s.maxInFlight))
+	}
+	if s.maxGoroutines > 0 {
+		pressure = max(pressure, float64(runtime.NumGoroutine())/float64(s.maxGoroutines))
+	}
+	if s.maxLatency > 0 {
+		s.mu.Lock()
+		latency := s.decayed(time.Now())
+		s.mu.Unlock()
+		pressure = max(pressure, latency/float64(s.maxLatency))
+	}
+	return pressure
+}
+
+// observe adds the latency of a request to the moving average, weighting it by the time since the last one.
+func (s *LoadShedder) observe(d time.Duration) {
+	if s.maxLatency <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	w := math.Exp(-float64(now.Sub(s.sampled)) / float64(_shedLatencyDecay))
+	s.latency = w*s.latency + (1-w)*float64(d)
+	s.sampled = now
+}
+
+// decayed returns the moving average of latency decayed towards zero since the last request, so that shedding
+// every request does not keep the average high forever.
+func (s *LoadShedder) decayed(now time.Time) float64 {
+	return s.latency * math.Exp(-float64(now.Sub(s.sampled))/float64(_shedLatencyDecay))
+}
@@ -0,0 +1,163 @@
+package hio
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ShutdownTrigger is what made [Serve] shut down.
+type ShutdownTrigger int
+
+const (
+	// ShutdownContext is triggered by the cancellation of the context given to [Serve].
+	ShutdownContext ShutdownTrigger = iota
+	// ShutdownSignal is triggered by one of the shutdown signals, SIGINT and SIGTERM by default.
+	ShutdownSignal
+	// ShutdownError is triggered by the listener or a background task of the server failing.
+	ShutdownError
+)
+
+func (t ShutdownTrigger) String() string {
+	switch t {
+	case ShutdownContext:
+		return "context"
+	case ShutdownSignal:
+		return "signal"
+	case ShutdownError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// ShutdownReport describes how [Serve] shut down, telling clean exits apart from timeouts.
+// Cause is the cancellation cause or error behind the trigger, Drain is the time spent waiting for requests
+// to complete, ForceClosed is the number of connections closed when the shutdown timeout elapsed, and
+// HookErrors are the errors returned by hooks registered with [WithShutdownHook].
+type ShutdownReport struct {
+	Trigger     ShutdownTrigger
+	Cause       error
+	Drain       time.Duration
+	ForceClosed int
+	HookErrors  []error
+}
+
+// Clean reports whether every connection drained and every hook succeeded.
+func (r ShutdownReport) Clean() bool { return r.ForceClosed == 0 && len(r.HookErrors) == 0 }
+
+// LogValue implements [slog.LogValuer].
+func (r ShutdownReport) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("trigger", r.Trigger.String()),
+		slog.Duration("drain", r.Drain),
+		slog.Int("force_closed", r.ForceClosed),
+		slog.Bool("clean", r.Clean()),
+	}
+	if r.Cause != nil {
+		attrs = append(attrs, slog.String("cause", r.Cause.Error()))
+	}
+	for _, err := range r.HookErrors {
+		attrs = append(attrs, slog.String("hook_error", err.Error()))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+type (
+	shutdownReportOption  struct{ value func(ShutdownReport) }
+	shutdownHookOption    struct{ value func(context.Context) error }
+	shutdownSignalsOption struct{ value []os.Signal }
+	noSignalsOption       struct{}
+	drainDelayOption      struct{ value time.Duration }
+)
+
+// WithShutdownReport calls fn with the [ShutdownReport] once [Serve] shut down.
+func WithShutdownReport(fn func(ShutdownReport)) ServeOption { return shutdownReportOption{value: fn} }
+
+// WithShutdownHook runs fn once the server drained, in the order hooks were registered, with a context
+// expiring at the end of the shutdown timeout. Its error is listed in the [ShutdownReport].
+func WithShutdownHook(fn func(context.Context) error) ServeOption {
+	return shutdownHookOption{value: fn}
+}
+
+// WithDrainDelay keeps serving requests for d once the shutdown is triggered, with readiness of [WithHealthChecks]
+// failing and keep-alives disabled, before waiting for the requests to complete, so that load balancers stop
+// routing to the server first. The delay does not count toward the shutdown timeout and is skipped when the
+// server fails.
+func WithDrainDelay(d time.Duration) ServeOption { return drainDelayOption{value: d} }
+
+// WithShutdownSignals shuts the server down on the signals instead of SIGINT and SIGTERM.
+func WithShutdownSignals(sig ...os.Signal) ServeOption { return shutdownSignalsOption{value: sig} }
+
+// WithoutSignalHandling leaves signals to the caller, which shuts the server down by cancelling the context
+// given to [Serve].
+func WithoutSignalHandling() ServeOption { return noSignalsOption{} }
+
+func (o shutdownReportOption) apply(cfg *ServeConfig)  { cfg.OnShutdown = o.value }
+func (o shutdownSignalsOption) apply(cfg *ServeConfig) { cfg.ShutdownSignals = o.value }
+func (o noSignalsOption) apply(cfg *ServeConfig)       { cfg.DisableSignalHandling = true }
+func (o drainDelayOption) apply(cfg *ServeConfig)      { cfg.DrainDelay = o.value }
+func (o shutdownHookOption) apply(cfg *ServeConfig) {
+	cfg.ShutdownHooks = append(cfg.ShutdownHooks, o.value)
+}
+
+// notifyContext returns a context done once a shutdown signal is received, or ctx without signal handling.
+func notifyContext(ctx context.Context, cfg ServeConfig) (context.Context, context.CancelFunc) {
+	if cfg.DisableSignalHandling {
+		return context.WithCancel(ctx)
+	}
+
+	sig := cfg.ShutdownSignals
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	return signal.NotifyContext(ctx, sig...)
+}
+
+func shutdownTrigger(ctx, sigCtx, egCtx context.Context) (ShutdownTrigger, error) {
+	switch {
+	case ctx.Err() != nil:
+		return ShutdownContext, context.Cause(ctx)
+	case sigCtx.Err() != nil:
+		return ShutdownSignal, context.Cause(sigCtx)
+	}
+	return ShutdownError, context.Cause(egCtx)
+}
+
+// connTracker counts the open connections of a server through its ConnState hook, by state in [Connections].
+type connTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]http.ConnState
+}
+
+func (t *connTracker) track(c net.Conn, s http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if prev, ok := t.conns[c]; ok {
+		Connections.Add(prev.String(), -1)
+	}
+
+	switch s {
+	case http.StateHijacked, http.StateClosed:
+		delete(t.conns, c)
+	default:
+		if t.conns == nil {
+			t.conns = make(map[net.Conn]http.ConnState)
+		}
+		t.conns[c] = s
+		Connections.Add(s.String(), 1)
+	}
+}
+
+func (t *connTracker) open() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.conns)
+}
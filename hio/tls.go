@@ -0,0 +1,210 @@
+package hio
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"expvar"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const _defaultTLSReloadInterval = 1 * time.Minute
+
+// TLSReloadErrors counts the failed reloads of the files of [WithTLSReload], after which the previous
+// certificates are kept. It is published through expvar as "hio.tls_reload_errors".
+var TLSReloadErrors = expvar.NewInt("hio.tls_reload_errors")
+
+type tlsReloadOption struct {
+	value *tlsReloader
+	err   error
+}
+
+// WithTLSReload configures TLS like [WithTLS], checking the files for changes every interval, which defaults to
+// a minute, and reloading them on the next handshake so that rotated certificates are served without a restart.
+// Established connections are kept. Files that fail to load keep the previous certificates in use. With
+// [WithOCSPStapling], a staple is fetched for each reloaded certificate.
+func WithTLSReload(caFile, certFile, keyFile string, interval time.Duration) ServeOption {
+	if interval <= 0 {
+		interval = _defaultTLSReloadInterval
+	}
+
+	r := &tlsReloader{caFile: caFile, certFile: certFile, keyFile: keyFile, interval: interval}
+	if err := r.reload(); err != nil {
+		return tlsReloadOption{err: err}
+	}
+	r.checked = time.Now()
+
+	return tlsReloadOption{value: r}
+}
+
+func (o tlsReloadOption) apply(cfg *ServeConfig) {
+	cfg.tlsReload, cfg.tlsErr = o.value, o.err
+	cfg.TLS = nil
+	if o.value != nil {
+		cfg.TLS = o.value.config()
+	}
+}
+
+type tlsReloader struct {
+	caFile, certFile, keyFile string
+	interval                  time.Duration
+
+	mu      sync.Mutex
+	checked time.Time
+	mods    [3]time.Time
+
+	state atomic.Pointer[tlsFiles]
+}
+
+type tlsFiles struct {
+	cert tls.Certificate
+	pool *x509.CertPool
+}
+
+// config returns the configuration of [WithTLS] with the files last loaded.
+func (r *tlsReloader) config() *tls.Config {
+	f := r.state.Load()
+	return &tls.Config{
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		Certificates: []tls.Certificate{f.cert},
+		ClientCAs:    f.pool,
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+}
+
+// configForClient returns a GetConfigForClient hook serving the files last loaded with the settings of base,
+// which is the configuration of the server. When stapler is not nil, it serves the certificate through the
+// GetCertificate of base, and is handed each reloaded certificate to staple.
+func (r *tlsReloader) configForClient(base *tls.Config, stapler *ocspStapler) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	var (
+		mu     sync.Mutex
+		served = r.state.Load()
+	)
+
+	return func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.check()
+
+		c := base.Clone()
+		c.GetConfigForClient = nil
+
+		if stapler != nil {
+			mu.Lock()
+			f := r.state.Load()
+			if f != served {
+				stapler.replace(0, f.cert)
+				served = f
+			}
+			mu.Unlock()
+
+			c.ClientCAs = f.pool
+			return c, nil
+		}
+
+		f := r.state.Load()
+		c.ClientCAs = f.pool
+		c.GetCertificate = nil
+		c.Certificates = []tls.Certificate{f.cert}
+		return c, nil
+	}
+}
+
+// check reloads the files when they changed since the last check, at most once per interval.
+func (r *tlsReloader) check() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) < r.interval {
+		return
+	}
+	r.checked = time.Now()
+
+	mods, err := r.modTimes()
+	if err != nil {
+		TLSReloadErrors.Add(1)
+		return
+	}
+	if mods == r.mods {
+		return
+	}
+	if err := r.reload(); err != nil {
+		TLSReloadErrors.Add(1)
+	}
+}
+
+// reload loads the files, keeping the previous ones on error.
+func (r *tlsReloader) reload() error {
+	mods, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	ca, err := os.ReadFile(r.caFile)
+	if err != nil {
+		return err
+	}
+
+	pool := x509.NewCertPool()
+	if ok := pool.AppendCertsFromPEM(ca); !ok {
+		return errors.New("unable to append certs from PEM")
+	}
+
+	r.state.Store(&tlsFiles{cert: cert, pool: pool})
+	r.mods = mods
+	return nil
+}
+
+func (r *tlsReloader) modTimes() ([3]time.Time, error) {
+	var mods [3]time.Time
+	for i, name := range []string{r.caFile, r.certFile, r.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return mods, fmt.Errorf("checking tls file: %w", err)
+		}
+		mods[i] = fi.ModTime()
+	}
+	return mods, nil
+}
+
+func rotateSessionTicketKeys(ctx context.Context, cfg *tls.Config, interval time.Duration, n int) error {
+	var keys [][32]byte
+
+	rotate := func() error {
+		var key [32]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return fmt.Errorf("generating session ticket key: %w", err)
+		}
+		keys = append([][32]byte{key}, keys[:min(len(keys), n-1)]...)
+		cfg.SetSessionTicketKeys(keys)
+		return nil
+	}
+
+	if err := rotate(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := rotate(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package hio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+// RequestTransform replaces the body of a request, such as by decrypting or decompressing it, and may modify the
+// header of the request, which is a clone of the one received by [TransformRequest]. Errors are answered with
+// 400 Bad Request.
+type RequestTransform func(r *http.Request, body io.ReadCloser) (io.ReadCloser, error)
+
+// ResponseTransform returns a writer transforming the response body written to it into w, such as by wrapping it
+// in an envelope. It is called once the status code is known and may still modify the header. Closing the writer
+// flushes what it buffered to w. Transforms not applying to the response return a writer passing bytes through.
+type ResponseTransform func(r *http.Request, status int, h http.Header, w io.Writer) io.WriteCloser
+
+// TransformRequest is a middleware replacing request bodies with the transforms in order, so the first transform
+// reads the body as received and each one sees the header as modified by the previous ones. They run on a clone
+// of the request before the handler, so [DecodeJSON] reads the transformed body while outer middlewares keep
+// seeing the request as received.
+func TransformRequest(ts ...RequestTransform) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.Clone(r.Context())
+
+			body := r.Body
+			for _, t := range ts {
+				var err error
+				if body, err = t(r, body); err != nil {
+					http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+					return
+				}
+			}
+
+			r.Body = body
+			r.ContentLength = -1
+			r.Header.Del("Content-Length")
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TransformResponse is a middleware passing response bodies through the transforms in order, so the first transform
+// sees the body as written by the handler, including by a [Responder], and the last one writes to the client.
+// Bodies are streamed through the transforms and flushing the response flushes them first.
+// Responses to HEAD requests and responses without a body, such as 204 No Content, are not transformed.
+func TransformResponse(ts ...ResponseTransform) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tw := &transformWriter{ResponseWriter: w, r: r, ts: ts}
+			next.ServeHTTP(tw, r)
+			tw.close()
+		})
+	}
+}
+
+// BufferResponse returns a [ResponseTransform] buffering the whole body and replacing it with the result of fn,
+// for transforms that cannot stream such as rewriting a JSON document.
+func BufferResponse(fn func(r *http.Request, status int, h http.Header, body []byte) []byte) ResponseTransform {
+	return func(r *http.Request, status int, h http.Header, w io.Writer) io.WriteCloser {
+		return &bufferTransform{w: w, fn: func(body []byte) []byte { return fn(r, status, h, body) }}
+	}
+}
+
+// DecompressRequest is a [RequestTransform] decompressing bodies sent with the gzip or deflate Content-Encoding.
+// Limit the decompressed size with [LimitBody] in a later transform or [MaxBytesReader] in the handler.
+func DecompressRequest(r *http.Request, body io.ReadCloser) (io.ReadCloser, error) {
+	var (
+		rc  io.ReadCloser
+		err error
+	)
+
+	switch r.Header.Get("Content-Encoding") {
+	case "":
+		return body, nil
+	case "gzip", "x-gzip":
+		rc, err = gzip.NewReader(body)
+	case "deflate":
+		rc, err = zlib.NewReader(body)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", r.Header.Get("Content-Encoding"))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("decompressing body: %w", err)
+	}
+
+	r.Header.Del("Content-Encoding")
+
+	return readCloser{Reader: rc, close: func() error { return errors.Join(rc.Close(), body.Close()) }}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (rc readCloser) Close() error { return rc.close() }
+
+type transformWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	ts          []ResponseTransform
+	w           io.Writer
+	chain       []io.WriteCloser
+	wroteHeader bool
+}
+
+func (tw *transformWriter) WriteHeader(status int) {
+	if tw.wroteHeader {
+		return
+	}
+
+	if status >= 100 && status <= 199 {
+		tw.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	tw.wroteHeader = true
+	tw.w = tw.ResponseWriter
+
+	if tw.r.Method != http.MethodHead && status != http.StatusNoContent && status != http.StatusNotModified {
+		for _, t := range slices.Backward(tw.ts) {
+			wc := t(tw.r, status, tw.Header(), tw.w)
+			tw.chain = append(tw.chain, wc)
+			tw.w = wc
+		}
+		tw.Header().Del("Content-Length")
+	}
+
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *transformWriter) Write(p []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if len(tw.chain) > 0 {
+		addBodySize(tw.r.Context(), len(p))
+	}
+	return tw.w.Write(p)
+}
+
+func (tw *transformWriter) Flush() {
+	for _, wc := range slices.Backward(tw.chain) {
+		if f, ok := wc.(interface{ Flush() error }); ok {
+			f.Flush()
+		}
+	}
+	http.NewResponseController(tw.ResponseWriter).Flush()
+}
+
+func (tw *transformWriter) Unwrap() http.ResponseWriter { return tw.ResponseWriter }
+
+func (tw *transformWriter) close() {
+	for _, wc := range slices.Backward(tw.chain) {
+		wc.Close()
+	}
+}
+
+type bufferTransform struct {
+	w   io.Writer
+	fn  func([]byte) []byte
+	buf bytes.Buffer
+}
+
+func (t *bufferTransform) Write(p []byte) (int, error) { return t.buf.Write(p) }
+
+func (t *bufferTransform) Close() error {
+	_, err := t.w.Write(t.fn(t.buf.Bytes()))
+	return err
+}
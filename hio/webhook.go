@@ -0,0 +1,221 @@
+package hio
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookScheme verifies the signature of a webhook body with the secret and returns a value unique
+// to the delivery that is used as a nonce for replay protection.
+type WebhookScheme func(h http.Header, body, secret []byte) (nonce string, err error)
+
+// GitHubWebhook verifies the X-Hub-Signature-256 header sent by GitHub, using the X-GitHub-Delivery header
+// as the nonce.
+func GitHubWebhook(h http.Header, body, secret []byte) (string, error) {
+	sig, ok := strings.CutPrefix(h.Get("X-Hub-Signature-256"), "sha256=")
+	if !ok {
+		return "", errors.New("missing signature")
+	}
+	if err := verifyHex(sig, secret, body); err != nil {
+		return "", err
+	}
+	delivery := h.Get("X-GitHub-Delivery")
+	if delivery == "" {
+		return "", errors.New("missing delivery id")
+	}
+	return delivery, nil
+}
+
+// StripeWebhook returns a [WebhookScheme] verifying the Stripe-Signature header sent by Stripe,
+// rejecting signatures whose timestamp is not within tolerance of the current time.
+func StripeWebhook(tolerance time.Duration) WebhookScheme {
+	return func(h http.Header, body, secret []byte) (string, error) {
+		var (
+			ts   string
+			sigs []string
+		)
+
+		for part := range strings.SplitSeq(h.Get("Stripe-Signature"), ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch k {
+			case "t":
+				ts = v
+			case "v1":
+				sigs = append(sigs, v)
+			}
+		}
+
+		if err := verifyTimestamp(ts, tolerance); err != nil {
+			return "", err
+		}
+
+		payload := append([]byte(ts+"."), body...)
+
+		for _, sig := range sigs {
+			if verifyHex(sig, secret, payload) == nil {
+				return sig, nil
+			}
+		}
+
+		return "", errors.New("invalid signature")
+	}
+}
+
+// SlackWebhook returns a [WebhookScheme] verifying the X-Slack-Signature header sent by Slack,
+// rejecting signatures whose timestamp is not within tolerance of the current time.
+func SlackWebhook(tolerance time.Duration) WebhookScheme {
+	return func(h http.Header, body, secret []byte) (string, error) {
+		ts := h.Get("X-Slack-Request-Timestamp")
+		if err := verifyTimestamp(ts, tolerance); err != nil {
+			return "", err
+		}
+
+		sig, ok := strings.CutPrefix(h.Get("X-Slack-Signature"), "v0=")
+		if !ok {
+			return "", errors.New("missing signature")
+		}
+
+		if err := verifyHex(sig, secret, append([]byte("v0:"+ts+":"), body...)); err != nil {
+			return "", err
+		}
+
+		return sig, nil
+	}
+}
+
+type webhookBodyKey struct{}
+
+// WebhookBody returns the raw body of a request verified by [VerifyWebhook].
+func WebhookBody(ctx context.Context) []byte {
+	b, _ := ctx.Value(webhookBodyKey{}).([]byte)
+	return b
+}
+
+// VerifyWebhook is a middleware rejecting webhook requests whose signature does not verify with 401 Unauthorized.
+// Bodies larger than maxBytes are rejected. When nonces is not nil, redelivered requests are rejected too, unless
+// the delivery they repeat got a response other than 2xx, so that senders can retry failed deliveries. The raw body is available through [WebhookBody] and the request body is replaced so [DecodeJSON] can read it.
+func VerifyWebhook(scheme WebhookScheme, secret []byte, nonces NonceStore, maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(MaxBytesReader(w, r.Body, maxBytes))
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			nonce, err := scheme(r.Header, body, secret)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if nonces != nil {
+				ok, err := nonces.Remember(r.Context(), nonce, time.Now().Add(24*time.Hour))
+				if err != nil || !ok {
+					http.Error(w, "replayed request", http.StatusUnauthorized)
+					return
+				}
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), webhookBodyKey{}, body))
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			if nonces == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sw := &statusWriter{ResponseWriter: w}
+			served := false
+			defer func() {
+				if !served || sw.status > 299 {
+					nonces.Forget(context.WithoutCancel(r.Context()), nonce)
+				}
+			}()
+
+			next.ServeHTTP(sw, r)
+			served = true
+		})
+	}
+}
+
+// statusWriter records the final status code written through it, which is 200 OK when none is written.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 && status >= 200 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(p)
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter { return sw.ResponseWriter }
+
+// AckWebhook returns an [http.Handler] handing the verified webhook body to enqueue, such as a
+// function enqueuing an hjob job, and responding with 202 Accepted without waiting for processing.
+// When enqueuing fails it responds with 503 Service Unavailable so that the sender retries.
+func AckWebhook(enqueue func(ctx context.Context, r *http.Request, body []byte) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := WebhookBody(r.Context())
+		if body == nil {
+			var err error
+			if body, err = io.ReadAll(r.Body); err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := enqueue(context.WithoutCancel(r.Context()), r, body); err != nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func verifyHex(sig string, secret, payload []byte) error {
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return errors.New("malformed signature")
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
+
+func verifyTimestamp(ts string, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("missing or malformed timestamp")
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return errors.New("stale timestamp")
+	}
+	return nil
+}
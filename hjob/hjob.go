@@ -0,0 +1,272 @@
+// Package hjob provides an in-process background job queue with retries and a worker pool.
+package hjob
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultWorkers      = 4
+	_defaultMaxAttempts  = 5
+	_defaultPollInterval = 1 * time.Second
+	_defaultBaseBackoff  = 1 * time.Second
+	_defaultMaxBackoff   = 1 * time.Minute
+)
+
+// Job is a unit of work processed by a [Queue].
+type Job struct {
+	Key         string
+	Kind        string
+	Payload     []byte
+	Attempt     int
+	MaxAttempts int
+	RunAt       time.Time
+	LastError   string
+}
+
+// ID returns the job key.
+func (j Job) ID() string { return j.Key }
+
+// Handler processes a job. Returning an error schedules a retry until the job exhausts its attempts.
+type Handler func(ctx context.Context, job Job) error
+
+// Queue dispatches enqueued jobs to handlers registered by kind using a pool of workers.
+type Queue struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	store    Store
+	workers  int
+	poll     time.Duration
+	backoff  func(attempt int) time.Duration
+	wake     chan struct{}
+}
+
+// NewQueue returns a new [Queue].
+func NewQueue(opts ...QueueOption) *Queue {
+	q := &Queue{
+		handlers: make(map[string]Handler),
+		store:    NewMemoryStore(),
+		workers:  _defaultWorkers,
+		poll:     _defaultPollInterval,
+		backoff:  exponentialBackoff,
+		wake:     make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt.apply(q)
+	}
+
+	return q
+}
+
+// Handle registers the handler for the given job kind.
+func (q *Queue) Handle(kind string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.handlers[kind] = h
+}
+
+// Enqueue stores a job of the given kind and returns its key.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload []byte, opts ...EnqueueOption) (string, error) {
+	var b [16]byte
+	rand.Read(b[:])
+
+	job := Job{
+		Key:         hex.EncodeToString(b[:]),
+		Kind:        kind,
+		Payload:     payload,
+		MaxAttempts: _defaultMaxAttempts,
+		RunAt:       time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt.apply(&job)
+	}
+
+	if err := q.store.Push(ctx, job); err != nil {
+		return "", fmt.Errorf("enqueuing: %w", err)
+	}
+
+	q.notify()
+
+	return job.Key, nil
+}
+
+// Run starts the workers and blocks until ctx is canceled and every in-flight job has returned.
+// In-flight jobs keep running with a context that is not canceled by ctx so that they can drain, while jobs
+// that are ready but not started are left in the store. A handler that panics fails its attempt.
+func (q *Queue) Run(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for range q.workers {
+		wg.Go(func() {
+			if err := q.work(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		})
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (q *Queue) work(ctx context.Context) error {
+	ticker := time.NewTicker(q.poll)
+	defer ticker.Stop()
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		job, ok, err := q.store.Pop(ctx, time.Now())
+		if err != nil {
+			return fmt.Errorf("popping job: %w", err)
+		}
+
+		if ok {
+			if err := q.run(context.WithoutCancel(ctx), job); err != nil {
+				return err
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job Job) error {
+	q.mu.RLock()
+	h, ok := q.handlers[job.Kind]
+	q.mu.RUnlock()
+
+	job.Attempt++
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler registered for kind %q", job.Kind)
+	} else {
+		err = call(ctx, h, job)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	job.LastError = err.Error()
+
+	if job.Attempt >= job.MaxAttempts {
+		if err := q.store.Bury(ctx, job); err != nil {
+			return fmt.Errorf("burying job: %w", err)
+		}
+		return nil
+	}
+
+	job.RunAt = time.Now().Add(q.backoff(job.Attempt))
+
+	if err := q.store.Push(ctx, job); err != nil {
+		return fmt.Errorf("retrying job: %w", err)
+	}
+
+	return nil
+}
+
+// call runs the handler, turning a panic into an error so that the job is retried like a failed one.
+func call(ctx context.Context, h Handler, job Job) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("handler panicked: %v", v)
+		}
+	}()
+	return h(ctx, job)
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func exponentialBackoff(attempt int) time.Duration {
+	d := _defaultBaseBackoff << (attempt - 1)
+	if d <= 0 || d > _defaultMaxBackoff {
+		return _defaultMaxBackoff
+	}
+	return d
+}
+
+// QueueOption applies options to a [Queue].
+type QueueOption interface{ apply(*Queue) }
+
+type (
+	storeOption        struct{ value Store }
+	workersOption      struct{ value int }
+	pollIntervalOption struct{ value time.Duration }
+	backoffOption      struct{ value func(int) time.Duration }
+)
+
+// WithStore sets the [Store] used to persist jobs.
+func WithStore(v Store) QueueOption { return storeOption{value: v} }
+
+// WithWorkers sets the number of workers.
+func WithWorkers(v int) QueueOption { return workersOption{value: v} }
+
+// WithPollInterval sets how often idle workers check the store for delayed jobs.
+func WithPollInterval(v time.Duration) QueueOption { return pollIntervalOption{value: v} }
+
+// WithBackoff sets the delay before a failed job is retried given its attempt count.
+func WithBackoff(v func(attempt int) time.Duration) QueueOption { return backoffOption{value: v} }
+
+func (o storeOption) apply(q *Queue)   { q.store = o.value }
+func (o backoffOption) apply(q *Queue) { q.backoff = o.value }
+func (o workersOption) apply(q *Queue) {
+	if o.value > 0 {
+		q.workers = o.value
+	}
+}
+func (o pollIntervalOption) apply(q *Queue) {
+	if o.value > 0 {
+		q.poll = o.value
+	}
+}
+
+// EnqueueOption applies options to a [Job] when it is enqueued.
+type EnqueueOption interface{ apply(*Job) }
+
+type (
+	delayOption       struct{ value time.Duration }
+	maxAttemptsOption struct{ value int }
+)
+
+// WithDelay postpones the first run of the job.
+func WithDelay(v time.Duration) EnqueueOption { return delayOption{value: v} }
+
+// WithMaxAttempts sets how many times the job is run before it is buried.
+func WithMaxAttempts(v int) EnqueueOption { return maxAttemptsOption{value: v} }
+
+func (o delayOption) apply(j *Job) { j.RunAt = j.RunAt.Add(o.value) }
+func (o maxAttemptsOption) apply(j *Job) {
+	if o.value > 0 {
+		j.MaxAttempts = o.value
+	}
+}
@@ -0,0 +1,74 @@
+package hjob
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/inmem"
+)
+
+// Store persists jobs for a [Queue].
+type Store interface {
+	// Push stores a job to be run at or after its RunAt time.
+	Push(ctx context.Context, job Job) error
+	// Pop removes and returns the job with the earliest RunAt time not after now.
+	Pop(ctx context.Context, now time.Time) (Job, bool, error)
+	// Bury stores a job that exhausted its attempts.
+	Bury(ctx context.Context, job Job) error
+}
+
+// MemoryStore is the default in-memory [Store]. Buried jobs are kept in an [inmem.Repository].
+type MemoryStore struct {
+	mu      sync.Mutex
+	pending jobHeap
+	dead    *inmem.Repository[string, Job]
+}
+
+// NewMemoryStore returns a new [MemoryStore].
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{dead: inmem.NewRepository[string, Job]()}
+}
+
+// Push stores a job to be run at or after its RunAt time.
+func (s *MemoryStore) Push(ctx context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	heap.Push(&s.pending, job)
+
+	return nil
+}
+
+// Pop removes and returns the job with the earliest RunAt time not after now.
+func (s *MemoryStore) Pop(ctx context.Context, now time.Time) (Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) == 0 || s.pending[0].RunAt.After(now) {
+		return Job{}, false, nil
+	}
+
+	return heap.Pop(&s.pending).(Job), true, nil
+}
+
+// Bury stores a job that exhausted its attempts.
+func (s *MemoryStore) Bury(ctx context.Context, job Job) error { return s.dead.Save(ctx, &job) }
+
+// DeadLetters returns the [inmem.Repository] holding buried jobs.
+func (s *MemoryStore) DeadLetters() *inmem.Repository[string, Job] { return s.dead }
+
+type jobHeap []Job
+
+func (h jobHeap) Len() int           { return len(h) }
+func (h jobHeap) Less(i, j int) bool { return h[i].RunAt.Before(h[j].RunAt) }
+func (h jobHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x any)        { *h = append(*h, x.(Job)) }
+func (h *jobHeap) Pop() any {
+	old := *h
+	n := len(old)
+	job := old[n-1]
+	*h = old[:n-1]
+	return job
+}
@@ -0,0 +1,137 @@
+package hlog
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const _defaultCaptureMax = 100
+
+type debugCaptureOption struct {
+	slow time.Duration
+	max  int
+}
+
+// WithDebugCapture buffers the Debug records logged during a request through a [CaptureHandler], keeping up to max
+// of them, and attaches them to the request log line under "debug" only when the request ends with a 5xx status
+// or takes longer than slow, giving context for failures without verbose logs for successes.
+// A zero slow threshold only attaches them to failures.
+func WithDebugCapture(slow time.Duration, max int) Option {
+	return debugCaptureOption{slow: slow, max: max}
+}
+
+func (o debugCaptureOption) apply(cfg *config) {
+	cfg.capture, cfg.slow, cfg.captureMax = true, o.slow, o.max
+	if cfg.captureMax <= 0 {
+		cfg.captureMax = _defaultCaptureMax
+	}
+}
+
+type captureKey struct{}
+
+type captureBuffer struct {
+	mu      sync.Mutex
+	max     int
+	records []slog.Attr
+	dropped int
+}
+
+func (b *captureBuffer) add(r slog.Record, attrs []slog.Attr) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.records) >= b.max {
+		b.dropped++
+		return
+	}
+
+	rec := []slog.Attr{
+		slog.Time(slog.TimeKey, r.Time),
+		slog.String(slog.MessageKey, r.Message),
+	}
+
+	b.records = append(b.records, slog.Attr{Key: strconv.Itoa(len(b.records)), Value: slog.GroupValue(append(rec, attrs...)...)})
+}
+
+func (b *captureBuffer) attr() (slog.Attr, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.records) == 0 {
+		return slog.Attr{}, false
+	}
+
+	records := slices.Clone(b.records)
+	if b.dropped > 0 {
+		records = append(records, slog.Int("dropped", b.dropped))
+	}
+
+	return slog.Attr{Key: "debug", Value: slog.GroupValue(records...)}, true
+}
+
+// CaptureHandler is a [slog.Handler] buffering records below the Info level logged with the context of a request
+// served by a [Middleware] configured with [WithDebugCapture], and passing every other record to the wrapped handler.
+// Handlers log through a logger using it to have their Debug records captured.
+type CaptureHandler struct {
+	next slog.Handler
+	goas []groupOrAttrs
+}
+
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewCaptureHandler returns a [CaptureHandler] wrapping next.
+func NewCaptureHandler(next slog.Handler) *CaptureHandler { return &CaptureHandler{next: next} }
+
+// Enabled reports whether the wrapped handler handles the level, and always does for captured requests.
+func (h *CaptureHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if _, ok := ctx.Value(captureKey{}).(*captureBuffer); ok && level < slog.LevelInfo {
+		return true
+	}
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle buffers records below the Info level of captured requests and passes the others to the wrapped handler.
+func (h *CaptureHandler) Handle(ctx context.Context, r slog.Record) error {
+	if b, ok := ctx.Value(captureKey{}).(*captureBuffer); ok && r.Level < slog.LevelInfo {
+		var attrs []slog.Attr
+		r.Attrs(func(a slog.Attr) bool {
+			attrs = append(attrs, a)
+			return true
+		})
+		b.add(r, nest(h.goas, attrs))
+		return nil
+	}
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs returns a [CaptureHandler] adding the attributes to every record.
+func (h *CaptureHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &CaptureHandler{next: h.next.WithAttrs(attrs), goas: append(slices.Clip(h.goas), groupOrAttrs{attrs: attrs})}
+}
+
+// WithGroup returns a [CaptureHandler] nesting the attributes of every record in the group.
+func (h *CaptureHandler) WithGroup(name string) slog.Handler {
+	return &CaptureHandler{next: h.next.WithGroup(name), goas: append(slices.Clip(h.goas), groupOrAttrs{group: name})}
+}
+
+// nest returns the attributes of a record nested in the groups and preceded by the attributes added to a handler.
+func nest(goas []groupOrAttrs, attrs []slog.Attr) []slog.Attr {
+	for _, goa := range slices.Backward(goas) {
+		if goa.group != "" {
+			attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+			continue
+		}
+		attrs = append(slices.Clone(goa.attrs), attrs...)
+	}
+	return attrs
+}
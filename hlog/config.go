@@ -0,0 +1,64 @@
+package hlog
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Config configures logging from the cfg tags read by hcfg.Load, such as nested under "log" in the configuration
+// of a service alongside hio.ServeConfig.
+type Config struct {
+	Level           slog.Level    `cfg:"level" default:"INFO"`
+	Format          string        `cfg:"format" default:"json"`
+	RedactKeys      []string      `cfg:"redact_keys"`
+	ECS             bool          `cfg:"ecs"`
+	Steps           bool          `cfg:"steps"`
+	DebugCapture    bool          `cfg:"debug_capture"`
+	SlowRequest     time.Duration `cfg:"slow_request"`
+	SamplePerSecond int           `cfg:"sample_per_second"`
+}
+
+// Validate reports whether the format is json or text.
+func (c Config) Validate() error {
+	if c.Format != "json" && c.Format != "text" {
+		return fmt.Errorf("format %q: expected json or text", c.Format)
+	}
+	return nil
+}
+
+// Logger returns a logger writing records at the level or above to w in the format, with the values of the
+// redact keys masked by a [Redactor] when any are set.
+func (c Config) Logger(w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: c.Level}
+
+	var h slog.Handler = slog.NewJSONHandler(w, opts)
+	if c.Format == "text" {
+		h = slog.NewTextHandler(w, opts)
+	}
+	if len(c.RedactKeys) > 0 {
+		h = NewRedactor(h, WithRedactKeys(c.RedactKeys...))
+	}
+	return slog.New(h)
+}
+
+// Options returns the options of [Middleware] set by the configuration.
+func (c Config) Options() []Option {
+	names := DefaultFieldNames
+	if c.ECS {
+		names = ECSFieldNames
+	}
+
+	opts := []Option{WithFieldNames(names)}
+	if c.Steps {
+		opts = append(opts, WithSteps())
+	}
+	if c.DebugCapture {
+		opts = append(opts, WithDebugCapture(c.SlowRequest, 0))
+	}
+	if c.SamplePerSecond > 0 {
+		opts = append(opts, WithAdaptiveSampling(c.SamplePerSecond, c.SlowRequest))
+	}
+	return opts
+}
@@ -0,0 +1,72 @@
+package hlog
+
+// FieldNames are the keys of the attributes logged by [Middleware]. When SchemaVersionKey is set, every request
+// log line carries SchemaVersion under it so that ingestion pipelines can tell schema changes apart. Scalar logs
+// the path as a string rather than the whole URL and the duration as an integer number of nanoseconds. Bytes is
+// the size of the response body sent and RawBytes its size before a [hio.ResponseTransform] such as compression.
+type FieldNames struct {
+	Path             string
+	Method           string
+	Duration         string
+	Status           string
+	Bytes            string
+	RawBytes         string
+	Route            string
+	Group            string
+	Deprecated       string
+	Debug            string
+	Cause            string
+	Deadline         string
+	SampleRate       string
+	Steps            string
+	SchemaVersionKey string
+	SchemaVersion    string
+	Scalar           bool
+}
+
+// DefaultFieldNames are the keys logged by default.
+var DefaultFieldNames = FieldNames{
+	Path:       "path",
+	Method:     "method",
+	Duration:   "duration",
+	Status:     "status",
+	Bytes:      "bytes",
+	RawBytes:   "raw_bytes",
+	Route:      "route",
+	Group:      "group",
+	Deprecated: "deprecated",
+	Debug:      "debug",
+	Cause:      "cause",
+	Deadline:   "deadline_remaining",
+	SampleRate: "sample_rate",
+	Steps:      "steps",
+}
+
+// ECSFieldNames are the keys of the Elastic Common Schema.
+var ECSFieldNames = FieldNames{
+	Path:             "url.path",
+	Method:           "http.request.method",
+	Duration:         "event.duration",
+	Status:           "http.response.status_code",
+	Bytes:            "http.response.body.bytes",
+	RawBytes:         "labels.response_raw_bytes",
+	Route:            "http.route",
+	Group:            "labels.route_group",
+	Deprecated:       "labels.deprecated",
+	Debug:            "debug",
+	Cause:            "event.reason",
+	Deadline:         "http.request.deadline_remaining",
+	SampleRate:       "event.sample_rate",
+	Steps:            "steps",
+	SchemaVersionKey: "ecs.version",
+	SchemaVersion:    "8.11.0",
+	Scalar:           true,
+}
+
+type fieldNamesOption struct{ value FieldNames }
+
+// WithFieldNames logs the attributes with the keys, such as [ECSFieldNames] or a custom mapping,
+// so that ingestion pipelines do not need to rename them.
+func WithFieldNames(v FieldNames) Option { return fieldNamesOption{value: v} }
+
+func (o fieldNamesOption) apply(cfg *config) { cfg.names = o.value }
@@ -2,30 +2,124 @@
 package hlog
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"slices"
 	"time"
+
+	"github.com/drakelthedragon/bazaar/hio"
 )
 
 // MiddlewareFunc is a function that wraps an [http.Handler] with additional functionality.
 type MiddlewareFunc func(http.Handler) http.Handler
 
+// Option configures [Middleware].
+type Option interface{ apply(*config) }
+
+type config struct {
+	names      FieldNames
+	capture    bool
+	slow       time.Duration
+	captureMax int
+	sampler    *sampler
+	steps      bool
+}
+
 // Middleware returns a [MiddlewareFunc] that logs HTTP requests and responses.
-func Middleware(l *slog.Logger) MiddlewareFunc {
+// Requests matched by an [hio.Router] are logged with their route pattern, group, and whether the route is
+// deprecated, and requests matched by an [http.ServeMux] with their pattern, so that logs group by endpoint.
+// Requests whose context ended are logged with its cancellation cause, telling client disconnects apart from
+// timeouts, and requests with a deadline with the time that remained before it when they completed.
+// Requests are logged with the bytes of the body sent, and with the bytes written by the handler as well when
+// the body went through a [hio.ResponseTransform] such as [hio.GzipResponse]. The trace propagated by the
+// traceparent header is held by the request context, read with [TraceFromContext].
+func Middleware(l *slog.Logger, opts ...Option) MiddlewareFunc {
+	cfg := config{names: DefaultFieldNames}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(
 			func(w http.ResponseWriter, r *http.Request) {
+				ctx := hio.NewBodySizeContext(hio.NewRouteContext(r.Context()))
+				if t, ok := TraceFromRequest(r); ok {
+					ctx = ContextWithTrace(ctx, t)
+				}
+				var capture *captureBuffer
+				if cfg.capture {
+					capture = &captureBuffer{max: cfg.captureMax}
+					ctx = context.WithValue(ctx, captureKey{}, capture)
+				}
+				var steps *stepTimer
+				if cfg.steps {
+					steps = &stepTimer{}
+					ctx = context.WithValue(ctx, stepsKey{}, steps)
+				}
+				r = r.WithContext(ctx)
 				rr := RecordResponse(next, w, r)
-				l.LogAttrs(
-					r.Context(),
-					slog.LevelInfo,
-					"request",
-					slog.Any("path", r.URL),
-					slog.String("method", r.Method),
-					slog.Duration("duration", rr.Duration),
-					slog.Int("status", rr.StatusCode),
-				)
+				rate := 1.0
+				if cfg.sampler != nil {
+					if rate = cfg.sampler.sample(rr.StatusCode, rr.Duration); rate == 0 {
+						return
+					}
+				}
+				n := cfg.names
+				attrs := []slog.Attr{
+					slog.Any(n.Path, r.URL),
+					slog.String(n.Method, r.Method),
+					slog.Duration(n.Duration, rr.Duration),
+					slog.Int(n.Status, rr.StatusCode),
+					slog.Int64(n.Bytes, rr.Bytes),
+				}
+				if n.Scalar {
+					attrs[0] = slog.String(n.Path, r.URL.Path)
+					attrs[2] = slog.Int64(n.Duration, rr.Duration.Nanoseconds())
+				}
+				if size, ok := hio.BodySizeFromContext(r.Context()); ok {
+					attrs = append(attrs, slog.Int64(n.RawBytes, size))
+				}
+				if rate < 1 {
+					attrs = append(attrs, slog.Float64(n.SampleRate, rate))
+				}
+				if n.SchemaVersionKey != "" {
+					attrs = append(attrs, slog.String(n.SchemaVersionKey, n.SchemaVersion))
+				}
+				if rt, ok := hio.RouteFromContext(r.Context()); ok {
+					attrs = append(attrs, slog.String(n.Route, rt.String()))
+					if rt.Group != "" {
+						attrs = append(attrs, slog.String(n.Group, rt.Group))
+					}
+					if rt.Deprecated {
+						attrs = append(attrs, slog.Bool(n.Deprecated, true))
+					}
+				} else if r.Pattern != "" {
+					attrs = append(attrs, slog.String(n.Route, r.Pattern))
+				}
+				if err := r.Context().Err(); err != nil {
+					attrs = append(attrs, slog.String(n.Cause, context.Cause(r.Context()).Error()))
+				}
+				if deadline, ok := r.Context().Deadline(); ok {
+					if n.Scalar {
+						attrs = append(attrs, slog.Int64(n.Deadline, time.Until(deadline).Nanoseconds()))
+					} else {
+						attrs = append(attrs, slog.Duration(n.Deadline, time.Until(deadline)))
+					}
+				}
+				if steps != nil {
+					if a, ok := steps.attr(n.Scalar); ok {
+						a.Key = n.Steps
+						attrs = append(attrs, a)
+					}
+				}
+				if capture != nil && (rr.StatusCode >= 500 || cfg.slow > 0 && rr.Duration > cfg.slow) {
+					if a, ok := capture.attr(); ok {
+						a.Key = n.Debug
+						attrs = append(attrs, a)
+					}
+				}
+				l.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
 			},
 		)
 	}
@@ -50,6 +144,7 @@ func Duration(d *time.Duration) MiddlewareFunc {
 type Response struct {
 	Duration   time.Duration
 	StatusCode int
+	Bytes      int64
 }
 
 // RecordResponse wraps an [http.Handler] and captures its response details.
@@ -58,6 +153,7 @@ func RecordResponse(h http.Handler, w http.ResponseWriter, r *http.Request) Resp
 	mws := []MiddlewareFunc{
 		Duration(&rr.Duration),
 		StatusCode(&rr.StatusCode),
+		BodyBytes(&rr.Bytes),
 	}
 	for _, wrap := range slices.Backward(mws) {
 		h = wrap(h)
@@ -70,6 +166,7 @@ func RecordResponse(h http.Handler, w http.ResponseWriter, r *http.Request) Resp
 type Interceptor struct {
 	http.ResponseWriter
 	OnWriteHeader func(code int)
+	OnWrite       func(n int)
 }
 
 // WriteHeader calls [Interceptor.OnWriteHeader] if provided and
@@ -81,6 +178,16 @@ func (ic *Interceptor) WriteHeader(code int) {
 	ic.ResponseWriter.WriteHeader(code)
 }
 
+// Write calls the embedded [http.ResponseWriter.Write] and then
+// [Interceptor.OnWrite] if provided with the number of bytes written.
+func (ic *Interceptor) Write(p []byte) (int, error) {
+	n, err := ic.ResponseWriter.Write(p)
+	if ic.OnWrite != nil {
+		ic.OnWrite(n)
+	}
+	return n, err
+}
+
 // Unwrap returns the embedded [http.ResponseWriter] to allow
 // handlers to access the original when needed to preserve
 // optional interfaces like [http.Flusher], etc.
@@ -101,3 +208,19 @@ func StatusCode(n *int) MiddlewareFunc {
 		)
 	}
 }
+
+// BodyBytes records the number of bytes of the response body sent into the provided variable.
+func BodyBytes(n *int64) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				*n = 0
+				w = &Interceptor{
+					ResponseWriter: w,
+					OnWrite:        func(written int) { *n += int64(written) },
+				}
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
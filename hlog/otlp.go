@@ -0,0 +1,362 @@
+package hlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultOTLPBatchSize = 512
+	_defaultOTLPInterval  = 5 * time.Second
+	_otlpScope            = "github.com/drakelthedragon/bazaar/hlog"
+)
+
+// OTLPOption configures an [OTLPHandler].
+type OTLPOption interface{ apply(*otlpExporter) }
+
+type (
+	otlpHeadersOption  struct{ value map[string]string }
+	otlpResourceOption struct{ value []slog.Attr }
+	otlpClientOption   struct{ value *http.Client }
+	otlpLevelOption    struct{ value slog.Leveler }
+	otlpOnErrorOption  struct{ value func(error) }
+)
+
+type otlpBatchOption struct {
+	size     int
+	interval time.Duration
+}
+
+type otlpTraceOption struct {
+	value func(context.Context) (Trace, bool)
+}
+
+// WithOTLPHeaders sets headers sent with every export, such as for authentication with the collector.
+func WithOTLPHeaders(h map[string]string) OTLPOption { return otlpHeadersOption{value: h} }
+
+// WithOTLPResource sets the attributes of the resource emitting the logs, such as service.name.
+func WithOTLPResource(attrs ...slog.Attr) OTLPOption { return otlpResourceOption{value: attrs} }
+
+// WithOTLPBatch exports records once size are buffered or every interval. Defaults to 512 records and 5s.
+func WithOTLPBatch(size int, interval time.Duration) OTLPOption {
+	return otlpBatchOption{size: size, interval: interval}
+}
+
+// WithOTLPClient sets the client sending exports. Defaults to [http.DefaultClient].
+func WithOTLPClient(c *http.Client) OTLPOption { return otlpClientOption{value: c} }
+
+// WithOTLPLevel sets the minimum level of the records exported. Defaults to Info.
+func WithOTLPLevel(l slog.Leveler) OTLPOption { return otlpLevelOption{value: l} }
+
+// WithOTLPTrace sets the function reading the trace a record is correlated with from its context, such as the
+// span context of a tracing SDK. Defaults to [TraceFromContext].
+func WithOTLPTrace(fn func(context.Context) (Trace, bool)) OTLPOption {
+	return otlpTraceOption{value: fn}
+}
+
+// WithOTLPErrorHandler sets the function called with the errors of failed exports, which are retried with the next.
+func WithOTLPErrorHandler(fn func(error)) OTLPOption { return otlpOnErrorOption{value: fn} }
+
+func (o otlpHeadersOption) apply(e *otlpExporter)  { e.headers = o.value }
+func (o otlpResourceOption) apply(e *otlpExporter) { e.resource = otlpAttrs(o.value) }
+func (o otlpBatchOption) apply(e *otlpExporter)    { e.size, e.interval = o.size, o.interval }
+func (o otlpClientOption) apply(e *otlpExporter)   { e.client = o.value }
+func (o otlpLevelOption) apply(e *otlpExporter)    { e.level = o.value }
+func (o otlpTraceOption) apply(e *otlpExporter)    { e.trace = o.value }
+func (o otlpOnErrorOption) apply(e *otlpExporter)  { e.onError = o.value }
+
+// OTLPHandler is a [slog.Handler] exporting records as OpenTelemetry logs to a collector over OTLP/HTTP with JSON
+// encoding, correlated with the trace of their context, so that services ship logs without a file-tailing agent.
+// Records are buffered and exported in batches in the background, and dropped while the buffer holds four
+// batches the collector did not accept. Route records to it alongside another handler with a [RoutingHandler].
+type OTLPHandler struct {
+	e    *otlpExporter
+	goas []groupOrAttrs
+}
+
+type otlpExporter struct {
+	endpoint string
+	headers  map[string]string
+	resource []otlpKeyValue
+	size     int
+	interval time.Duration
+	client   *http.Client
+	level    slog.Leveler
+	trace    func(context.Context) (Trace, bool)
+	onError  func(error)
+
+	mu      sync.Mutex
+	pending []otlpLogRecord
+	flush   chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewOTLPHandler returns an [OTLPHandler] exporting to the logs endpoint of the collector, such as
+// http://localhost:4318/v1/logs, until it is shut down.
+func NewOTLPHandler(endpoint string, opts ...OTLPOption) *OTLPHandler {
+	e := &otlpExporter{
+		endpoint: endpoint,
+		size:     _defaultOTLPBatchSize,
+		interval: _defaultOTLPInterval,
+		client:   http.DefaultClient,
+		level:    slog.LevelInfo,
+		trace:    TraceFromContext,
+		flush:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt.apply(e)
+	}
+
+	go e.run()
+
+	return &OTLPHandler{e: e}
+}
+
+// Enabled reports whether the level is at least the one set with [WithOTLPLevel].
+func (h *OTLPHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.e.level.Level()
+}
+
+// Handle buffers the record for the next export.
+func (h *OTLPHandler) Handle(ctx context.Context, r slog.Record) error {
+	var attrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	rec := otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(r.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       min(max(9+int(r.Level), 1), 24),
+		SeverityText:         r.Level.String(),
+		Body:                 otlpAnyValue{StringValue: &r.Message},
+		Attributes:           otlpAttrs(nest(h.goas, attrs)),
+	}
+	if t, ok := h.e.trace(ctx); ok {
+		rec.TraceID = hex.EncodeToString(t.TraceID[:])
+		rec.SpanID = hex.EncodeToString(t.SpanID[:])
+		rec.Flags = int(t.Flags)
+	}
+
+	h.e.add(rec)
+	return nil
+}
+
+// WithAttrs returns an [OTLPHandler] adding the attributes to every record.
+func (h *OTLPHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &OTLPHandler{e: h.e, goas: append(slices.Clip(h.goas), groupOrAttrs{attrs: attrs})}
+}
+
+// WithGroup returns an [OTLPHandler] nesting the attributes of every record in the group.
+func (h *OTLPHandler) WithGroup(name string) slog.Handler {
+	return &OTLPHandler{e: h.e, goas: append(slices.Clip(h.goas), groupOrAttrs{group: name})}
+}
+
+// Shutdown stops exporting in the background and exports the buffered records until ctx is done.
+func (h *OTLPHandler) Shutdown(ctx context.Context) error {
+	h.e.once.Do(func() { close(h.e.done) })
+	<-h.e.stopped
+	return h.e.export(ctx)
+}
+
+func (e *otlpExporter) add(rec otlpLogRecord) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.pending) >= 4*e.size {
+		return
+	}
+	e.pending = append(e.pending, rec)
+	if len(e.pending) >= e.size {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.stopped)
+
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-t.C:
+		case <-e.flush:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), e.interval)
+		if err := e.export(ctx); err != nil && e.onError != nil {
+			e.onError(err)
+		}
+		cancel()
+	}
+}
+
+// export sends the buffered records in batches, keeping those of a failed batch for the next export.
+func (e *otlpExporter) export(ctx context.Context) error {
+	for {
+		e.mu.Lock()
+		batch := e.pending[:min(len(e.pending), e.size)]
+		e.mu.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := e.send(ctx, batch); err != nil {
+			return err
+		}
+
+		e.mu.Lock()
+		e.pending = slices.Delete(e.pending, 0, len(batch))
+		e.mu.Unlock()
+	}
+}
+
+func (e *otlpExporter) send(ctx context.Context, batch []otlpLogRecord) error {
+	body, err := json.Marshal(otlpLogsRequest{ResourceLogs: []otlpResourceLogs{{
+		Resource:  otlpResource{Attributes: e.resource},
+		ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: _otlpScope}, LogRecords: batch}},
+	}}})
+	if err != nil {
+		return fmt.Errorf("encoding logs: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("exporting logs: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("exporting logs: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusBadRequest:
+		// The collector will never accept the batch, so it is dropped rather than retried.
+		e.mu.Lock()
+		e.pending = slices.Delete(e.pending, 0, len(batch))
+		e.mu.Unlock()
+		return errors.New("exporting logs: batch rejected by the collector")
+	}
+	return fmt.Errorf("exporting logs: unexpected status %s", resp.Status)
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+	Flags                int            `json:"flags,omitzero"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// otlpAnyValue is the JSON encoding of an OTLP AnyValue, which encodes 64-bit integers as strings.
+type otlpAnyValue struct {
+	StringValue *string        `json:"stringValue,omitzero"`
+	BoolValue   *bool          `json:"boolValue,omitzero"`
+	IntValue    *string        `json:"intValue,omitzero"`
+	DoubleValue *float64       `json:"doubleValue,omitzero"`
+	KvlistValue *otlpKeyValues `json:"kvlistValue,omitzero"`
+}
+
+type otlpKeyValues struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+func otlpAttrs(attrs []slog.Attr) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup && a.Key == "" {
+			kvs = append(kvs, otlpAttrs(v.Group())...)
+			continue
+		}
+		kvs = append(kvs, otlpKeyValue{Key: a.Key, Value: otlpValue(v)})
+	}
+	return kvs
+}
+
+func otlpValue(v slog.Value) otlpAnyValue {
+	switch v.Kind() {
+	case slog.KindBool:
+		b := v.Bool()
+		return otlpAnyValue{BoolValue: &b}
+	case slog.KindInt64:
+		s := strconv.FormatInt(v.Int64(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case slog.KindUint64:
+		s := strconv.FormatUint(v.Uint64(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case slog.KindDuration:
+		s := strconv.FormatInt(v.Duration().Nanoseconds(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case slog.KindFloat64:
+		f := v.Float64()
+		return otlpAnyValue{DoubleValue: &f}
+	case slog.KindTime:
+		s := v.Time().Format(time.RFC3339Nano)
+		return otlpAnyValue{StringValue: &s}
+	case slog.KindGroup:
+		return otlpAnyValue{KvlistValue: &otlpKeyValues{Values: otlpAttrs(v.Group())}}
+	}
+	s := v.String()
+	return otlpAnyValue{StringValue: &s}
+}
@@ -0,0 +1,125 @@
+package hlog
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/hio"
+)
+
+// RecentRequest is a request recorded by [RecentRequests].
+type RecentRequest struct {
+	Time       time.Time     `json:"time"`
+	Method     string        `json:"method"`
+	Path       string        `json:"path"`
+	Route      string        `json:"route,omitempty"`
+	RemoteAddr string        `json:"remote_addr"`
+	Status     int           `json:"status"`
+	Duration   time.Duration `json:"duration_ms,format:milli"`
+}
+
+// RecentRequests keeps the last requests in a fixed-size ring buffer for debugging environments without
+// centralized logging.
+type RecentRequests struct {
+	mu   sync.Mutex
+	ring []RecentRequest
+	next int
+	full bool
+}
+
+// NewRecentRequests returns a [RecentRequests] keeping the last n requests.
+func NewRecentRequests(n int) *RecentRequests { return &RecentRequests{ring: make([]RecentRequest, n)} }
+
+// Middleware returns a [MiddlewareFunc] recording requests.
+func (rr *RecentRequests) Middleware() MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				r = r.WithContext(hio.NewRouteContext(r.Context()))
+				req := RecentRequest{Time: time.Now(), Method: r.Method, Path: r.URL.Path, RemoteAddr: r.RemoteAddr}
+				res := RecordResponse(next, w, r)
+				req.Status, req.Duration = res.StatusCode, res.Duration
+				if rt, ok := hio.RouteFromContext(r.Context()); ok {
+					req.Route = rt.String()
+				} else {
+					req.Route = r.Pattern
+				}
+				rr.add(req)
+			},
+		)
+	}
+}
+
+func (rr *RecentRequests) add(req RecentRequest) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if len(rr.ring) == 0 {
+		return
+	}
+
+	rr.ring[rr.next] = req
+	rr.next = (rr.next + 1) % len(rr.ring)
+	rr.full = rr.full || rr.next == 0
+}
+
+// Requests returns the recorded requests, newest first.
+func (rr *RecentRequests) Requests() []RecentRequest {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	n := rr.next
+	if rr.full {
+		n = len(rr.ring)
+	}
+
+	reqs := make([]RecentRequest, 0, n)
+	for i := range n {
+		reqs = append(reqs, rr.ring[(rr.next-1-i+len(rr.ring))%len(rr.ring)])
+	}
+
+	return reqs
+}
+
+// Handler returns an [http.Handler] for the admin server rendering the recorded requests as JSON, newest first.
+// They are filtered by the query parameters method, route, path (a prefix), status (a code or a class such as 5xx),
+// min_duration (such as 250ms), and limit.
+func (rr *RecentRequests) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		minDuration, _ := time.ParseDuration(q.Get("min_duration"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		status := q.Get("status")
+
+		reqs := []RecentRequest{}
+		for _, req := range rr.Requests() {
+			switch {
+			case q.Has("method") && !strings.EqualFold(req.Method, q.Get("method")):
+			case q.Has("route") && req.Route != q.Get("route"):
+			case q.Has("path") && !strings.HasPrefix(req.Path, q.Get("path")):
+			case status != "" && !matchStatus(status, req.Status):
+			case req.Duration < minDuration:
+			default:
+				reqs = append(reqs, req)
+			}
+			if limit > 0 && len(reqs) == limit {
+				break
+			}
+		}
+
+		if err := hio.EncodeJSONBuffered(w, reqs, http.StatusOK); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func matchStatus(pattern string, code int) bool {
+	if len(pattern) == 3 && strings.HasSuffix(pattern, "xx") {
+		return strconv.Itoa(code / 100)[0] == pattern[0]
+	}
+	return pattern == strconv.Itoa(code)
+}
@@ -0,0 +1,181 @@
+package hlog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the values masked by a [Redactor].
+const Redacted = "[REDACTED]"
+
+var (
+	jwtPattern  = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	cardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+// Detector returns the string with the sensitive parts it finds masked, and whether it found any.
+type Detector func(string) (string, bool)
+
+// DetectJWT masks JSON Web Tokens, such as bearer tokens in a logged URL or header.
+func DetectJWT(s string) (string, bool) {
+	if !strings.Contains(s, "eyJ") {
+		return s, false
+	}
+	masked := jwtPattern.ReplaceAllString(s, Redacted)
+	return masked, masked != s
+}
+
+// DetectCardNumber masks sequences of 13 to 19 digits, optionally separated by spaces or dashes, passing the
+// Luhn check of payment card numbers.
+func DetectCardNumber(s string) (string, bool) {
+	found := false
+	masked := cardPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if !luhn(m) {
+			return m
+		}
+		found = true
+		return Redacted
+	})
+	return masked, found
+}
+
+func luhn(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return sum%10 == 0
+}
+
+// DefaultRedactKeys are the key patterns masked by a [Redactor] by default.
+var DefaultRedactKeys = []string{"password", "passwd", "secret", "token", "authorization", "cookie", "api_key", "apikey", "ssn"}
+
+// RedactOption configures a [Redactor].
+type RedactOption interface{ apply(*Redactor) }
+
+type (
+	redactKeysOption      struct{ value []string }
+	redactDetectorsOption struct{ value []Detector }
+)
+
+// WithRedactKeys sets the key patterns whose values are masked, matching keys containing them regardless of case
+// such as "token" for "refresh_token". Defaults to [DefaultRedactKeys].
+func WithRedactKeys(patterns ...string) RedactOption { return redactKeysOption{value: patterns} }
+
+// WithRedactDetectors sets the detectors masking sensitive parts of values of any key. Defaults to [DetectJWT]
+// and [DetectCardNumber].
+func WithRedactDetectors(ds ...Detector) RedactOption { return redactDetectorsOption{value: ds} }
+
+func (o redactKeysOption) apply(h *Redactor) {
+	h.keys = make([]string, len(o.value))
+	for i, k := range o.value {
+		h.keys[i] = strings.ToLower(k)
+	}
+}
+
+func (o redactDetectorsOption) apply(h *Redactor) { h.detectors = o.value }
+
+// Redactor is a [slog.Handler] masking the values of attributes whose key matches a pattern, and the parts of
+// values found by detectors, before passing records to the wrapped handler. Wrapping the handler of the logger
+// given to [Middleware] keeps secrets, such as tokens in logged URLs, out of every sink.
+type Redactor struct {
+	next      slog.Handler
+	keys      []string
+	detectors []Detector
+}
+
+// NewRedactor returns a [Redactor] wrapping next.
+func NewRedactor(next slog.Handler, opts ...RedactOption) *Redactor {
+	h := &Redactor{next: next, keys: DefaultRedactKeys, detectors: []Detector{DetectJWT, DetectCardNumber}}
+	for _, opt := range opts {
+		opt.apply(h)
+	}
+	return h
+}
+
+// Enabled reports whether the wrapped handler handles the level.
+func (h *Redactor) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle passes the record with its attributes redacted to the wrapped handler.
+func (h *Redactor) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, h.redactString(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs returns a [Redactor] adding the attributes, redacted, to every record.
+func (h *Redactor) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redact(a)
+	}
+	return &Redactor{next: h.next.WithAttrs(redacted), keys: h.keys, detectors: h.detectors}
+}
+
+// WithGroup returns a [Redactor] nesting the attributes of every record in the group.
+func (h *Redactor) WithGroup(name string) slog.Handler {
+	return &Redactor{next: h.next.WithGroup(name), keys: h.keys, detectors: h.detectors}
+}
+
+func (h *Redactor) redact(a slog.Attr) slog.Attr {
+	lower := strings.ToLower(a.Key)
+	for _, k := range h.keys {
+		if strings.Contains(lower, k) {
+			return slog.String(a.Key, Redacted)
+		}
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		attrs := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			attrs[i] = h.redact(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}
+	case slog.KindString:
+		return slog.String(a.Key, h.redactString(v.String()))
+	case slog.KindAny:
+		if s, ok := v.Any().(fmt.Stringer); ok {
+			if masked, found := h.detect(s.String()); found {
+				return slog.String(a.Key, masked)
+			}
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+func (h *Redactor) redactString(s string) string {
+	masked, _ := h.detect(s)
+	return masked
+}
+
+func (h *Redactor) detect(s string) (string, bool) {
+	found := false
+	for _, d := range h.detectors {
+		var ok bool
+		s, ok = d(s)
+		found = found || ok
+	}
+	return s, found
+}
@@ -0,0 +1,159 @@
+package hlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Matcher reports whether a record with the level and attributes is routed, attributes in groups being keyed
+// by their dotted path such as "http.status".
+type Matcher func(level slog.Level, attrs map[string]slog.Value) bool
+
+// LogRoute sends the records matched by Match to Handler as well as to the default handler of a [RoutingHandler],
+// or instead of it when Exclusive.
+type LogRoute struct {
+	Match     Matcher
+	Handler   slog.Handler
+	Exclusive bool
+}
+
+// MatchLevel matches records at the level or above.
+func MatchLevel(threshold slog.Level) Matcher {
+	return func(level slog.Level, _ map[string]slog.Value) bool { return level >= threshold }
+}
+
+// MatchStatusClass matches records whose status under the key, such as the Status of [FieldNames], is in the
+// class, such as 5 for 5xx responses.
+func MatchStatusClass(key string, class int) Matcher {
+	return func(_ slog.Level, attrs map[string]slog.Value) bool {
+		v, ok := attrs[key]
+		if !ok {
+			return false
+		}
+		status, err := strconv.Atoi(v.String())
+		return err == nil && status/100 == class
+	}
+}
+
+// MatchAttr matches records with the attribute, such as an audit flag set with [slog.Logger.With].
+func MatchAttr(key, value string) Matcher {
+	return func(_ slog.Level, attrs map[string]slog.Value) bool {
+		v, ok := attrs[key]
+		return ok && v.String() == value
+	}
+}
+
+// MatchAttrPrefix matches records whose attribute starts with the prefix, such as the Route of [FieldNames]
+// starting with "POST /admin/".
+func MatchAttrPrefix(key, prefix string) Matcher {
+	return func(_ slog.Level, attrs map[string]slog.Value) bool {
+		v, ok := attrs[key]
+		return ok && strings.HasPrefix(v.String(), prefix)
+	}
+}
+
+// MatchAll matches records matched by every matcher.
+func MatchAll(ms ...Matcher) Matcher {
+	return func(level slog.Level, attrs map[string]slog.Value) bool {
+		for _, m := range ms {
+			if !m(level, attrs) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// RoutingHandler is a [slog.Handler] sending records to the handlers of the routes they match, such as failed
+// requests to an alerting handler and audit records to a separate sink, in addition to or instead of a default
+// handler.
+type RoutingHandler struct {
+	next   slog.Handler
+	routes []LogRoute
+	goas   []groupOrAttrs
+}
+
+// NewRoutingHandler returns a [RoutingHandler] sending records to next unless an exclusive route matches them.
+func NewRoutingHandler(next slog.Handler, routes ...LogRoute) *RoutingHandler {
+	return &RoutingHandler{next: next, routes: routes}
+}
+
+// Enabled reports whether the default handler or the handler of any route handles the level.
+func (h *RoutingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.next.Enabled(ctx, level) {
+		return true
+	}
+	for _, rt := range h.routes {
+		if rt.Handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the record to the handlers of the routes it matches, and to the default handler unless one of
+// them is exclusive.
+func (h *RoutingHandler) Handle(ctx context.Context, r slog.Record) error {
+	var recordAttrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		recordAttrs = append(recordAttrs, a)
+		return true
+	})
+	attrs := make(map[string]slog.Value)
+	flatten(attrs, "", nest(h.goas, recordAttrs))
+
+	var (
+		errs      []error
+		exclusive bool
+	)
+	for _, rt := range h.routes {
+		if !rt.Match(r.Level, attrs) {
+			continue
+		}
+		exclusive = exclusive || rt.Exclusive
+		if rt.Handler.Enabled(ctx, r.Level) {
+			errs = append(errs, rt.Handler.Handle(ctx, r.Clone()))
+		}
+	}
+	if !exclusive && h.next.Enabled(ctx, r.Level) {
+		errs = append(errs, h.next.Handle(ctx, r))
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a [RoutingHandler] adding the attributes to every record.
+func (h *RoutingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	routes := slices.Clone(h.routes)
+	for i := range routes {
+		routes[i].Handler = routes[i].Handler.WithAttrs(attrs)
+	}
+	return &RoutingHandler{next: h.next.WithAttrs(attrs), routes: routes, goas: append(slices.Clip(h.goas), groupOrAttrs{attrs: attrs})}
+}
+
+// WithGroup returns a [RoutingHandler] nesting the attributes of every record in the group.
+func (h *RoutingHandler) WithGroup(name string) slog.Handler {
+	routes := slices.Clone(h.routes)
+	for i := range routes {
+		routes[i].Handler = routes[i].Handler.WithGroup(name)
+	}
+	return &RoutingHandler{next: h.next.WithGroup(name), routes: routes, goas: append(slices.Clip(h.goas), groupOrAttrs{group: name})}
+}
+
+func flatten(m map[string]slog.Value, prefix string, attrs []slog.Attr) {
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			if a.Key == "" {
+				flatten(m, prefix, v.Group())
+			} else {
+				flatten(m, prefix+a.Key+".", v.Group())
+			}
+			continue
+		}
+		m[prefix+a.Key] = v
+	}
+}
@@ -0,0 +1,60 @@
+package hlog
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+type adaptiveSamplingOption struct {
+	perSecond int
+	slow      time.Duration
+}
+
+// WithAdaptiveSampling logs every failed request, with a 5xx status, and every request slower than slow, but only
+// a sample of the other requests once more than perSecond requests were served in the previous second, so that log
+// volume stays bounded during traffic spikes. Sampled lines carry the probability they were kept with.
+func WithAdaptiveSampling(perSecond int, slow time.Duration) Option {
+	return adaptiveSamplingOption{perSecond: perSecond, slow: slow}
+}
+
+func (o adaptiveSamplingOption) apply(cfg *config) {
+	cfg.sampler = &sampler{perSecond: o.perSecond, slow: o.slow}
+}
+
+// sampler estimates the request rate over the previous second to derive the sampling probability.
+type sampler struct {
+	perSecond int
+	slow      time.Duration
+
+	mu      sync.Mutex
+	second  int64
+	current int
+	last    int
+}
+
+// sample counts the request and returns the probability to keep it with, or zero to drop it.
+func (s *sampler) sample(status int, d time.Duration) float64 {
+	s.mu.Lock()
+	now := time.Now().Unix()
+	switch {
+	case now == s.second:
+	case now == s.second+1:
+		s.second, s.last, s.current = now, s.current, 0
+	default:
+		s.second, s.last, s.current = now, 0, 0
+	}
+	s.current++
+	rate := max(s.last, s.current)
+	s.mu.Unlock()
+
+	if status >= 500 || s.slow > 0 && d > s.slow || rate <= s.perSecond {
+		return 1
+	}
+
+	p := float64(s.perSecond) / float64(rate)
+	if rand.Float64() >= p {
+		return 0
+	}
+	return p
+}
@@ -0,0 +1,79 @@
+package hlog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type stepsOption struct{}
+
+// WithSteps times the steps of a request marked with [Step], such as database queries or template rendering, and
+// attaches their count and total duration by name to the request log line under "steps", giving a breakdown of
+// its latency without tracing.
+func WithSteps() Option { return stepsOption{} }
+
+func (o stepsOption) apply(cfg *config) { cfg.steps = true }
+
+type stepsKey struct{}
+
+type stepTimer struct {
+	mu    sync.Mutex
+	steps []stepTotal
+}
+
+type stepTotal struct {
+	name  string
+	count int
+	total time.Duration
+}
+
+// Step starts timing a step of the request served with ctx, and returns the function ending it, such as:
+//
+//	defer hlog.Step(ctx, "db.query")()
+//
+// Steps of the same name are added up. Step does nothing outside a [Middleware] configured with [WithSteps].
+func Step(ctx context.Context, name string) func() {
+	t, ok := ctx.Value(stepsKey{}).(*stepTimer)
+	if !ok {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() { t.add(name, time.Since(start)) }
+}
+
+func (t *stepTimer) add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range t.steps {
+		if t.steps[i].name == name {
+			t.steps[i].count++
+			t.steps[i].total += d
+			return
+		}
+	}
+	t.steps = append(t.steps, stepTotal{name: name, count: 1, total: d})
+}
+
+func (t *stepTimer) attr(scalar bool) (slog.Attr, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.steps) == 0 {
+		return slog.Attr{}, false
+	}
+
+	steps := make([]slog.Attr, len(t.steps))
+	for i, s := range t.steps {
+		d := slog.Duration("duration", s.total)
+		if scalar {
+			d = slog.Int64("duration", s.total.Nanoseconds())
+		}
+		steps[i] = slog.Group(s.name, slog.Int("count", s.count), d)
+	}
+
+	return slog.Attr{Key: "steps", Value: slog.GroupValue(steps...)}, true
+}
@@ -0,0 +1,53 @@
+package hlog
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Trace identifies the span a request is served in, as propagated by the W3C traceparent header.
+type Trace struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+type traceKey struct{}
+
+// ContextWithTrace returns a copy of ctx holding the trace.
+func ContextWithTrace(ctx context.Context, t Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// TraceFromContext returns the trace held by ctx, such as the one of a request served by [Middleware].
+func TraceFromContext(ctx context.Context) (Trace, bool) {
+	t, ok := ctx.Value(traceKey{}).(Trace)
+	return t, ok
+}
+
+// TraceFromRequest parses the traceparent header of the request.
+func TraceFromRequest(r *http.Request) (Trace, bool) {
+	parts := strings.Split(r.Header.Get("Traceparent"), "-")
+	if len(parts) < 4 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return Trace{}, false
+	}
+
+	var (
+		t     Trace
+		flags [1]byte
+	)
+	if _, err := hex.Decode(t.TraceID[:], []byte(parts[1])); err != nil || t.TraceID == [16]byte{} {
+		return Trace{}, false
+	}
+	if _, err := hex.Decode(t.SpanID[:], []byte(parts[2])); err != nil || t.SpanID == [8]byte{} {
+		return Trace{}, false
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return Trace{}, false
+	}
+	t.Flags = flags[0]
+
+	return t, true
+}
@@ -0,0 +1,216 @@
+package hretry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by a [CircuitBreaker] that rejects calls.
+var ErrOpen = errors.New("circuit breaker open")
+
+// State is the state of a [CircuitBreaker].
+type State int
+
+const (
+	// Closed lets every call through.
+	Closed State = iota
+	// Open rejects every call until the cooldown elapses.
+	Open
+	// HalfOpen lets a single probe call through to decide whether to close again.
+	HalfOpen
+)
+
+// String returns the state name.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops calling a failing dependency after a number of consecutive failures
+// and probes it again once a cooldown has elapsed.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     State
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	// generation counts the state changes, so that the outcomes of calls allowed before the last one are ignored.
+	generation uint64
+}
+
+// NewCircuitBreaker returns a [CircuitBreaker] opening after threshold consecutive failures for cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: max(threshold, 1), cooldown: cooldown}
+}
+
+// State returns the current state.
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.advance()
+
+	return cb.state
+}
+
+// Allow returns [ErrOpen] if a call must not be made. Otherwise the outcome of the call must be reported with
+// [CircuitBreaker.Record] along with the generation returned.
+func (cb *CircuitBreaker) Allow() (uint64, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.advance()
+
+	switch cb.state {
+	case Open:
+		return 0, ErrOpen
+	case HalfOpen:
+		if cb.probing {
+			return 0, ErrOpen
+		}
+		cb.probing = true
+	}
+
+	return cb.generation, nil
+}
+
+// Record reports the outcome of a call allowed by [CircuitBreaker.Allow] with the generation it returned.
+// Outcomes of calls allowed before the breaker last changed state are ignored, so that a late success does not
+// close an open breaker and a late result does not let another probe through.
+func (cb *CircuitBreaker) Record(generation uint64, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if generation != cb.generation {
+		return
+	}
+
+	if err == nil {
+		if cb.state == HalfOpen {
+			cb.transition(Closed)
+		}
+		cb.failures = 0
+		return
+	}
+
+	cb.failures++
+
+	if cb.state == HalfOpen || cb.failures >= cb.threshold {
+		cb.transition(Open)
+		cb.openedAt = time.Now()
+	}
+}
+
+// Execute calls fn if allowed and records its outcome.
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	gen, err := cb.Allow()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if v := recover(); v != nil {
+			cb.Record(gen, fmt.Errorf("panicked: %v", v))
+			panic(v)
+		}
+	}()
+
+	err = fn()
+	cb.Record(gen, err)
+
+	return err
+}
+
+// Middleware responds with 503 Service Unavailable while the breaker is open, counting 5xx responses as failures.
+func (cb *CircuitBreaker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gen, err := cb.Allow()
+		if err != nil {
+			w.Header().Set("Retry-After", strconv.Itoa(int(cb.cooldown.Seconds())))
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			// A panic, including http.ErrAbortHandler, is recorded as a failure so that a half-open breaker
+			// does not wait for the outcome of its probe forever.
+			if v := recover(); v != nil {
+				cb.Record(gen, fmt.Errorf("handler panicked: %v", v))
+				panic(v)
+			}
+			if sw.status >= http.StatusInternalServerError {
+				cb.Record(gen, errors.New(http.StatusText(sw.status)))
+				return
+			}
+			cb.Record(gen, nil)
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// Transport returns an [http.RoundTripper] failing fast with [ErrOpen] while the breaker is open,
+// counting transport errors and 5xx responses as failures.
+func (cb *CircuitBreaker) Transport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		gen, err := cb.Allow()
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := next.RoundTrip(r)
+
+		switch {
+		case err != nil:
+			cb.Record(gen, err)
+		case res.StatusCode >= http.StatusInternalServerError:
+			cb.Record(gen, errors.New(res.Status))
+		default:
+			cb.Record(gen, nil)
+		}
+
+		return res, err
+	})
+}
+
+func (cb *CircuitBreaker) advance() {
+	if cb.state == Open && time.Since(cb.openedAt) >= cb.cooldown {
+		cb.transition(HalfOpen)
+	}
+}
+
+// transition changes the state, starting a new generation without a probe or failures.
+func (cb *CircuitBreaker) transition(s State) {
+	cb.state, cb.failures, cb.probing = s, 0, false
+	cb.generation++
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
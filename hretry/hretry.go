@@ -0,0 +1,207 @@
+// Package hretry provides retry, backoff, and circuit breaker primitives.
+package hretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/ppp"
+)
+
+const (
+	_defaultMaxAttempts = 3
+	_defaultBaseDelay   = 100 * time.Millisecond
+	_defaultMaxDelay    = 10 * time.Second
+)
+
+// Backoff returns the delay before the given retry attempt, starting at 1.
+type Backoff func(attempt int) time.Duration
+
+// Exponential returns a [Backoff] doubling base on every attempt up to max with a random jitter
+// between zero and the given fraction of the delay subtracted from it.
+func Exponential(base, max time.Duration, jitter float64) Backoff {
+	return func(attempt int) time.Duration {
+		d := base << (attempt - 1)
+		if d <= 0 || d > max {
+			d = max
+		}
+		if jitter > 0 {
+			d -= time.Duration(rand.Float64() * jitter * float64(d))
+		}
+		return d
+	}
+}
+
+// Constant returns a [Backoff] always waiting d.
+func Constant(d time.Duration) Backoff { return func(int) time.Duration { return d } }
+
+// Policy describes how an operation is retried.
+type Policy struct {
+	// MaxAttempts is the maximum number of calls including the first one.
+	MaxAttempts int
+	// Backoff returns the delay before each retry.
+	Backoff Backoff
+	// Retryable reports whether an error should be retried, defaulting to every error.
+	Retryable func(error) bool
+}
+
+// DefaultPolicy returns a [Policy] with default values.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts: _defaultMaxAttempts,
+		Backoff:     Exponential(_defaultBaseDelay, _defaultMaxDelay, 0.5),
+	}
+}
+
+// Permanent wraps err so that it is never retried.
+func Permanent(err error) error { return &permanentError{err: err} }
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Do calls fn until it succeeds, returns a non-retryable error, exhausts the policy attempts, or ctx is done.
+func Do(ctx context.Context, p Policy, fn func(context.Context) error) error {
+	_, err := Retry(ctx, p, func(ctx context.Context) (struct{}, error) { return struct{}{}, fn(ctx) })
+	return err
+}
+
+// Retry calls fn until it succeeds, returns a non-retryable error, exhausts the policy attempts, or ctx is done.
+func Retry[T any](ctx context.Context, p Policy, fn func(context.Context) (T, error)) (T, error) {
+	p.setDefaultZeroValues()
+
+	for attempt := 1; ; attempt++ {
+		v, err := fn(ctx)
+		if err == nil {
+			return v, nil
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) || !p.Retryable(err) || attempt >= p.MaxAttempts {
+			return v, err
+		}
+
+		timer := time.NewTimer(p.Backoff(attempt))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return v, fmt.Errorf("retrying after attempt %d: %w (last error: %w)", attempt, context.Cause(ctx), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// WrapProcessor returns a [ppp.Processor] retrying p with the policy and guarding it with cb when not nil.
+func WrapProcessor[I, O any](p ppp.Processor[I, O], policy Policy, cb *CircuitBreaker) ppp.Processor[I, O] {
+	return processor[I, O]{p: p, policy: policy, cb: cb}
+}
+
+// RetryPages returns a [ppp.HTTPOption] retrying the page requests of a [ppp.NewHTTPParser] with the policy.
+// Unless the policy sets Retryable, transport errors and temporary [ppp.HTTPStatusError] responses are retried.
+func RetryPages(p Policy) ppp.HTTPOption {
+	if p.Retryable == nil {
+		p.Retryable = func(err error) bool {
+			var se *ppp.HTTPStatusError
+			return !errors.As(err, &se) || se.Temporary()
+		}
+	}
+	return ppp.WithRetry(func(ctx context.Context, fetch func(context.Context) error) error { return Do(ctx, p, fetch) })
+}
+
+type processor[I, O any] struct {
+	p      ppp.Processor[I, O]
+	policy Policy
+	cb     *CircuitBreaker
+}
+
+func (p processor[I, O]) Process(ctx context.Context, in I) (O, error) {
+	return Retry(ctx, p.policy, func(ctx context.Context) (O, error) {
+		if p.cb == nil {
+			return p.p.Process(ctx, in)
+		}
+		var out O
+		err := p.cb.Execute(func() error {
+			var err error
+			out, err = p.p.Process(ctx, in)
+			return err
+		})
+		return out, err
+	})
+}
+
+func (p *Policy) setDefaultZeroValues() {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = _defaultMaxAttempts
+	}
+
+	if p.Backoff == nil {
+		p.Backoff = Exponential(_defaultBaseDelay, _defaultMaxDelay, 0.5)
+	}
+
+	if p.Retryable == nil {
+		p.Retryable = func(error) bool { return true }
+	}
+}
+
+// Transport returns an [http.RoundTripper] retrying idempotent requests that fail with a transport
+// error, a 429 Too Many Requests, or a 5xx response. Request bodies are replayed using GetBody.
+func Transport(next http.RoundTripper, p Policy) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if !idempotent(r) || (r.Body != nil && r.Body != http.NoBody && r.GetBody == nil) {
+			return next.RoundTrip(r)
+		}
+
+		var res *http.Response
+
+		err := Do(r.Context(), p, func(ctx context.Context) error {
+			if res != nil {
+				io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+				res = nil
+			}
+
+			req := r
+			if r.GetBody != nil {
+				body, err := r.GetBody()
+				if err != nil {
+					return Permanent(err)
+				}
+				req = r.Clone(ctx)
+				req.Body = body
+			}
+
+			var err error
+			res, err = next.RoundTrip(req)
+			if err != nil {
+				return err
+			}
+
+			if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError {
+				return errors.New(res.Status)
+			}
+
+			return nil
+		})
+
+		if res != nil {
+			return res, nil
+		}
+
+		return nil, err
+	})
+}
+
+func idempotent(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.Header.Get("Idempotency-Key") != ""
+}
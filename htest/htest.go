@@ -0,0 +1,162 @@
+// Package htest provides utilities for testing HTTP servers built with hio and hlog.
+package htest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/hio"
+)
+
+// UpdateEnv is the environment variable that makes [Golden] rewrite golden files instead of comparing them.
+const UpdateEnv = "HTEST_UPDATE"
+
+// Serve runs [hio.Serve] with h on a free local port until the test finishes and returns its base URL.
+// The options are applied after the host and port so they can override any other setting.
+func Serve(tb testing.TB, h http.Handler, opts ...hio.ServeOption) string {
+	tb.Helper()
+
+	port, err := freePort()
+	if err != nil {
+		tb.Fatalf("finding free port: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- hio.Serve(ctx, h, append([]hio.ServeOption{hio.WithHost("127.0.0.1"), hio.WithPort(port)}, opts...)...)
+	}()
+
+	tb.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			tb.Errorf("serving: %v", err)
+		}
+	})
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+
+		select {
+		case err := <-done:
+			tb.Fatalf("serving: %v", err)
+		default:
+		}
+
+		if time.Now().After(deadline) {
+			tb.Fatalf("waiting for server: %v", err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return "http://" + addr
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Golden compares got to the contents of testdata/name.golden, rewriting the file when [UpdateEnv] is set.
+func Golden(tb testing.TB, name string, got []byte) {
+	tb.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatalf("creating golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			tb.Fatalf("writing golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		tb.Fatalf("golden file %s does not exist, run with %s=1 to create it", path, UpdateEnv)
+	}
+	if err != nil {
+		tb.Fatalf("reading golden file: %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		tb.Errorf("%s mismatch:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// AssertResponse checks the status code of res and compares its body to the golden file name.
+// The response body is consumed and closed.
+func AssertResponse(tb testing.TB, res *http.Response, status int, name string) {
+	tb.Helper()
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		tb.Fatalf("reading response body: %v", err)
+	}
+
+	if res.StatusCode != status {
+		tb.Errorf("status = %d, want %d", res.StatusCode, status)
+	}
+
+	Golden(tb, name, body)
+}
+
+// Clock is a manually advanced clock for code that accepts a func() time.Time.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a [Clock] set to t.
+func NewClock(t time.Time) *Clock { return &Clock{now: t} }
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = t
+}
@@ -0,0 +1,120 @@
+package htest
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// Record is a log record captured by a [LogRecorder] with its attributes flattened by group.
+type Record struct {
+	Level   slog.Level
+	Message string
+	Attrs   map[string]slog.Value
+}
+
+// LogRecorder is a [slog.Handler] capturing records for assertions.
+type LogRecorder struct {
+	mu      *sync.Mutex
+	records *[]Record
+	level   slog.Leveler
+	attrs   []slog.Attr
+	group   string
+}
+
+// NewLogRecorder returns a [LogRecorder] capturing records at or above level.
+func NewLogRecorder(level slog.Leveler) *LogRecorder {
+	return &LogRecorder{mu: &sync.Mutex{}, records: &[]Record{}, level: level}
+}
+
+// Logger returns a [slog.Logger] writing to the recorder.
+func (h *LogRecorder) Logger() *slog.Logger { return slog.New(h) }
+
+// Enabled reports whether the level is at or above the recorder level.
+func (h *LogRecorder) Enabled(_ context.Context, l slog.Level) bool { return l >= h.level.Level() }
+
+// Handle captures the record.
+func (h *LogRecorder) Handle(_ context.Context, r slog.Record) error {
+	rec := Record{Level: r.Level, Message: r.Message, Attrs: make(map[string]slog.Value)}
+
+	for _, a := range h.attrs {
+		flatten(rec.Attrs, "", a)
+	}
+
+	r.Attrs(func(a slog.Attr) bool {
+		flatten(rec.Attrs, h.group, a)
+		return true
+	})
+
+	h.mu.Lock()
+	*h.records = append(*h.records, rec)
+	h.mu.Unlock()
+
+	return nil
+}
+
+// WithAttrs returns a recorder sharing the captured records that adds the attributes to every record.
+func (h *LogRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = slices.Clone(h.attrs)
+	for _, a := range attrs {
+		if h.group != "" {
+			a = slog.Attr{Key: h.group + a.Key, Value: a.Value}
+		}
+		c.attrs = append(c.attrs, a)
+	}
+	return &c
+}
+
+// WithGroup returns a recorder sharing the captured records that prefixes attribute keys with the group name.
+func (h *LogRecorder) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.group = h.group + name + "."
+	return &c
+}
+
+// Records returns a copy of the captured records.
+func (h *LogRecorder) Records() []Record {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return slices.Clone(*h.records)
+}
+
+// Find returns the captured records with the message.
+func (h *LogRecorder) Find(msg string) []Record {
+	var found []Record
+	for _, r := range h.Records() {
+		if r.Message == msg {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+// Reset discards the captured records.
+func (h *LogRecorder) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	*h.records = nil
+}
+
+func flatten(m map[string]slog.Value, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			flatten(m, p, ga)
+		}
+		return
+	}
+	m[prefix+a.Key] = v
+}
@@ -0,0 +1,143 @@
+// Package hval provides declarative validation producing structured field errors.
+//
+// Values can be validated with struct tags through [Struct] or with rules built in code through
+// [Validator]. Both report an [Errors] value listing every failed field, which is suitable for
+// returning from the Validate() error method that hio.DecodeJSON calls: hio.Responder.Error answers it
+// with 422 Unprocessable Entity listing the fields, and ppp.Validate fails stream items with it.
+package hval
+
+import (
+	"cmp"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes a field that failed a validation rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error returns the field name and message.
+func (e FieldError) Error() string { return e.Field + ": " + e.Message }
+
+// Errors is a list of [FieldError]s.
+type Errors []FieldError
+
+// Error returns the field errors separated by semicolons.
+func (e Errors) Error() string {
+	s := make([]string, len(e))
+	for i, fe := range e {
+		s[i] = fe.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// Rule is a named check of a value of type T.
+type Rule[T any] struct {
+	Name    string
+	Message string
+	Check   func(T) bool
+}
+
+// Validator collects field errors from rules applied with [Field].
+type Validator struct{ errs Errors }
+
+// New returns a new [Validator].
+func New() *Validator { return &Validator{} }
+
+// Field applies the rules to the value, recording an error for every rule it fails.
+func Field[T any](v *Validator, name string, value T, rules ...Rule[T]) {
+	for _, r := range rules {
+		if !r.Check(value) {
+			v.errs = append(v.errs, FieldError{Field: name, Rule: r.Name, Message: r.Message})
+		}
+	}
+}
+
+// Check records an error for the field when ok is false.
+func (v *Validator) Check(name string, ok bool, rule, message string) {
+	if !ok {
+		v.errs = append(v.errs, FieldError{Field: name, Rule: rule, Message: message})
+	}
+}
+
+// Err returns the collected [Errors] or nil if every rule passed.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Func returns a custom [Rule].
+func Func[T any](name, message string, check func(T) bool) Rule[T] {
+	return Rule[T]{Name: name, Message: message, Check: check}
+}
+
+// Required fails for the zero value.
+func Required[T comparable]() Rule[T] {
+	var zero T
+	return Func("required", "is required", func(v T) bool { return v != zero })
+}
+
+// Min fails for values less than n.
+func Min[T cmp.Ordered](n T) Rule[T] {
+	return Func("min", fmt.Sprintf("must be at least %v", n), func(v T) bool { return v >= n })
+}
+
+// Max fails for values greater than n.
+func Max[T cmp.Ordered](n T) Rule[T] {
+	return Func("max", fmt.Sprintf("must be at most %v", n), func(v T) bool { return v <= n })
+}
+
+// Between fails for values outside of [lo, hi].
+func Between[T cmp.Ordered](lo, hi T) Rule[T] {
+	return Func("between", fmt.Sprintf("must be between %v and %v", lo, hi), func(v T) bool { return v >= lo && v <= hi })
+}
+
+// OneOf fails for values not in the list.
+func OneOf[T comparable](values ...T) Rule[T] {
+	return Func("oneof", fmt.Sprintf("must be one of %v", values), func(v T) bool { return slices.Contains(values, v) })
+}
+
+// MinLen fails for strings shorter than n characters.
+func MinLen(n int) Rule[string] {
+	return Func("minlen", fmt.Sprintf("must be at least %d characters", n), func(v string) bool { return utf8.RuneCountInString(v) >= n })
+}
+
+// MaxLen fails for strings longer than n characters.
+func MaxLen(n int) Rule[string] {
+	return Func("maxlen", fmt.Sprintf("must be at most %d characters", n), func(v string) bool { return utf8.RuneCountInString(v) <= n })
+}
+
+// Match fails for strings not matching re.
+func Match(re *regexp.Regexp) Rule[string] {
+	return Func("match", "must match "+re.String(), re.MatchString)
+}
+
+// Format fails for strings that are not in the registered format, such as "email", "url", or "uuid".
+func Format(name string) Rule[string] {
+	return Func(name, "must be a valid "+name, func(v string) bool {
+		fn, ok := lookupFormat(name)
+		return ok && fn(v)
+	})
+}
+
+var _uuid = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func isEmail(s string) bool {
+	a, err := mail.ParseAddress(s)
+	return err == nil && a.Address == s
+}
+
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
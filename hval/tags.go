@@ -0,0 +1,132 @@
+package hval
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var (
+	_formatsMu sync.RWMutex
+	_formats   = map[string]func(string) bool{
+		"email": isEmail,
+		"url":   isURL,
+		"uuid":  _uuid.MatchString,
+	}
+)
+
+// RegisterFormat registers a named string format usable with [Format] and the format tag rule.
+func RegisterFormat(name string, fn func(string) bool) {
+	_formatsMu.Lock()
+	defer _formatsMu.Unlock()
+
+	_formats[name] = fn
+}
+
+func lookupFormat(name string) (func(string) bool, bool) {
+	_formatsMu.RLock()
+	defer _formatsMu.RUnlock()
+
+	fn, ok := _formats[name]
+
+	return fn, ok
+}
+
+// Struct validates the fields of the struct v using their validate tags, recursing into nested structs.
+// Rules are comma separated: required, min=n, max=n, oneof=a b c, and format=name. For strings min and max
+// apply to the number of characters like [MinLen] and [MaxLen], and for slices and maps to the length. Fields are named after their json tag when present.
+func Struct(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validating: %T is not a struct", v)
+	}
+
+	var val Validator
+
+	validateStruct(&val, rv, "")
+
+	return val.Err()
+}
+
+func validateStruct(val *Validator, rv reflect.Value, prefix string) {
+	for i := range rv.NumField() {
+		sf := rv.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := prefix + fieldName(sf)
+		fv := rv.Field(i)
+
+		if tag, ok := sf.Tag.Lookup("validate"); ok && tag != "-" {
+			for rule := range strings.SplitSeq(tag, ",") {
+				key, arg, _ := strings.Cut(rule, "=")
+				validateRule(val, name, fv, key, arg)
+			}
+		}
+
+		if s := reflect.Indirect(fv); s.Kind() == reflect.Struct {
+			validateStruct(val, s, name+".")
+		}
+	}
+}
+
+func validateRule(val *Validator, name string, fv reflect.Value, key, arg string) {
+	switch key {
+	case "required":
+		val.Check(name, !fv.IsZero(), key, "is required")
+	case "min", "max":
+		n, ok := measure(fv)
+		if !ok {
+			return
+		}
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			val.Check(name, false, key, "has invalid rule "+key+"="+arg)
+			return
+		}
+		if key == "min" {
+			val.Check(name, n >= limit, key, "must be at least "+arg)
+		} else {
+			val.Check(name, n <= limit, key, "must be at most "+arg)
+		}
+	case "oneof":
+		s := fmt.Sprint(fv.Interface())
+		val.Check(name, fv.IsZero() || slices.Contains(strings.Fields(arg), s), key, "must be one of "+arg)
+	case "format":
+		if fv.Kind() != reflect.String || fv.Len() == 0 {
+			return
+		}
+		fn, ok := lookupFormat(arg)
+		val.Check(name, ok && fn(fv.String()), arg, "must be a valid "+arg)
+	}
+}
+
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func fieldName(sf reflect.StructField) string {
+	if tag := sf.Tag.Get("json"); tag != "" && tag != "-" {
+		if name, _, _ := strings.Cut(tag, ","); name != "" {
+			return name
+		}
+	}
+	return sf.Name
+}
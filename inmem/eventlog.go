@@ -0,0 +1,84 @@
+package inmem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrVersionConflict is returned when an append to an [EventLog] stream
+// expects a version that differs from the current one.
+var ErrVersionConflict = errors.New("version conflict")
+
+// Event is a single entry of an [EventLog] stream.
+type Event[K comparable] struct {
+	Stream   K
+	Sequence uint64
+	Type     string
+	Data     any
+}
+
+// EventLog is an append-only in-memory store of per-aggregate event streams.
+type EventLog[K comparable] struct {
+	mu      sync.RWMutex
+	streams map[K][]Event[K]
+}
+
+// NewEventLog creates a new in-memory event log.
+func NewEventLog[K comparable]() *EventLog[K] {
+	return &EventLog[K]{
+		streams: make(map[K][]Event[K]),
+	}
+}
+
+// Append adds events to the stream identified by id if its current version
+// equals expected, assigning stream and sequence numbers to each event.
+// It returns the new version of the stream which is its last sequence number.
+func (l *EventLog[K]) Append(ctx context.Context, id K, expected uint64, events ...Event[K]) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stream := l.streams[id]
+	version := uint64(len(stream))
+
+	if version != expected {
+		return version, fmt.Errorf("appending: expected version %d, got %d: %w", expected, version, ErrVersionConflict)
+	}
+
+	for _, e := range events {
+		version++
+		e.Stream, e.Sequence = id, version
+		stream = append(stream, e)
+	}
+
+	l.streams[id] = stream
+
+	return version, nil
+}
+
+// ReadStream returns a copy of the events of the stream identified by id
+// with a sequence number greater than after.
+func (l *EventLog[K]) ReadStream(ctx context.Context, id K, after uint64) ([]Event[K], error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	stream, exists := l.streams[id]
+	if !exists {
+		return nil, &KeyError{Op: "reading stream", Key: id, Err: ErrNotFound}
+	}
+
+	if after >= uint64(len(stream)) {
+		return nil, nil
+	}
+
+	return append([]Event[K](nil), stream[after:]...), nil
+}
+
+// Version returns the current version of the stream identified by id, which is zero for unknown streams.
+func (l *EventLog[K]) Version(ctx context.Context, id K) uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return uint64(len(l.streams[id]))
+}
@@ -0,0 +1,83 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const _expirySweepInterval = time.Minute
+
+type expiryOption[V any] struct{ value func(V) time.Time }
+
+// WithExpiry expires values once the time returned by expires passes, such as for sessions or nonces kept for a
+// TTL. The zero time never expires. Expired values are no longer loaded, found, or iterated over, and saving a
+// value with the key of an expired one replaces it. They are removed, updating views, sizes, and watchers like
+// [Repository.DeleteWhere], by writes at most once a minute or by [Repository.Expire]. V must be the value type
+// of the repository.
+func WithExpiry[V any](expires func(V) time.Time) RepositoryOption {
+	return expiryOption[V]{value: expires}
+}
+
+func (o expiryOption[V]) apply(cfg *repositoryConfig) { cfg.expires = o.value }
+
+func newExpiry[V any](expires any) func(V) time.Time {
+	if expires == nil {
+		return nil
+	}
+	f, ok := expires.(func(V) time.Time)
+	if !ok {
+		panic(fmt.Sprintf("inmem: expiry %T does not take the value type %T", expires, *new(V)))
+	}
+	return f
+}
+
+// Expire removes the expired values of a repository created [WithExpiry] and returns how many were removed.
+func (r *Repository[K, V]) Expire(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.sweep(time.Now())
+}
+
+// expired reports whether the value has expired by now.
+func (r *Repository[K, V]) expired(val *V, now time.Time) bool {
+	if r.expires == nil {
+		return false
+	}
+	exp := r.expires(*val)
+	return !exp.IsZero() && !now.Before(exp)
+}
+
+// sweepIfDue removes the expired values of the locked repository when the sweep interval passed since the last
+// sweep, or when the value of key has expired so that it can be replaced. Values that a [Restrict] relation
+// keeps are left for a later sweep.
+func (r *Repository[K, V]) sweepIfDue(key K, now time.Time) {
+	if r.expires == nil {
+		return
+	}
+	if v, ok := r.data.get(key); !(ok && r.expired(v, now)) && now.Sub(r.swept) < _expirySweepInterval {
+		return
+	}
+	r.sweep(now)
+}
+
+// sweep removes the expired values of the locked repository.
+func (r *Repository[K, V]) sweep(now time.Time) (int, error) {
+	r.swept = now
+
+	var keys []K
+	for key, val := range r.data.all() {
+		if r.expired(val, now) {
+			keys = append(keys, key)
+		}
+	}
+
+	finish, err := r.prepareDelete(keys)
+	if err != nil {
+		return 0, err
+	}
+	finish(true)
+
+	return len(keys), nil
+}
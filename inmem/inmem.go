@@ -4,25 +4,96 @@ package inmem
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+var (
+	// ErrNotFound is wrapped by a [KeyError] when loading a key that does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyExists is wrapped by a [KeyError] when saving a key that already exists.
+	ErrAlreadyExists = errors.New("already exists")
+)
+
+// KeyError reports the key an operation failed for, so that handlers can tell which resource is missing or
+// conflicting from an error matched with [errors.As].
+type KeyError struct {
+	Op  string
+	Key any
+	Err error
+}
+
+// Error returns the operation, key, and underlying error.
+func (e *KeyError) Error() string { return fmt.Sprintf("%s %v: %v", e.Op, e.Key, e.Err) }
+
+// Unwrap returns the underlying error.
+func (e *KeyError) Unwrap() error { return e.Err }
+
 // IDer is an interface for types that have an ID of type K.
 type IDer[K comparable] interface {
 	ID() K
 }
 
+// KeySetter is implemented by pointers to values whose key is assigned by [WithKeyGenerator].
+type KeySetter[K comparable] interface {
+	SetID(K)
+}
+
+// Storer is the interface implemented by [Repository] that alternative stores can satisfy to share its semantics.
+type Storer[K comparable, V IDer[K]] interface {
+	Load(ctx context.Context, val *V) error
+	Save(ctx context.Context, val *V) error
+}
+
 // Repository is a generic in-memory repository for types that implement the IDer interface.
 type Repository[K comparable, V IDer[K]] struct {
-	mu   sync.RWMutex
-	data map[K]*V
+	mu       sync.RWMutex
+	data     storage[K, V]
+	views    map[string]observer[K, V]
+	watchers map[*watcher[K, V]]struct{}
+	size     *sizeTracker[K]
+	newKey   func() K
+	uniques  []*uniqueIndex[K, V]
+	rel      relations[K, V]
+	snapshot atomic.Pointer[snapshot[K, V]]
+	expires  func(V) time.Time
+	swept    time.Time
 }
 
 // NewRepository creates a new in-memory repository.
-func NewRepository[K comparable, V IDer[K]]() *Repository[K, V] {
-	return &Repository[K, V]{
-		data: make(map[K]*V),
+func NewRepository[K comparable, V IDer[K]](opts ...RepositoryOption) *Repository[K, V] {
+	var cfg repositoryConfig
+
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	r := &Repository[K, V]{
+		data: mapStorage[K, V]{},
+	}
+
+	if cfg.slabSize > 0 {
+		r.data = newSlabStorage[K, V](cfg.slabSize)
+	}
+
+	if cfg.sizeTracking {
+		r.size = &sizeTracker[K]{sizes: make(map[K]int64), limit: cfg.memoryLimit, onExceed: cfg.onExceed}
 	}
+
+	r.uniques = newUniqueIndexes[K, V](cfg.uniques)
+	r.expires = newExpiry[V](cfg.expires)
+
+	if cfg.keyGenerator != nil {
+		gen, ok := cfg.keyGenerator.(func() K)
+		if !ok {
+			panic(fmt.Sprintf("inmem: key generator %T does not return the key type %T", cfg.keyGenerator, *new(K)))
+		}
+		r.newKey = gen
+	}
+
+	return r
 }
 
 // Load retrieves a value by its ID and populates the provided pointer.
@@ -34,9 +105,9 @@ func (r *Repository[K, V]) Load(ctx context.Context, val *V) error {
 		return errors.New("loading: value empty")
 	}
 
-	v, exists := r.data[(*val).ID()]
-	if !exists || v == nil {
-		return errors.New("loading: not found")
+	v, exists := r.data.get((*val).ID())
+	if !exists || v == nil || r.expired(v, time.Now()) {
+		return &KeyError{Op: "loading", Key: (*val).ID(), Err: ErrNotFound}
 	}
 
 	*val = *v
@@ -44,22 +115,161 @@ func (r *Repository[K, V]) Load(ctx context.Context, val *V) error {
 	return nil
 }
 
-// Save stores a value in the repository.
+// Save stores a value in the repository, assigning it a key first if it has the zero key and the repository
+// was created [WithKeyGenerator].
 func (r *Repository[K, V]) Save(ctx context.Context, val *V) error {
+	_, err := r.Create(ctx, val)
+	return err
+}
+
+// Create stores a value like [Repository.Save] and returns its key, such as the one it was assigned.
+func (r *Repository[K, V]) Create(ctx context.Context, val *V) (K, error) {
+	defer r.checkMemoryLimit()
+	defer r.lockParents()()
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	var zero K
+
 	if val == nil {
-		return errors.New("saving: value empty")
+		return zero, errors.New("saving: value empty")
 	}
 
 	key := (*val).ID()
 
-	if _, exists := r.data[key]; exists {
-		return errors.New("saving: already exists")
+	if key == zero && r.newKey != nil {
+		setter, ok := any(val).(KeySetter[K])
+		if !ok {
+			return zero, fmt.Errorf("saving: %T does not implement KeySetter", val)
+		}
+		if key = r.newKey(); key == zero {
+			return zero, errors.New("saving: generated zero key")
+		}
+		setter.SetID(key)
 	}
 
-	r.data[key] = val
+	r.sweepIfDue(key, time.Now())
 
-	return nil
+	if _, exists := r.data.get(key); exists {
+		return zero, &KeyError{Op: "saving", Key: key, Err: ErrAlreadyExists}
+	}
+
+	if len(r.uniques) > 0 || len(r.rel.refs) > 0 {
+		writes := map[K]V{key: *val}
+		if err := r.checkUnique("saving", writes); err != nil {
+			return zero, err
+		}
+		if err := r.checkRefs("saving", writes); err != nil {
+			return zero, err
+		}
+	}
+
+	r.set(key, val)
+
+	return key, nil
+}
+
+// observer is notified of every mutation of a [Repository] while its write lock is held.
+// A nil value means the key was removed.
+type observer[K comparable, V any] interface {
+	observe(key K, val *V)
+}
+
+// set stores the value of key, accounting for its size and notifying observers.
+func (r *Repository[K, V]) set(key K, val *V) {
+	r.unindex(key)
+	r.data.set(key, val)
+	r.index(key, val)
+	if r.size != nil {
+		r.size.set(key, sizeOf(val))
+	}
+	r.notify(key, val)
+}
+
+// delete removes the value of key, accounting for its size and notifying observers.
+func (r *Repository[K, V]) delete(key K) {
+	r.unindex(key)
+	r.data.delete(key)
+	if r.size != nil {
+		r.size.delete(key)
+	}
+	r.notify(key, nil)
+}
+
+func (r *Repository[K, V]) notify(key K, val *V) {
+	r.snapshot.Store(nil)
+
+	for _, o := range r.views {
+		o.observe(key, val)
+	}
+
+	for w := range r.watchers {
+		w.observe(key, val)
+	}
+}
+
+// UpdateWhere replaces every value accepted by pred with the result of mutate under a single write lock,
+// so that no concurrent write slips between finding and updating values. It returns the number of values
+// updated. Values are left unchanged if mutate changes the ID of any of them or the updates violate a unique
+// constraint or relation.
+func (r *Repository[K, V]) UpdateWhere(ctx context.Context, pred func(V) bool, mutate func(V) V) (int, error) {
+	defer r.checkMemoryLimit()
+	defer r.lockParents()()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updates := make(map[K]V)
+	for key, val := range r.data.all() {
+		if !pred(*val) {
+			continue
+		}
+		v := mutate(*val)
+		if v.ID() != key {
+			return 0, errors.New("updating where: id changed")
+		}
+		updates[key] = v
+	}
+
+	if err := r.checkUnique("updating where", updates); err != nil {
+		return 0, err
+	}
+	if err := r.checkRefs("updating where", updates); err != nil {
+		return 0, err
+	}
+
+	for key, val := range updates {
+		r.set(key, &val)
+	}
+
+	return len(updates), nil
+}
+
+// DeleteWhere removes every value accepted by pred under a single write lock, updating views and notifying
+// watchers of each removal. It returns the number of values removed. Values referenced through a relation are
+// removed along with the values referencing them for [Cascade] relations, and none are removed when a [Restrict]
+// relation still references one.
+func (r *Repository[K, V]) DeleteWhere(ctx context.Context, pred func(V) bool) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var keys []K
+	for key, val := range r.data.all() {
+		if pred(*val) {
+			keys = append(keys, key)
+		}
+	}
+
+	finish, err := r.prepareDelete(keys)
+	if err != nil {
+		return 0, err
+	}
+	finish(true)
+
+	return len(keys), nil
+}
+
+// Clear removes all values like [Repository.DeleteWhere] accepting every value.
+func (r *Repository[K, V]) Clear(ctx context.Context) error {
+	_, err := r.DeleteWhere(ctx, func(V) bool { return true })
+	return err
 }
@@ -0,0 +1,277 @@
+// Package inmemtest provides utilities for verifying that [inmem.Storer] implementations match inmem semantics.
+package inmemtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/drakelthedragon/bazaar/inmem"
+)
+
+const (
+	_defaultWorkers = 8
+	_defaultKeys    = 64
+	_defaultOps     = 1000
+
+	_defaultWatchTimeout = time.Second
+)
+
+// Watcher is implemented by stores delivering their changes like [inmem.Repository.Watch], whose ordering
+// [Check] verifies.
+type Watcher[K comparable, V any] interface {
+	Watch(ctx context.Context) <-chan inmem.Change[K, V]
+}
+
+// Workload describes a concurrent mix of saves and loads run by [Check].
+type Workload[K comparable, V inmem.IDer[K]] struct {
+	// Workers is the number of goroutines issuing operations.
+	Workers int
+	// Keys is the number of distinct keys the workers contend on.
+	Keys int
+	// Ops is the number of operations issued by each worker.
+	Ops int
+	// Seed makes the operation mix reproducible.
+	Seed uint64
+	// New returns a value whose ID is derived from key. Values built with
+	// different versions of the same key must be distinguishable by Equal.
+	New func(key, version int) V
+	// Equal reports whether two values are equal, defaulting to [reflect.DeepEqual].
+	Equal func(a, b V) bool
+	// Lookup returns the value a secondary index of the store maps key to, such as a unique constraint or
+	// an [inmem.View], so that Check also verifies the index. Lookups are mixed into the workload when set.
+	Lookup func(ctx context.Context, key int) (V, bool)
+	// WatchTimeout is how long Check waits for the changes of a [Watcher] once the workload finishes,
+	// defaulting to a second.
+	WatchTimeout time.Duration
+}
+
+type save[V any] struct {
+	value      V
+	worker, op int
+}
+
+// Check runs the workload against s and verifies its invariants:
+//   - exactly one concurrent Save of a key succeeds,
+//   - loads never observe a value other than the one successfully saved,
+//   - the saved value is not lost once the workload finishes,
+//   - keys that were never saved cannot be loaded,
+//   - the index of Lookup agrees with the saved values, during and after the workload,
+//   - a store implementing [Watcher] delivers exactly one change for each successful Save, in the order each
+//     worker issued them.
+//
+// All violations are joined into the returned error.
+func Check[K comparable, V inmem.IDer[K]](ctx context.Context, s inmem.Storer[K, V], w Workload[K, V]) error {
+	w.setDefaultZeroValues()
+
+	if w.New == nil {
+		return errors.New("workload must provide New")
+	}
+
+	var (
+		mu      sync.Mutex
+		winners = make(map[int][]save[V])
+		loaded  = make(map[int][]V)
+		indexed = make(map[int][]V)
+		errs    []error
+		wg      sync.WaitGroup
+	)
+
+	var changes <-chan inmem.Change[K, V]
+	if watcher, ok := s.(Watcher[K, V]); ok {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		changes = watcher.Watch(watchCtx)
+	}
+
+	kinds := 2
+	if w.Lookup != nil {
+		kinds = 3
+	}
+
+	for worker := range w.Workers {
+		wg.Go(func() {
+			rng := rand.New(rand.NewPCG(w.Seed, uint64(worker)))
+			for op := range w.Ops {
+				key := rng.IntN(w.Keys)
+
+				switch rng.IntN(kinds) {
+				case 0:
+					v := w.New(key, worker*w.Ops+op+1)
+					if err := s.Save(ctx, &v); err == nil {
+						mu.Lock()
+						winners[key] = append(winners[key], save[V]{value: v, worker: worker, op: op})
+						mu.Unlock()
+					}
+				case 1:
+					v := w.New(key, 0)
+					if err := s.Load(ctx, &v); err == nil {
+						mu.Lock()
+						loaded[key] = append(loaded[key], v)
+						mu.Unlock()
+					}
+				default:
+					if v, ok := w.Lookup(ctx, key); ok {
+						mu.Lock()
+						indexed[key] = append(indexed[key], v)
+						mu.Unlock()
+					}
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	keys := make(map[K]int, w.Keys)
+	for key := range w.Keys {
+		saved := winners[key]
+
+		switch {
+		case len(saved) > 1:
+			errs = append(errs, fmt.Errorf("key %d: %d concurrent saves succeeded, want 1", key, len(saved)))
+			for _, s := range saved {
+				keys[s.value.ID()] = -1
+			}
+			continue
+		case len(saved) == 0:
+			v := w.New(key, 0)
+			if err := s.Load(ctx, &v); err == nil {
+				errs = append(errs, fmt.Errorf("key %d: loaded a value that was never saved", key))
+			}
+			if w.Lookup != nil {
+				if _, ok := w.Lookup(ctx, key); ok {
+					errs = append(errs, fmt.Errorf("key %d: index holds a value that was never saved", key))
+				}
+			}
+			continue
+		}
+		want := saved[0].value
+		keys[want.ID()] = key
+
+		for _, v := range loaded[key] {
+			if !w.Equal(v, want) {
+				errs = append(errs, fmt.Errorf("key %d: loaded %v, want %v", key, v, want))
+				break
+			}
+		}
+
+		for _, v := range indexed[key] {
+			if !w.Equal(v, want) {
+				errs = append(errs, fmt.Errorf("key %d: index returned %v, want %v", key, v, want))
+				break
+			}
+		}
+
+		v := w.New(key, 0)
+		if err := s.Load(ctx, &v); err != nil {
+			errs = append(errs, fmt.Errorf("key %d: lost saved value: %w", key, err))
+		} else if !w.Equal(v, want) {
+			errs = append(errs, fmt.Errorf("key %d: final value %v, want %v", key, v, want))
+		}
+
+		if w.Lookup != nil {
+			if v, ok := w.Lookup(ctx, key); !ok {
+				errs = append(errs, fmt.Errorf("key %d: index lost saved value", key))
+			} else if !w.Equal(v, want) {
+				errs = append(errs, fmt.Errorf("key %d: final index value %v, want %v", key, v, want))
+			}
+		}
+	}
+
+	if changes != nil {
+		errs = append(errs, w.checkChanges(changes, keys, winners)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkChanges receives a change for each key saved once and verifies that each matches the winning Save of its
+// key, and that the changes of each worker arrive in the order it issued the saves. Keys mapped to -1 were saved
+// more than once, which Check already reported, and their changes are ignored.
+func (w *Workload[K, V]) checkChanges(changes <-chan inmem.Change[K, V], keys map[K]int, winners map[int][]save[V]) []error {
+	var (
+		errs   []error
+		seen   = make(map[int]bool, len(keys))
+		lastOp = make(map[int]int, w.Workers)
+	)
+
+	timer := time.NewTimer(w.WatchTimeout)
+	defer timer.Stop()
+
+	want := 0
+	for _, key := range keys {
+		if key >= 0 {
+			want++
+		}
+	}
+
+	for len(seen) < want {
+		var (
+			c  inmem.Change[K, V]
+			ok bool
+		)
+		select {
+		case <-timer.C:
+			return append(errs, fmt.Errorf("watch: received %d of %d changes", len(seen), want))
+		case c, ok = <-changes:
+		}
+		if !ok {
+			return append(errs, fmt.Errorf("watch: closed after %d of %d changes", len(seen), want))
+		}
+
+		key, ok := keys[c.Key]
+		switch {
+		case key < 0:
+			continue
+		case !ok:
+			errs = append(errs, fmt.Errorf("watch: change of key %v that was never saved", c.Key))
+			continue
+		case c.Deleted:
+			errs = append(errs, fmt.Errorf("key %d: watch delivered a deletion", key))
+			continue
+		case seen[key]:
+			errs = append(errs, fmt.Errorf("key %d: watch delivered a second change", key))
+			continue
+		}
+		seen[key] = true
+
+		s := winners[key][0]
+		if !w.Equal(c.Value, s.value) {
+			errs = append(errs, fmt.Errorf("key %d: watch delivered %v, want %v", key, c.Value, s.value))
+		}
+		if last, ok := lastOp[s.worker]; ok && last > s.op {
+			errs = append(errs, fmt.Errorf("key %d: watch delivered operation %d of worker %d after operation %d",
+				key, s.op, s.worker, last))
+		}
+		lastOp[s.worker] = max(lastOp[s.worker], s.op)
+	}
+
+	return errs
+}
+
+func (w *Workload[K, V]) setDefaultZeroValues() {
+	if w.Workers <= 0 {
+		w.Workers = _defaultWorkers
+	}
+
+	if w.Keys <= 0 {
+		w.Keys = _defaultKeys
+	}
+
+	if w.Ops <= 0 {
+		w.Ops = _defaultOps
+	}
+
+	if w.WatchTimeout <= 0 {
+		w.WatchTimeout = _defaultWatchTimeout
+	}
+
+	if w.Equal == nil {
+		w.Equal = func(a, b V) bool { return reflect.DeepEqual(a, b) }
+	}
+}
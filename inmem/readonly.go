@@ -0,0 +1,56 @@
+package inmem
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// List returns copies of all values in the repository, in no particular order.
+func (r *Repository[K, V]) List(ctx context.Context) ([]V, error) {
+	return r.Find(ctx, func(V) bool { return true })
+}
+
+// Find returns copies of the values accepted by pred, in no particular order.
+func (r *Repository[K, V]) Find(ctx context.Context, pred func(V) bool) ([]V, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var (
+		vals []V
+		now  = time.Now()
+	)
+	for _, val := range r.data.all() {
+		if !r.expired(val, now) && pred(*val) {
+			vals = append(vals, *val)
+		}
+	}
+
+	return vals, nil
+}
+
+// Reader is a read-only handle of a [Repository] returned by [Repository.ReadOnly], for components that must
+// never write to it such as presenters and reporting endpoints.
+type Reader[K comparable, V IDer[K]] struct {
+	r *Repository[K, V]
+}
+
+// ReadOnly returns a [Reader] of the repository.
+func (r *Repository[K, V]) ReadOnly() Reader[K, V] { return Reader[K, V]{r: r} }
+
+// Load calls [Repository.Load].
+func (ro Reader[K, V]) Load(ctx context.Context, val *V) error { return ro.r.Load(ctx, val) }
+
+// List calls [Repository.List].
+func (ro Reader[K, V]) List(ctx context.Context) ([]V, error) { return ro.r.List(ctx) }
+
+// Find calls [Repository.Find].
+func (ro Reader[K, V]) Find(ctx context.Context, pred func(V) bool) ([]V, error) {
+	return ro.r.Find(ctx, pred)
+}
+
+// Watch calls [Repository.Watch].
+func (ro Reader[K, V]) Watch(ctx context.Context) <-chan Change[K, V] { return ro.r.Watch(ctx) }
+
+// All calls [Repository.All].
+func (ro Reader[K, V]) All(opts ...IterOption) iter.Seq2[K, V] { return ro.r.All(opts...) }
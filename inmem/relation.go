@@ -0,0 +1,177 @@
+package inmem
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrReferenced is wrapped by a [RelationError] when deleting a value still referenced through a [Restrict]
+	// relation.
+	ErrReferenced = errors.New("still referenced")
+	// ErrMissingReference is wrapped by a [RelationError] when writing a value referencing one that does not exist.
+	ErrMissingReference = errors.New("missing reference")
+)
+
+// RelationError reports the relation registered with [HasMany] that a write or delete violated.
+type RelationError struct {
+	Op       string
+	Relation string
+	// Key is the key of the value written or deleted and Ref the key of the value referenced or referencing it.
+	Key, Ref any
+	Err      error
+}
+
+// Error returns the operation, relation, and keys.
+func (e *RelationError) Error() string {
+	return fmt.Sprintf("%s %v: %s %v: %v", e.Op, e.Key, e.Relation, e.Ref, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RelationError) Unwrap() error { return e.Err }
+
+// DeletePolicy is what deleting a value referenced through a relation does to the values referencing it.
+type DeletePolicy int
+
+const (
+	// Restrict fails deleting values that are referenced.
+	Restrict DeletePolicy = iota
+	// Cascade deletes the values referencing the deleted ones.
+	Cascade
+)
+
+// relations holds the checks of a repository taking part in relations.
+type relations[K comparable, V any] struct {
+	// parents read locks the repositories referenced by values of this one, returning a function unlocking them.
+	parents []func() func()
+	// refs checks that the values written reference existing values.
+	refs []func(op string, key K, val V) error
+	// deletes prepare deleting the keys from the repositories referencing this one, which stay locked until
+	// the returned function commits or aborts the deletion.
+	deletes []func(keys []K) (func(commit bool), error)
+}
+
+// HasMany relates each value of child to the value of parent whose key is returned by foreignKey, with the zero
+// key meaning none. Saving and updating children then fails with [ErrMissingReference] unless their parent exists,
+// and deleting parents applies the policy to their children. Relations must not form cycles, and repositories are
+// locked from parent to child to keep them consistent.
+func HasMany[PK comparable, P IDer[PK], CK comparable, C IDer[CK]](name string, parent *Repository[PK, P], child *Repository[CK, C], foreignKey func(C) PK, onDelete DeletePolicy) {
+	if any(parent) == any(child) {
+		panic("inmem: relations within one repository are not supported")
+	}
+
+	parent.mu.Lock()
+	defer parent.mu.Unlock()
+	child.mu.Lock()
+	defer child.mu.Unlock()
+
+	child.rel.parents = append(child.rel.parents, func() func() {
+		parent.mu.RLock()
+		return parent.mu.RUnlock
+	})
+
+	child.rel.refs = append(child.rel.refs, func(op string, key CK, val C) error {
+		var zero PK
+		if ref := foreignKey(val); ref != zero {
+			if _, exists := parent.data.get(ref); !exists {
+				return &RelationError{Op: op, Relation: name, Key: key, Ref: ref, Err: ErrMissingReference}
+			}
+		}
+		return nil
+	})
+
+	parent.rel.deletes = append(parent.rel.deletes, func(keys []PK) (func(bool), error) {
+		deleted := make(map[PK]struct{}, len(keys))
+		for _, key := range keys {
+			deleted[key] = struct{}{}
+		}
+
+		child.mu.Lock()
+
+		var children []CK
+		for key, val := range child.data.all() {
+			if _, ok := deleted[foreignKey(*val)]; ok {
+				if onDelete == Restrict {
+					child.mu.Unlock()
+					return nil, &RelationError{Op: "deleting", Relation: name, Key: foreignKey(*val), Ref: key, Err: ErrReferenced}
+				}
+				children = append(children, key)
+			}
+		}
+
+		finish, err := child.prepareDelete(children)
+		if err != nil {
+			child.mu.Unlock()
+			return nil, err
+		}
+
+		return func(commit bool) {
+			finish(commit)
+			child.mu.Unlock()
+		}, nil
+	})
+}
+
+// BelongsTo is [HasMany] registered from the side of the child.
+func BelongsTo[CK comparable, C IDer[CK], PK comparable, P IDer[PK]](name string, child *Repository[CK, C], parent *Repository[PK, P], foreignKey func(C) PK, onDelete DeletePolicy) {
+	HasMany(name, parent, child, foreignKey, onDelete)
+}
+
+// lockParents read locks the repositories referenced by this one, before it is locked itself.
+func (r *Repository[K, V]) lockParents() func() {
+	r.mu.RLock()
+	parents := r.rel.parents
+	r.mu.RUnlock()
+
+	unlocks := make([]func(), len(parents))
+	for i, lock := range parents {
+		unlocks[i] = lock()
+	}
+
+	return func() {
+		for _, unlock := range unlocks {
+			unlock()
+		}
+	}
+}
+
+func (r *Repository[K, V]) checkRefs(op string, writes map[K]V) error {
+	for _, ref := range r.rel.refs {
+		for key, val := range writes {
+			if err := ref(op, key, val); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// prepareDelete checks that the keys can be deleted from the locked repository and returns a function deleting
+// them, along with the values referencing them, when committed.
+func (r *Repository[K, V]) prepareDelete(keys []K) (func(commit bool), error) {
+	var finishes []func(bool)
+
+	if len(keys) > 0 {
+		for _, prepare := range r.rel.deletes {
+			finish, err := prepare(keys)
+			if err != nil {
+				for _, f := range finishes {
+					f(false)
+				}
+				return nil, err
+			}
+			finishes = append(finishes, finish)
+		}
+	}
+
+	return func(commit bool) {
+		for _, f := range finishes {
+			f(commit)
+		}
+		if commit {
+			for _, key := range keys {
+				r.delete(key)
+			}
+		}
+	}, nil
+}
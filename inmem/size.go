@@ -0,0 +1,125 @@
+package inmem
+
+import (
+	"reflect"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Sizer is implemented by values reporting their approximate size in bytes for [WithSizeTracking].
+type Sizer interface {
+	Size() int
+}
+
+// Stats describe the contents of a [Repository].
+type Stats struct {
+	// Len is the number of values.
+	Len int
+	// Bytes is the estimated size of the values, zero without [WithSizeTracking].
+	Bytes int64
+}
+
+// Stats returns the number and estimated size of the values in the repository.
+func (r *Repository[K, V]) Stats() Stats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s := Stats{Len: r.data.len()}
+	if r.size != nil {
+		s.Bytes = r.size.bytes.Load()
+	}
+
+	return s
+}
+
+// checkMemoryLimit calls the callback of [WithMemoryLimit] when it is exceeded. It is deferred before the lock
+// of the repository is taken, so that it runs once the lock is released.
+func (r *Repository[K, V]) checkMemoryLimit() {
+	if r.size == nil || r.size.onExceed == nil || r.size.bytes.Load() <= r.size.limit {
+		return
+	}
+	r.size.onExceed(r.Stats())
+}
+
+type sizeTracker[K comparable] struct {
+	bytes    atomic.Int64
+	sizes    map[K]int64
+	limit    int64
+	onExceed func(Stats)
+}
+
+func (t *sizeTracker[K]) set(key K, size int64) {
+	t.bytes.Add(size - t.sizes[key])
+	t.sizes[key] = size
+}
+
+func (t *sizeTracker[K]) delete(key K) {
+	t.bytes.Add(-t.sizes[key])
+	delete(t.sizes, key)
+}
+
+// sizeOf returns the size reported by a [Sizer] or else estimated by reflection.
+func sizeOf[V any](val *V) int64 {
+	if s, ok := any(*val).(Sizer); ok {
+		return int64(s.Size())
+	}
+	v := reflect.ValueOf(val).Elem()
+	return int64(v.Type().Size()) + estimate(v, make(map[uintptr]struct{}))
+}
+
+// estimate returns the bytes referenced by v beyond its own size, counting memory shared through pointers once.
+func estimate(v reflect.Value, seen map[uintptr]struct{}) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		if v.Kind() == reflect.Pointer {
+			if _, ok := seen[v.Pointer()]; ok {
+				return 0
+			}
+			seen[v.Pointer()] = struct{}{}
+		}
+		e := v.Elem()
+		return int64(e.Type().Size()) + estimate(e, seen)
+	case reflect.Slice:
+		if v.IsNil() {
+			return 0
+		}
+		if _, ok := seen[v.Pointer()]; ok {
+			return 0
+		}
+		seen[v.Pointer()] = struct{}{}
+		n := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := range v.Len() {
+			n += estimate(v.Index(i), seen)
+		}
+		return n
+	case reflect.Array:
+		var n int64
+		for i := range v.Len() {
+			n += estimate(v.Index(i), seen)
+		}
+		return n
+	case reflect.Struct:
+		var n int64
+		for i := range v.NumField() {
+			n += estimate(v.Field(i), seen)
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() {
+			return 0
+		}
+		// Buckets hold keys and values with about an eighth of overhead at their typical load.
+		entry := int64(v.Type().Key().Size()+v.Type().Elem().Size()) * 9 / 8
+		n := int64(unsafe.Sizeof(uintptr(0))) * 6
+		for iter := v.MapRange(); iter.Next(); {
+			n += entry + estimate(iter.Key(), seen) + estimate(iter.Value(), seen)
+		}
+		return n
+	}
+	return 0
+}
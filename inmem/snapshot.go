@@ -0,0 +1,90 @@
+package inmem
+
+import (
+	"iter"
+	"time"
+)
+
+// IterOption configures [Repository.All].
+type IterOption interface{ apply(*iterConfig) }
+
+type iterConfig struct {
+	snapshot bool
+}
+
+type snapshotOption struct{}
+
+// WithSnapshot iterates over a snapshot of the repository instead of holding its read lock, so that long
+// enumerations such as exports neither block writers nor observe their writes. Snapshots are copied at most once
+// between writes and shared by the iterations in the meantime.
+func WithSnapshot() IterOption { return snapshotOption{} }
+
+func (snapshotOption) apply(cfg *iterConfig) { cfg.snapshot = true }
+
+type snapshot[K comparable, V any] struct {
+	keys []K
+	vals []V
+}
+
+// All iterates over copies of the values in the repository, in no particular order, holding its read lock for
+// the duration of the iteration unless [WithSnapshot] is given.
+func (r *Repository[K, V]) All(opts ...IterOption) iter.Seq2[K, V] {
+	var cfg iterConfig
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	if cfg.snapshot {
+		return func(yield func(K, V) bool) {
+			s := r.takeSnapshot()
+			now := time.Now()
+			for i, k := range s.keys {
+				if r.expired(&s.vals[i], now) {
+					continue
+				}
+				if !yield(k, s.vals[i]) {
+					return
+				}
+			}
+		}
+	}
+
+	return func(yield func(K, V) bool) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		now := time.Now()
+		for k, v := range r.data.all() {
+			if r.expired(v, now) {
+				continue
+			}
+			if !yield(k, *v) {
+				return
+			}
+		}
+	}
+}
+
+// takeSnapshot returns the snapshot taken since the last write, or takes one.
+func (r *Repository[K, V]) takeSnapshot() *snapshot[K, V] {
+	if s := r.snapshot.Load(); s != nil {
+		return s
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if s := r.snapshot.Load(); s != nil {
+		return s
+	}
+
+	n := r.data.len()
+	s := &snapshot[K, V]{keys: make([]K, 0, n), vals: make([]V, 0, n)}
+	for k, v := range r.data.all() {
+		s.keys, s.vals = append(s.keys, k), append(s.vals, *v)
+	}
+
+	r.snapshot.Store(s)
+
+	return s
+}
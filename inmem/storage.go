@@ -0,0 +1,162 @@
+package inmem
+
+import (
+	"iter"
+	"sync/atomic"
+)
+
+// RepositoryOption applies options to a [Repository].
+type RepositoryOption interface{ apply(*repositoryConfig) }
+
+type repositoryConfig struct {
+	slabSize     int
+	sizeTracking bool
+	memoryLimit  int64
+	onExceed     func(Stats)
+	keyGenerator any
+	uniques      []uniqueSpec
+	expires      any
+}
+
+type (
+	slabStorageOption  struct{ value int }
+	sizeTrackingOption struct{}
+	memoryLimitOption  struct {
+		bytes    int64
+		onExceed func(Stats)
+	}
+	keyGeneratorOption[K comparable] struct{ value func() K }
+)
+
+// WithSlabStorage stores values by copy in pre-allocated slabs of n values instead of a map of pointers.
+// Large repositories benefit from the reduced number of pointers the garbage collector has to scan,
+// especially when the key type itself contains no pointers.
+func WithSlabStorage(n int) RepositoryOption { return slabStorageOption{value: n} }
+
+// WithSizeTracking estimates the memory used by the values of the repository, reported by [Repository.Stats].
+// Values implementing [Sizer] report their own size, others are estimated by walking them with reflection.
+func WithSizeTracking() RepositoryOption { return sizeTrackingOption{} }
+
+// WithMemoryLimit tracks sizes like [WithSizeTracking] and calls onExceed after each write leaving the estimated
+// size of the values above bytes, without holding the lock of the repository, so that it can evict values such as
+// with [Repository.DeleteWhere].
+func WithMemoryLimit(bytes int64, onExceed func(Stats)) RepositoryOption {
+	return memoryLimitOption{bytes: bytes, onExceed: onExceed}
+}
+
+// WithKeyGenerator assigns the keys returned by gen, such as UUIDs or a [Sequence], to values saved with the
+// zero key, like a database assigning primary keys. The values must implement [KeySetter] through their pointer,
+// and K must be the key type of the repository.
+func WithKeyGenerator[K comparable](gen func() K) RepositoryOption {
+	return keyGeneratorOption[K]{value: gen}
+}
+
+// Sequence returns a key generator counting up from 1.
+func Sequence[K ~int | ~int32 | ~int64 | ~uint | ~uint32 | ~uint64]() func() K {
+	var n atomic.Uint64
+	return func() K { return K(n.Add(1)) }
+}
+
+func (o slabStorageOption) apply(cfg *repositoryConfig) { cfg.slabSize = o.value }
+func (sizeTrackingOption) apply(cfg *repositoryConfig)  { cfg.sizeTracking = true }
+
+func (o keyGeneratorOption[K]) apply(cfg *repositoryConfig) { cfg.keyGenerator = o.value }
+
+func (o memoryLimitOption) apply(cfg *repositoryConfig) {
+	cfg.sizeTracking, cfg.memoryLimit, cfg.onExceed = true, o.bytes, o.onExceed
+}
+
+type storage[K comparable, V any] interface {
+	get(key K) (*V, bool)
+	set(key K, val *V)
+	delete(key K)
+	len() int
+	all() iter.Seq2[K, *V]
+}
+
+type mapStorage[K comparable, V any] map[K]*V
+
+func (s mapStorage[K, V]) get(key K) (*V, bool) {
+	v, exists := s[key]
+	return v, exists && v != nil
+}
+
+func (s mapStorage[K, V]) set(key K, val *V) { s[key] = val }
+
+func (s mapStorage[K, V]) delete(key K) { delete(s, key) }
+
+func (s mapStorage[K, V]) len() int { return len(s) }
+
+func (s mapStorage[K, V]) all() iter.Seq2[K, *V] {
+	return func(yield func(K, *V) bool) {
+		for k, v := range s {
+			if v != nil && !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+type slabStorage[K comparable, V any] struct {
+	size  int
+	index map[K]int
+	slabs [][]V
+	next  int
+	free  []int
+}
+
+func newSlabStorage[K comparable, V any](size int) *slabStorage[K, V] {
+	return &slabStorage[K, V]{
+		size:  size,
+		index: make(map[K]int),
+	}
+}
+
+func (s *slabStorage[K, V]) get(key K) (*V, bool) {
+	i, exists := s.index[key]
+	if !exists {
+		return nil, false
+	}
+	return &s.slabs[i/s.size][i%s.size], true
+}
+
+func (s *slabStorage[K, V]) set(key K, val *V) {
+	i, exists := s.index[key]
+	if !exists {
+		if n := len(s.free); n > 0 {
+			i, s.free = s.free[n-1], s.free[:n-1]
+		} else {
+			i = s.next
+			s.next++
+			if i/s.size == len(s.slabs) {
+				s.slabs = append(s.slabs, make([]V, s.size))
+			}
+		}
+		s.index[key] = i
+	}
+	s.slabs[i/s.size][i%s.size] = *val
+}
+
+// delete zeroes the slot of key so that it holds no references, and reuses it for the next new key.
+func (s *slabStorage[K, V]) delete(key K) {
+	i, exists := s.index[key]
+	if !exists {
+		return
+	}
+	delete(s.index, key)
+	var zero V
+	s.slabs[i/s.size][i%s.size] = zero
+	s.free = append(s.free, i)
+}
+
+func (s *slabStorage[K, V]) len() int { return len(s.index) }
+
+func (s *slabStorage[K, V]) all() iter.Seq2[K, *V] {
+	return func(yield func(K, *V) bool) {
+		for k, i := range s.index {
+			if !yield(k, &s.slabs[i/s.size][i%s.size]) {
+				return
+			}
+		}
+	}
+}
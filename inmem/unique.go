@@ -0,0 +1,108 @@
+package inmem
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrConstraintViolation is wrapped by a [ConstraintError] when a write violates a unique constraint.
+var ErrConstraintViolation = errors.New("constraint violation")
+
+// ConstraintError reports the unique constraint declared [WithUnique] that a write violated.
+type ConstraintError struct {
+	Op         string
+	Constraint string
+	// Key is the key of the value written and Owner the key of the value already holding Value.
+	Key, Owner any
+	Value      any
+}
+
+// Error returns the operation, constraint, and conflicting keys.
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%s %v: %v: %s %v is taken by %v", e.Op, e.Key, ErrConstraintViolation, e.Constraint, e.Value, e.Owner)
+}
+
+// Unwrap returns [ErrConstraintViolation].
+func (e *ConstraintError) Unwrap() error { return ErrConstraintViolation }
+
+type uniqueSpec struct {
+	name string
+	key  any
+}
+
+type uniqueOption[V any] struct {
+	name string
+	key  func(V) any
+}
+
+// WithUnique declares the unique constraint name over the values returned by key, which must be comparable,
+// such as an email address or a struct of several fields for a composite key. Writes giving a value the same
+// key as another value fail with a [ConstraintError]. V must be the value type of the repository.
+func WithUnique[V any](name string, key func(V) any) RepositoryOption {
+	return uniqueOption[V]{name: name, key: key}
+}
+
+func (o uniqueOption[V]) apply(cfg *repositoryConfig) {
+	cfg.uniques = append(cfg.uniques, uniqueSpec{name: o.name, key: o.key})
+}
+
+type uniqueIndex[K comparable, V any] struct {
+	name   string
+	key    func(V) any
+	owners map[any]K
+}
+
+func newUniqueIndexes[K comparable, V any](specs []uniqueSpec) []*uniqueIndex[K, V] {
+	indexes := make([]*uniqueIndex[K, V], len(specs))
+	for i, spec := range specs {
+		key, ok := spec.key.(func(V) any)
+		if !ok {
+			panic(fmt.Sprintf("inmem: unique constraint %s key %T does not take the value type %T", spec.name, spec.key, *new(V)))
+		}
+		indexes[i] = &uniqueIndex[K, V]{name: spec.name, key: key, owners: make(map[any]K)}
+	}
+	return indexes
+}
+
+// checkUnique returns a [ConstraintError] if writing the values would violate a unique constraint, either with
+// values already stored under other keys or among themselves.
+func (r *Repository[K, V]) checkUnique(op string, writes map[K]V) error {
+	for _, u := range r.uniques {
+		taken := make(map[any]K, len(writes))
+		for key, val := range writes {
+			v := u.key(val)
+			if owner, ok := taken[v]; ok {
+				return &ConstraintError{Op: op, Constraint: u.name, Key: key, Owner: owner, Value: v}
+			}
+			taken[v] = key
+			if owner, ok := u.owners[v]; ok && owner != key {
+				if _, rewritten := writes[owner]; !rewritten {
+					return &ConstraintError{Op: op, Constraint: u.name, Key: key, Owner: owner, Value: v}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// unindex removes the unique values of the value stored under key.
+func (r *Repository[K, V]) unindex(key K) {
+	if len(r.uniques) == 0 {
+		return
+	}
+	old, ok := r.data.get(key)
+	if !ok {
+		return
+	}
+	for _, u := range r.uniques {
+		if v := u.key(*old); u.owners[v] == key {
+			delete(u.owners, v)
+		}
+	}
+}
+
+func (r *Repository[K, V]) index(key K, val *V) {
+	for _, u := range r.uniques {
+		u.owners[u.key(*val)] = key
+	}
+}
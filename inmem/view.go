@@ -0,0 +1,91 @@
+package inmem
+
+import (
+	"iter"
+	"sync"
+)
+
+// View is a read model derived from a [Repository] and kept up to date on every mutation.
+type View[K comparable, P any] struct {
+	mu    sync.RWMutex
+	name  string
+	items map[K]P
+}
+
+// MaterializedView registers a [View] named name on the repository, projecting every value accepted by filter.
+// A nil filter accepts all values. Registering a view with the same name replaces the previous one.
+func MaterializedView[K comparable, V IDer[K], P any](r *Repository[K, V], name string, project func(V) P, filter func(V) bool) *View[K, P] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v := &materializedView[K, V, P]{
+		View:    &View[K, P]{name: name, items: make(map[K]P)},
+		project: project,
+		filter:  filter,
+	}
+
+	for key, val := range r.data.all() {
+		v.observe(key, val)
+	}
+
+	if r.views == nil {
+		r.views = make(map[string]observer[K, V])
+	}
+
+	r.views[name] = v
+
+	return v.View
+}
+
+// Name returns the name the view was registered with.
+func (v *View[K, P]) Name() string { return v.name }
+
+// Get returns the projection stored for key.
+func (v *View[K, P]) Get(key K) (P, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	p, exists := v.items[key]
+
+	return p, exists
+}
+
+// Len returns the number of projections in the view.
+func (v *View[K, P]) Len() int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return len(v.items)
+}
+
+// All iterates over the projections in the view holding its read lock for the duration of the iteration.
+func (v *View[K, P]) All() iter.Seq2[K, P] {
+	return func(yield func(K, P) bool) {
+		v.mu.RLock()
+		defer v.mu.RUnlock()
+
+		for k, p := range v.items {
+			if !yield(k, p) {
+				return
+			}
+		}
+	}
+}
+
+type materializedView[K comparable, V any, P any] struct {
+	*View[K, P]
+	project func(V) P
+	filter  func(V) bool
+}
+
+func (v *materializedView[K, V, P]) observe(key K, val *V) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if val == nil || (v.filter != nil && !v.filter(*val)) {
+		delete(v.items, key)
+		return
+	}
+
+	v.items[key] = v.project(*val)
+}
@@ -0,0 +1,87 @@
+package inmem
+
+import (
+	"context"
+	"sync"
+)
+
+// Change describes a mutation of a [Repository] delivered by [Repository.Watch].
+type Change[K comparable, V any] struct {
+	Key     K
+	Value   V
+	Deleted bool
+}
+
+// Watch returns a channel receiving every change made to the repository, in order, until ctx is done.
+// Changes are queued without blocking writers, so slow receivers only delay their own delivery.
+func (r *Repository[K, V]) Watch(ctx context.Context) <-chan Change[K, V] {
+	w := &watcher[K, V]{
+		ch:     make(chan Change[K, V]),
+		signal: make(chan struct{}, 1),
+	}
+
+	r.mu.Lock()
+	if r.watchers == nil {
+		r.watchers = make(map[*watcher[K, V]]struct{})
+	}
+	r.watchers[w] = struct{}{}
+	r.mu.Unlock()
+
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.watchers, w)
+			r.mu.Unlock()
+			close(w.ch)
+		}()
+		w.deliver(ctx)
+	}()
+
+	return w.ch
+}
+
+type watcher[K comparable, V any] struct {
+	mu     sync.Mutex
+	queue  []Change[K, V]
+	ch     chan Change[K, V]
+	signal chan struct{}
+}
+
+func (w *watcher[K, V]) observe(key K, val *V) {
+	c := Change[K, V]{Key: key, Deleted: val == nil}
+	if val != nil {
+		c.Value = *val
+	}
+
+	w.mu.Lock()
+	w.queue = append(w.queue, c)
+	w.mu.Unlock()
+
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (w *watcher[K, V]) deliver(ctx context.Context) {
+	for {
+		w.mu.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.mu.Unlock()
+
+		for _, c := range queue {
+			select {
+			case w.ch <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-w.signal:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
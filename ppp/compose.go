@@ -0,0 +1,41 @@
+package ppp
+
+import (
+	"context"
+	"errors"
+)
+
+// ProcessorFunc is an adapter to use a function as a [Processor].
+type ProcessorFunc[I, O any] func(context.Context, I) (O, error)
+
+// Process calls f(ctx, i).
+func (f ProcessorFunc[I, O]) Process(ctx context.Context, i I) (O, error) { return f(ctx, i) }
+
+// Fallback returns a [Processor] using secondary when primary fails, such as cached enrichment when the live
+// lookup fails. It does not fall back once ctx is done. When both fail the errors are joined.
+func Fallback[I, O any](primary, secondary Processor[I, O]) Processor[I, O] {
+	return ProcessorFunc[I, O](func(ctx context.Context, i I) (O, error) {
+		o, err := primary.Process(ctx, i)
+		if err == nil || ctx.Err() != nil {
+			return o, err
+		}
+
+		o, err2 := secondary.Process(ctx, i)
+		if err2 != nil {
+			return o, errors.Join(err, err2)
+		}
+		return o, nil
+	})
+}
+
+// OnError returns a [Processor] passing the errors of p to handle along with the input, which either recovers
+// with an output or returns an error, such as a wrapped or more specific one.
+func OnError[I, O any](p Processor[I, O], handle func(ctx context.Context, i I, err error) (O, error)) Processor[I, O] {
+	return ProcessorFunc[I, O](func(ctx context.Context, i I) (O, error) {
+		o, err := p.Process(ctx, i)
+		if err != nil {
+			return handle(ctx, i, err)
+		}
+		return o, nil
+	})
+}
@@ -0,0 +1,33 @@
+package ppp
+
+import "time"
+
+// Config configures pipelines from the cfg tags read by hcfg.Load, such as nested under "pipeline" in the
+// configuration of a service alongside hio.ServeConfig.
+type Config struct {
+	FlushBytes     int           `cfg:"flush_bytes" default:"32768"`
+	FlushInterval  time.Duration `cfg:"flush_interval" default:"1s"`
+	FlushItems     int           `cfg:"flush_items"`
+	SQLBatchSize   int           `cfg:"sql_batch_size" default:"500"`
+	SQLMaxParams   int           `cfg:"sql_max_params" default:"999"`
+	KafkaBatchSize int           `cfg:"kafka_batch_size" default:"100"`
+	ItemTimeout    time.Duration `cfg:"item_timeout"`
+}
+
+// FlushOptions returns the options of [NewFlushWriter] and [NewStreamPresenter] set by the configuration.
+func (c Config) FlushOptions() []FlushOption {
+	return []FlushOption{WithFlushBytes(c.FlushBytes), WithFlushInterval(c.FlushInterval), WithFlushItems(c.FlushItems)}
+}
+
+// SQLOptions returns the options of [NewSQLPresenter] set by the configuration.
+func (c Config) SQLOptions() []SQLOption {
+	return []SQLOption{WithBatchSize(c.SQLBatchSize), WithMaxParams(c.SQLMaxParams)}
+}
+
+// KafkaOptions returns the options of [NewKafkaPresenter] set by the configuration.
+func (c Config) KafkaOptions() []KafkaOption {
+	return []KafkaOption{WithKafkaBatchSize(c.KafkaBatchSize)}
+}
+
+// WatchOptions returns the options of [Watch] set by the configuration.
+func (c Config) WatchOptions() []WatchOption { return []WatchOption{WithItemTimeout(c.ItemTimeout)} }
@@ -0,0 +1,339 @@
+package ppp
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RowError is an error of a single CSV value.
+type RowError struct {
+	Line   int
+	Column string
+	Err    error
+}
+
+// Error returns the line, column, and underlying error.
+func (e *RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d, column %s: %v", e.Line, e.Column, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RowError) Unwrap() error { return e.Err }
+
+// RowErrors is returned by the parser of [NewCSVParser] with the rows that could not be parsed.
+type RowErrors []*RowError
+
+// Error returns the errors one per line.
+func (errs RowErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors.
+func (errs RowErrors) Unwrap() []error {
+	all := make([]error, len(errs))
+	for i, err := range errs {
+		all[i] = err
+	}
+	return all
+}
+
+// CSVOption configures [NewCSVParser].
+type CSVOption interface{ apply(*csvConfig) }
+
+type csvConfig struct {
+	delimiter  rune
+	comment    rune
+	lazyQuotes bool
+	thousands  rune
+	decimal    rune
+	layouts    []string
+
+	// serialDates accepts times as the day serial numbers of spreadsheets.
+	serialDates bool
+}
+
+type (
+	delimiterOption    struct{ value rune }
+	commentOption      struct{ value rune }
+	lazyQuotesOption   struct{}
+	numberFormatOption struct{ thousands, decimal rune }
+	timeLayoutsOption  struct{ value []string }
+)
+
+// WithDelimiter sets the field delimiter, such as ';' or '\t'. Defaults to ','.
+func WithDelimiter(r rune) CSVOption { return delimiterOption{value: r} }
+
+// WithComment skips lines starting with the rune, such as '#'.
+func WithComment(r rune) CSVOption { return commentOption{value: r} }
+
+// WithLazyQuotes accepts quotes in unquoted fields and unescaped quotes in quoted fields.
+func WithLazyQuotes() CSVOption { return lazyQuotesOption{} }
+
+// WithNumberFormat sets the separators of numbers, such as '.' and ',' for 1.234,5 in German locales.
+// A zero thousands separator accepts none. Defaults to ',' and '.'.
+func WithNumberFormat(thousands, decimal rune) CSVOption {
+	return numberFormatOption{thousands: thousands, decimal: decimal}
+}
+
+// WithTimeLayouts sets the layouts tried in order to parse [time.Time] fields.
+// Defaults to [time.RFC3339], [time.DateTime], and [time.DateOnly].
+func WithTimeLayouts(layouts ...string) CSVOption { return timeLayoutsOption{value: layouts} }
+
+func (o delimiterOption) apply(cfg *csvConfig)   { cfg.delimiter = o.value }
+func (o commentOption) apply(cfg *csvConfig)     { cfg.comment = o.value }
+func (lazyQuotesOption) apply(cfg *csvConfig)    { cfg.lazyQuotes = true }
+func (o timeLayoutsOption) apply(cfg *csvConfig) { cfg.layouts = o.value }
+
+func (o numberFormatOption) apply(cfg *csvConfig) {
+	cfg.thousands, cfg.decimal = o.thousands, o.decimal
+}
+
+var (
+	_timeType            = reflect.TypeFor[time.Time]()
+	_durationType        = reflect.TypeFor[time.Duration]()
+	_textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// NewCSVParser returns a [Parser] reading CSV with a header row into a slice of structs of type T.
+// Columns map to fields by the names of their csv tag, such as `csv:"email,e-mail,mail"` listing aliases after
+// the name, or else of their json tag or field name, ignoring case. Columns without a field are ignored.
+//
+// Values are converted to the field type: numbers with the separators of [WithNumberFormat], times with the
+// layouts of [WithTimeLayouts], durations, booleans, types implementing [encoding.TextUnmarshaler], and pointers
+// to them, which stay nil for empty values. Rows with invalid values are skipped and the parser returns the
+// other rows with [RowErrors] giving the line and column of each invalid value.
+func NewCSVParser[T any](opts ...CSVOption) Parser[[]T] {
+	cfg := csvConfig{
+		delimiter: ',',
+		thousands: ',',
+		decimal:   '.',
+		layouts:   []string{time.RFC3339, time.DateTime, time.DateOnly},
+	}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	return ParserFunc[[]T](func(r io.Reader) ([]T, error) {
+		cr := csv.NewReader(r)
+		cr.Comma, cr.Comment, cr.LazyQuotes = cfg.delimiter, cfg.comment, cfg.lazyQuotes
+		cr.FieldsPerRecord = -1
+
+		header, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading csv header: %w", err)
+		}
+
+		dec, err := newRowDecoder[T](cfg, header)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			rows []T
+			errs RowErrors
+		)
+
+		for {
+			record, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				var pe *csv.ParseError
+				if !errors.As(err, &pe) {
+					return rows, fmt.Errorf("reading csv: %w", err)
+				}
+				errs = append(errs, &RowError{Line: pe.Line, Err: pe.Err})
+				continue
+			}
+
+			row, rerrs := dec.decode(record, func(i int) int {
+				line, _ := cr.FieldPos(i)
+				return line
+			})
+			if rerrs != nil {
+				errs = append(errs, rerrs...)
+				continue
+			}
+			rows = append(rows, row)
+		}
+
+		if len(errs) > 0 {
+			return rows, errs
+		}
+		return rows, nil
+	})
+}
+
+// rowDecoder decodes records of values into structs of type T by the header naming their columns.
+type rowDecoder[T any] struct {
+	cfg     csvConfig
+	header  []string
+	columns [][]int
+}
+
+func newRowDecoder[T any](cfg csvConfig, header []string) (*rowDecoder[T], error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("decoding rows into %s: expected a struct", typ)
+	}
+
+	fields := tagFields(typ, "csv")
+	columns := make([][]int, len(header))
+	for i, name := range header {
+		columns[i] = fields[strings.ToLower(strings.TrimSpace(name))]
+	}
+
+	return &rowDecoder[T]{cfg: cfg, header: header, columns: columns}, nil
+}
+
+// decode returns the row of the record, or the errors of its invalid values located by line.
+func (d *rowDecoder[T]) decode(record []string, line func(column int) int) (T, RowErrors) {
+	var (
+		row  T
+		errs RowErrors
+		v    = reflect.ValueOf(&row).Elem()
+	)
+	for i, value := range record {
+		if i >= len(d.columns) || d.columns[i] == nil {
+			continue
+		}
+		if err := d.cfg.set(v.FieldByIndex(d.columns[i]), value); err != nil {
+			errs = append(errs, &RowError{Line: line(i), Column: d.header[i], Err: err})
+		}
+	}
+	return row, errs
+}
+
+// tagFields maps the lowercase names and aliases of the fields of typ to their index, named by the tag, such as
+// `csv:"amount,total"`, or else by their JSON name or field name.
+func tagFields(typ reflect.Type, key string) map[string][]int {
+	fields := make(map[string][]int)
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous && f.Type.Kind() == reflect.Struct {
+			continue
+		}
+
+		names := []string{f.Name}
+		if tag, ok := f.Tag.Lookup(key); ok {
+			if tag == "-" {
+				continue
+			}
+			names = strings.Split(tag, ",")
+		} else if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name == "-" {
+			continue
+		} else if name != "" {
+			names = []string{name}
+		}
+
+		for _, name := range names {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				fields[name] = f.Index
+			}
+		}
+	}
+	return fields
+}
+
+func (cfg csvConfig) set(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+
+	if v.Kind() == reflect.Pointer {
+		if s == "" {
+			return nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	if s == "" && v.Kind() != reflect.String {
+		return nil
+	}
+
+	switch {
+	case v.Type() == _timeType:
+		for _, layout := range cfg.layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		if serial, err := strconv.ParseFloat(s, 64); err == nil && cfg.serialDates {
+			v.Set(reflect.ValueOf(fromSerial(serial)))
+			return nil
+		}
+		return fmt.Errorf("parsing time %q: expected one of the layouts %s", s, strings.Join(cfg.layouts, ", "))
+	case v.Type() == _durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Addr().Type().Implements(_textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(cfg.number(s), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(cfg.number(s), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(cfg.number(s), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+
+	return nil
+}
+
+// number removes the thousands separators of s and replaces its decimal separator with a dot.
+func (cfg csvConfig) number(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case cfg.thousands, ' ', ' ', ' ':
+			return -1
+		case cfg.decimal:
+			return '.'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,249 @@
+package ppp
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// ErrUnknownFormat is returned when presenting in a format that is not registered.
+var ErrUnknownFormat = errors.New("unknown format")
+
+// ParserFunc is an adapter to use a function as a [Parser].
+type ParserFunc[I any] func(io.Reader) (I, error)
+
+// Parse calls f(r).
+func (f ParserFunc[I]) Parse(r io.Reader) (I, error) { return f(r) }
+
+// PresenterFunc is an adapter to use a function as a [Presenter].
+type PresenterFunc[O any] func(io.Writer, O) error
+
+// Present calls f(w, o).
+func (f PresenterFunc[O]) Present(w io.Writer, o O) error { return f(w, o) }
+
+// FormatRegistry is a [Presenter] holding a presenter per output format, such as json, csv, or table,
+// selected at runtime with [Executor.ExecuteFormat] from a CLI flag or by [FormatRegistry.Negotiate]
+// from an Accept header. It presents in the first registered format by default.
+type FormatRegistry[O any] struct {
+	names      []string
+	presenters map[string]Presenter[O]
+	mediaTypes map[string]string
+}
+
+// NewFormatRegistry returns an empty [FormatRegistry].
+func NewFormatRegistry[O any]() *FormatRegistry[O] {
+	return &FormatRegistry[O]{presenters: make(map[string]Presenter[O]), mediaTypes: make(map[string]string)}
+}
+
+// Register adds or replaces the presenter of the format served with the media type.
+func (r *FormatRegistry[O]) Register(name, mediaType string, p Presenter[O]) *FormatRegistry[O] {
+	if _, ok := r.presenters[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.presenters[name], r.mediaTypes[name] = p, mediaType
+	return r
+}
+
+// Names returns the registered formats in registration order, such as for the usage of a CLI flag.
+func (r *FormatRegistry[O]) Names() []string { return slices.Clone(r.names) }
+
+// Format returns the presenter of the format, or of the default format when name is empty.
+func (r *FormatRegistry[O]) Format(name string) (Presenter[O], error) {
+	if name == "" && len(r.names) > 0 {
+		name = r.names[0]
+	}
+	p, ok := r.presenters[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expected one of %s", ErrUnknownFormat, name, strings.Join(r.names, ", "))
+	}
+	return p, nil
+}
+
+// Negotiate returns the format and media type of the first media range of the Accept header naming a registered
+// media type, in order of preference, or the default format for an empty header or a wildcard.
+func (r *FormatRegistry[O]) Negotiate(accept string) (name, mediaType string, err error) {
+	type candidate struct {
+		mediaType string
+		q         float64
+	}
+
+	var candidates []candidate
+	for part := range strings.SplitSeq(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			fmt.Sscanf(v, "%g", &q)
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{mediaType: mt, q: q})
+		}
+	}
+
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return cmp.Compare(b.q, a.q) })
+
+	if len(candidates) == 0 && len(r.names) > 0 {
+		return r.names[0], r.mediaTypes[r.names[0]], nil
+	}
+
+	for _, c := range candidates {
+		for _, name := range r.names {
+			if mt := r.mediaTypes[name]; mediaTypeMatch(c.mediaType, mt) {
+				return name, mt, nil
+			}
+		}
+	}
+
+	return "", "", fmt.Errorf("%w for %q", ErrUnknownFormat, accept)
+}
+
+func mediaTypeMatch(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	typ, ok := strings.CutSuffix(pattern, "/*")
+	return ok && strings.HasPrefix(mediaType, typ+"/")
+}
+
+// Present presents the output in the default format.
+func (r *FormatRegistry[O]) Present(w io.Writer, o O) error {
+	p, err := r.Format("")
+	if err != nil {
+		return err
+	}
+	return p.Present(w, o)
+}
+
+// NewJSONPresenter returns a [Presenter] encoding the output as JSON.
+func NewJSONPresenter[O any]() Presenter[O] {
+	return PresenterFunc[O](func(w io.Writer, o O) error {
+		if err := json.MarshalWrite(w, o); err != nil {
+			return fmt.Errorf("encoding json: %w", err)
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	})
+}
+
+// NewCSVPresenter returns a [Presenter] writing an output that is a slice of structs or maps as CSV, with a header
+// row naming the struct fields by their json tag or the sorted map keys.
+func NewCSVPresenter[O any]() Presenter[O] {
+	return PresenterFunc[O](func(w io.Writer, o O) error {
+		header, rows, err := tabulate(o)
+		if err != nil {
+			return err
+		}
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		for _, row := range rows {
+			cw.Write(cellStrings(row))
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+}
+
+// NewTablePresenter returns a [Presenter] writing an output that is a slice of structs or maps as an aligned
+// text table for terminals, with the same columns as [NewCSVPresenter].
+func NewTablePresenter[O any]() Presenter[O] {
+	return PresenterFunc[O](func(w io.Writer, o O) error {
+		header, rows, err := tabulate(o)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(cellStrings(row), "\t"))
+		}
+		return tw.Flush()
+	})
+}
+
+// tabulate turns a slice of structs or maps into a header and rows of values, nil for missing ones.
+func tabulate(o any) ([]string, [][]any, error) {
+	v := reflect.Indirect(reflect.ValueOf(o))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		v = reflect.Append(reflect.MakeSlice(reflect.SliceOf(v.Type()), 0, 1), v)
+	}
+
+	elem := v.Type().Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+
+	var (
+		header []string
+		rows   [][]any
+	)
+
+	switch elem.Kind() {
+	case reflect.Struct:
+		var fields []int
+		for i := range elem.NumField() {
+			f := elem.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			header, fields = append(header, name), append(fields, i)
+		}
+		for i := range v.Len() {
+			ev := reflect.Indirect(v.Index(i))
+			row := make([]any, len(fields))
+			if ev.IsValid() {
+				for j, fi := range fields {
+					row[j] = ev.Field(fi).Interface()
+				}
+			}
+			rows = append(rows, row)
+		}
+	case reflect.Map:
+		keys := make(map[string]struct{})
+		for i := range v.Len() {
+			for _, k := range v.Index(i).MapKeys() {
+				keys[fmt.Sprint(k.Interface())] = struct{}{}
+			}
+		}
+		for k := range keys {
+			header = append(header, k)
+		}
+		slices.Sort(header)
+		for i := range v.Len() {
+			m := v.Index(i)
+			row := make([]any, len(header))
+			for _, k := range m.MapKeys() {
+				row[slices.Index(header, fmt.Sprint(k.Interface()))] = m.MapIndex(k).Interface()
+			}
+			rows = append(rows, row)
+		}
+	default:
+		return nil, nil, fmt.Errorf("tabulating %s: expected a slice of structs or maps", v.Type())
+	}
+
+	return header, rows, nil
+}
+
+// cellStrings formats the values of a row, leaving missing ones empty.
+func cellStrings(row []any) []string {
+	cells := make([]string, len(row))
+	for i, v := range row {
+		if v != nil {
+			cells[i] = fmt.Sprint(v)
+		}
+	}
+	return cells
+}
@@ -0,0 +1,341 @@
+package ppp
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPOption configures [NewHTTPParser].
+type HTTPOption interface{ apply(*httpConfig) }
+
+type httpConfig struct {
+	header     http.Header
+	items      string
+	pagination pagination
+	interval   time.Duration
+	retry      RetryFunc
+}
+
+// RetryFunc calls fetch until it succeeds or gives up, such as when its error is not temporary.
+type RetryFunc func(ctx context.Context, fetch func(context.Context) error) error
+
+// HTTPStatusError is the error of a response to a page request with a status other than 2xx.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+// Error returns the URL and status.
+func (e *HTTPStatusError) Error() string { return fmt.Sprintf("fetching %s: %s", e.URL, e.Status) }
+
+// Temporary reports whether the request may succeed when retried, for 429 Too Many Requests and 5xx statuses.
+func (e *HTTPStatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// pagination follows the pages of an API.
+type pagination interface {
+	// first sets the query of the first page.
+	first(u *url.URL)
+	// next returns the URL of the page after the one fetched from u, or nil after the last page.
+	next(u *url.URL, res *http.Response, page jsontext.Value, items int) (*url.URL, error)
+}
+
+type (
+	requestHeaderOption   struct{ value http.Header }
+	itemsFieldOption      struct{ value string }
+	requestIntervalOption struct{ value time.Duration }
+	retryOption           struct{ value RetryFunc }
+	linkPagination        struct{}
+	cursorPagination      struct{ field, param string }
+	offsetPagination      struct {
+		offset, limit string
+		size          int
+	}
+)
+
+// WithRequestHeader sets headers sent with every request, such as Authorization.
+func WithRequestHeader(h http.Header) HTTPOption { return requestHeaderOption{value: h} }
+
+// WithItemsField sets the field of the response holding the array of items, with nested fields separated by
+// dots such as "data.items". Defaults to the response being the array.
+func WithItemsField(field string) HTTPOption { return itemsFieldOption{value: field} }
+
+// WithRequestInterval sets the minimum time between the start of requests, to stay under the rate limit of the API.
+func WithRequestInterval(d time.Duration) HTTPOption { return requestIntervalOption{value: d} }
+
+// WithRetry retries fetching pages with retry, such as the option returned by hretry.RetryPages retrying with a policy.
+// Failed fetches return an [HTTPStatusError] for responses other than 2xx.
+func WithRetry(retry RetryFunc) HTTPOption { return retryOption{value: retry} }
+
+// WithLinkPagination follows the URL of the Link header with rel="next", the default.
+func WithLinkPagination() HTTPOption { return linkPagination{} }
+
+// WithCursorPagination sends the cursor found in the field of the response, with nested fields separated by dots
+// such as "meta.next_cursor", as the query parameter, until the field is missing, null, or empty.
+func WithCursorPagination(field, param string) HTTPOption {
+	return cursorPagination{field: field, param: param}
+}
+
+// WithOffsetPagination sends the number of items already fetched and the page size as the query parameters,
+// such as "offset" and "limit", until a page has fewer items than size.
+func WithOffsetPagination(offset, limit string, size int) HTTPOption {
+	return offsetPagination{offset: offset, limit: limit, size: size}
+}
+
+func (o requestHeaderOption) apply(cfg *httpConfig)   { cfg.header = o.value }
+func (o itemsFieldOption) apply(cfg *httpConfig)      { cfg.items = o.value }
+func (o requestIntervalOption) apply(cfg *httpConfig) { cfg.interval = o.value }
+func (o retryOption) apply(cfg *httpConfig)           { cfg.retry = o.value }
+func (o linkPagination) apply(cfg *httpConfig)        { cfg.pagination = o }
+func (o cursorPagination) apply(cfg *httpConfig)      { cfg.pagination = o }
+func (o offsetPagination) apply(cfg *httpConfig)      { cfg.pagination = o }
+
+// NewHTTPParser returns a [Parser] streaming the items of the JSON pages of an API into values of type T, for
+// processing with [Map] and presenting with [NewStreamPresenter] or [NewSQLPresenter]. The input is not read:
+// pages are fetched with GET from rawURL as the items are iterated, and no longer once the iteration stops or
+// ctx is done. Responses other than 2xx fail the iteration unless retried with [WithRetry].
+func NewHTTPParser[T any](ctx context.Context, client *http.Client, rawURL string, opts ...HTTPOption) Parser[iter.Seq2[T, error]] {
+	cfg := httpConfig{
+		pagination: linkPagination{},
+		retry:      func(ctx context.Context, fetch func(context.Context) error) error { return fetch(ctx) },
+	}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	return ParserFunc[iter.Seq2[T, error]](func(io.Reader) (iter.Seq2[T, error], error) {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing url: %w", err)
+		}
+		cfg.pagination.first(u)
+
+		return func(yield func(T, error) bool) {
+			var (
+				zero T
+				last time.Time
+			)
+
+			for u := u; u != nil; {
+				var (
+					res  *http.Response
+					page jsontext.Value
+				)
+				err := cfg.retry(ctx, func(ctx context.Context) error {
+					if err := wait(ctx, last, cfg.interval); err != nil {
+						return err
+					}
+					last = time.Now()
+
+					var err error
+					res, page, err = cfg.fetch(ctx, client, u)
+					return err
+				})
+				if err != nil {
+					yield(zero, err)
+					return
+				}
+
+				items := page
+				if cfg.items != "" {
+					var ok bool
+					if items, ok = member(page, cfg.items); !ok {
+						yield(zero, fmt.Errorf("decoding page %s: missing field %s", u, cfg.items))
+						return
+					}
+				}
+
+				var values []jsontext.Value
+				if items.Kind() != 'n' {
+					if err := json.Unmarshal(items, &values); err != nil {
+						yield(zero, fmt.Errorf("decoding page %s: %w", u, err))
+						return
+					}
+				}
+
+				for _, value := range values {
+					var item T
+					if err := json.Unmarshal(value, &item); err != nil {
+						yield(zero, fmt.Errorf("decoding item: %w", err))
+						return
+					}
+					if !yield(item, nil) {
+						return
+					}
+				}
+
+				next, err := cfg.pagination.next(u, res, page, len(values))
+				if err != nil {
+					yield(zero, fmt.Errorf("paginating %s: %w", u, err))
+					return
+				}
+				if next != nil && next.String() == u.String() {
+					yield(zero, fmt.Errorf("paginating %s: next page is the same", u))
+					return
+				}
+				u = next
+			}
+		}, nil
+	})
+}
+
+// wait waits until interval passed since the last request, or ctx is done.
+func wait(ctx context.Context, last time.Time, interval time.Duration) error {
+	d := interval - time.Since(last)
+	if last.IsZero() || d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for rate limit: %w", context.Cause(ctx))
+	case <-timer.C:
+		return nil
+	}
+}
+
+// fetch gets the page at u.
+func (cfg httpConfig) fetch(ctx context.Context, client *http.Client, u *url.URL) (*http.Response, jsontext.Value, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching %s: %w", u, err)
+	}
+	for k, vs := range cfg.header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching %s: %w", u, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		io.Copy(io.Discard, io.LimitReader(res.Body, 4<<10))
+		return nil, nil, &HTTPStatusError{URL: u.String(), StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	var page jsontext.Value
+	if err := json.UnmarshalRead(res.Body, &page); err != nil {
+		return nil, nil, fmt.Errorf("decoding page %s: %w", u, err)
+	}
+	return res, page, nil
+}
+
+func (linkPagination) first(*url.URL) {}
+
+func (linkPagination) next(u *url.URL, res *http.Response, _ jsontext.Value, _ int) (*url.URL, error) {
+	for _, header := range res.Header.Values("Link") {
+		for link := range strings.SplitSeq(header, ",") {
+			target, params, ok := strings.Cut(link, ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for param := range strings.SplitSeq(params, ";") {
+				key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(key, "rel") || !relNext(strings.Trim(value, `"`)) {
+					continue
+				}
+				next, err := u.Parse(target[1 : len(target)-1])
+				if err != nil {
+					return nil, fmt.Errorf("parsing link: %w", err)
+				}
+				return next, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func relNext(rel string) bool {
+	for r := range strings.FieldsSeq(rel) {
+		if strings.EqualFold(r, "next") {
+			return true
+		}
+	}
+	return false
+}
+
+func (cursorPagination) first(*url.URL) {}
+
+func (p cursorPagination) next(u *url.URL, _ *http.Response, page jsontext.Value, _ int) (*url.URL, error) {
+	v, ok := member(page, p.field)
+	if !ok {
+		return nil, nil
+	}
+
+	var cursor string
+	switch v.Kind() {
+	case 'n':
+		return nil, nil
+	case '"':
+		if err := json.Unmarshal(v, &cursor); err != nil {
+			return nil, fmt.Errorf("decoding cursor: %w", err)
+		}
+	case '0':
+		cursor = string(v)
+	default:
+		return nil, fmt.Errorf("decoding cursor: unexpected %s", v.Kind())
+	}
+	if cursor == "" {
+		return nil, nil
+	}
+
+	next := *u
+	q := next.Query()
+	q.Set(p.param, cursor)
+	next.RawQuery = q.Encode()
+	return &next, nil
+}
+
+func (p offsetPagination) first(u *url.URL) {
+	q := u.Query()
+	q.Set(p.offset, "0")
+	q.Set(p.limit, strconv.Itoa(p.size))
+	u.RawQuery = q.Encode()
+}
+
+func (p offsetPagination) next(u *url.URL, _ *http.Response, _ jsontext.Value, items int) (*url.URL, error) {
+	if items == 0 || items < p.size {
+		return nil, nil
+	}
+
+	q := u.Query()
+	offset, _ := strconv.Atoi(q.Get(p.offset))
+	q.Set(p.offset, strconv.Itoa(offset+items))
+
+	next := *u
+	next.RawQuery = q.Encode()
+	return &next, nil
+}
+
+// member returns the value of the dotted field path of the JSON object v.
+func member(v jsontext.Value, path string) (jsontext.Value, bool) {
+	for name := range strings.SplitSeq(path, ".") {
+		var obj map[string]jsontext.Value
+		if v.Kind() != '{' || json.Unmarshal(v, &obj) != nil {
+			return nil, false
+		}
+		var ok bool
+		if v, ok = obj[name]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
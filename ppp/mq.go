@@ -0,0 +1,263 @@
+package ppp
+
+import (
+	"context"
+	"encoding"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+const _defaultKafkaBatchSize = 100
+
+// Delivery is a message received from a queue, acknowledged once it is processed or rejected for redelivery.
+// The messages of NATS JetStream implement it.
+type Delivery interface {
+	Data() []byte
+	Ack() error
+	Nak() error
+}
+
+// NATSMessages is a pull subscription shaped like the message iterators of NATS JetStream, whose Next returns
+// the next message, blocking until one arrives, and whose Stop unsubscribes. Next returning [io.EOF] ends the
+// stream.
+type NATSMessages interface {
+	Next() (Delivery, error)
+	Stop()
+}
+
+// NATSPublisher publishes messages to a subject, such as a NATS connection.
+type NATSPublisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// KafkaMessage is a message of a Kafka topic.
+type KafkaMessage struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+}
+
+// KafkaReader fetches messages from Kafka and commits their offsets, shaped like the readers of Kafka clients
+// with consumer groups. FetchMessage returning [io.EOF] ends the stream.
+type KafkaReader interface {
+	FetchMessage(ctx context.Context) (KafkaMessage, error)
+	CommitMessages(ctx context.Context, msgs ...KafkaMessage) error
+}
+
+// KafkaWriter writes messages to Kafka, shaped like the writers of Kafka clients.
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...KafkaMessage) error
+}
+
+// KafkaOption configures [NewKafkaPresenter].
+type KafkaOption interface{ apply(*kafkaConfig) }
+
+type kafkaConfig struct {
+	batchSize int
+}
+
+type kafkaBatchSizeOption struct{ value int }
+
+// WithKafkaBatchSize sets the number of messages written at once. Defaults to 100.
+func WithKafkaBatchSize(n int) KafkaOption { return kafkaBatchSizeOption{value: n} }
+
+func (o kafkaBatchSizeOption) apply(cfg *kafkaConfig) { cfg.batchSize = o.value }
+
+// NewChannelParser returns a [Parser] streaming the deliveries received from the channel into values of type T,
+// for processing with [Map] so that the same processor serves batch and event-driven pipelines. Data is decoded
+// with the UnmarshalBinary method of *T when it has one, or else as JSON. Each delivery is acknowledged once the
+// iteration moves past its item, that is once the item is processed and presented, and rejected for redelivery
+// when the iteration stops at it instead. A delivery that cannot be decoded is rejected and ends the stream with
+// an error, leaving poison messages to the redelivery limits of the queue. The input is not read, and the stream
+// ends when the channel is closed.
+func NewChannelParser[T any](deliveries <-chan Delivery) Parser[iter.Seq2[T, error]] {
+	return ParserFunc[iter.Seq2[T, error]](func(io.Reader) (iter.Seq2[T, error], error) {
+		return consume[T](func() (Delivery, error) {
+			d, ok := <-deliveries
+			if !ok {
+				return nil, io.EOF
+			}
+			return d, nil
+		}), nil
+	})
+}
+
+// NewNATSParser returns a [Parser] streaming the messages of the subscription like [NewChannelParser], stopping
+// the subscription once the iteration stops.
+func NewNATSParser[T any](msgs NATSMessages) Parser[iter.Seq2[T, error]] {
+	return ParserFunc[iter.Seq2[T, error]](func(io.Reader) (iter.Seq2[T, error], error) {
+		seq := consume[T](msgs.Next)
+		return func(yield func(T, error) bool) {
+			defer msgs.Stop()
+			seq(yield)
+		}, nil
+	})
+}
+
+// NewKafkaParser returns a [Parser] streaming the values of the messages of the reader like [NewChannelParser],
+// committing the offset of each message once the iteration moves past its item. Messages are not rejected, since
+// Kafka redelivers those whose offset was not committed once the consumer group rebalances. Messages are fetched
+// and committed with ctx, so that the stream ends with its error once ctx is done.
+func NewKafkaParser[T any](ctx context.Context, r KafkaReader) Parser[iter.Seq2[T, error]] {
+	return ParserFunc[iter.Seq2[T, error]](func(io.Reader) (iter.Seq2[T, error], error) {
+		return consume[T](func() (Delivery, error) {
+			msg, err := r.FetchMessage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return kafkaDelivery{ctx: ctx, r: r, msg: msg}, nil
+		}), nil
+	})
+}
+
+type kafkaDelivery struct {
+	ctx context.Context
+	r   KafkaReader
+	msg KafkaMessage
+}
+
+func (d kafkaDelivery) Data() []byte { return d.msg.Value }
+func (d kafkaDelivery) Ack() error   { return d.r.CommitMessages(d.ctx, d.msg) }
+func (d kafkaDelivery) Nak() error   { return nil }
+
+// consume returns a stream of the deliveries returned by next until it returns [io.EOF].
+func consume[T any](next func() (Delivery, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		for {
+			d, err := next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(zero, fmt.Errorf("receiving message: %w", err))
+				return
+			}
+
+			item, err := decodeMessage[T](d.Data())
+			if err != nil {
+				d.Nak()
+				yield(zero, fmt.Errorf("decoding message: %w", err))
+				return
+			}
+			if !yield(item, nil) {
+				d.Nak()
+				return
+			}
+			if err := d.Ack(); err != nil {
+				yield(zero, fmt.Errorf("acknowledging message: %w", err))
+				return
+			}
+		}
+	}
+}
+
+// NewChannelPresenter returns a [Presenter] sending a stream of items to the channel, encoded with the
+// MarshalBinary method of T when it has one, or else as JSON. The output is not written to.
+func NewChannelPresenter[T any](ch chan<- []byte) Presenter[iter.Seq2[T, error]] {
+	return produce[T](func(data []byte) error {
+		ch <- data
+		return nil
+	})
+}
+
+// NewNATSPresenter returns a [Presenter] publishing a stream of items to the subject, encoded like
+// [NewChannelPresenter].
+func NewNATSPresenter[T any](pub NATSPublisher, subject string) Presenter[iter.Seq2[T, error]] {
+	return produce[T](func(data []byte) error {
+		if err := pub.Publish(subject, data); err != nil {
+			return fmt.Errorf("publishing message: %w", err)
+		}
+		return nil
+	})
+}
+
+// NewKafkaPresenter returns a [Presenter] writing a stream of items to the writer in batches, encoded like
+// [NewChannelPresenter] and keyed by the Key method of items implementing [Keyer], so that the items of a key
+// stay in order on one partition. Since items are acknowledged by the parsers of this package before their batch
+// is written, a batch size of 1 is needed for at-least-once delivery from a queue to Kafka. Messages are written
+// with ctx.
+func NewKafkaPresenter[T any](ctx context.Context, w KafkaWriter, opts ...KafkaOption) Presenter[iter.Seq2[T, error]] {
+	cfg := kafkaConfig{batchSize: _defaultKafkaBatchSize}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	if cfg.batchSize <= 0 {
+		cfg.batchSize = _defaultKafkaBatchSize
+	}
+
+	return PresenterFunc[iter.Seq2[T, error]](func(_ io.Writer, seq iter.Seq2[T, error]) error {
+		batch := make([]KafkaMessage, 0, cfg.batchSize)
+		write := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := w.WriteMessages(ctx, batch...); err != nil {
+				return fmt.Errorf("writing messages: %w", err)
+			}
+			batch = batch[:0]
+			return nil
+		}
+
+		for item, err := range seq {
+			if err != nil {
+				return errors.Join(err, write())
+			}
+			data, err := encodeMessage(item)
+			if err != nil {
+				return errors.Join(fmt.Errorf("encoding message: %w", err), write())
+			}
+
+			msg := KafkaMessage{Value: data}
+			if k, ok := any(item).(Keyer); ok {
+				msg.Key = []byte(k.Key())
+			}
+			if batch = append(batch, msg); len(batch) == cfg.batchSize {
+				if err := write(); err != nil {
+					return err
+				}
+			}
+		}
+
+		return write()
+	})
+}
+
+// produce returns a [Presenter] passing the encoding of each item of a stream to send.
+func produce[T any](send func([]byte) error) Presenter[iter.Seq2[T, error]] {
+	return PresenterFunc[iter.Seq2[T, error]](func(_ io.Writer, seq iter.Seq2[T, error]) error {
+		for item, err := range seq {
+			if err != nil {
+				return err
+			}
+			data, err := encodeMessage(item)
+			if err != nil {
+				return fmt.Errorf("encoding message: %w", err)
+			}
+			if err := send(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func decodeMessage[T any](data []byte) (T, error) {
+	var item T
+	if u, ok := any(&item).(encoding.BinaryUnmarshaler); ok {
+		return item, u.UnmarshalBinary(data)
+	}
+	return item, json.Unmarshal(data, &item)
+}
+
+func encodeMessage(item any) ([]byte, error) {
+	if m, ok := item.(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	return json.Marshal(item)
+}
@@ -0,0 +1,318 @@
+package ppp
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"os"
+	"reflect"
+	"time"
+)
+
+const _defaultRowGroupSize = 64 << 10
+
+// ParquetFile is an open Parquet file with rows of type T, such as one opened by [OpenParquet]. Files that
+// [OpenParquet] does not read, such as those with nested columns, can be read with a library such as
+// github.com/parquet-go/parquet-go whose files, row groups, and column chunk statistics are adapted to
+// [ParquetFile], [ParquetRowGroup], and [ColumnStats], and whose writers to [ParquetWriter].
+type ParquetFile[T any] interface {
+	RowGroups() []ParquetRowGroup[T]
+}
+
+// ParquetRowGroup is a row group of a [ParquetFile].
+type ParquetRowGroup[T any] interface {
+	NumRows() int64
+	// Stats returns the statistics of the column from the row group metadata, if it has them.
+	Stats(column string) (ColumnStats, bool)
+	Rows() iter.Seq2[T, error]
+}
+
+// ColumnStats are the statistics of a column in a row group.
+type ColumnStats struct {
+	Min, Max  any
+	NullCount int64
+}
+
+// ParquetWriter writes rows of type T to a Parquet file.
+type ParquetWriter[T any] interface {
+	Write(rows []T) error
+	// Flush ends the current row group.
+	Flush() error
+	Close() error
+}
+
+// ParquetOpener opens a Parquet file of the size from r, such as [OpenParquet].
+type ParquetOpener[T any] func(r io.ReaderAt, size int64) (ParquetFile[T], error)
+
+// ParquetCreator starts a Parquet file written to w, such as [CreateParquet].
+type ParquetCreator[T any] func(w io.Writer) (ParquetWriter[T], error)
+
+// RowGroupFilter reports whether a row group may hold matching rows given the statistics of its columns.
+// Row groups it rejects are skipped without being read.
+type RowGroupFilter func(stats func(column string) (ColumnStats, bool)) bool
+
+// ColumnBetween returns a [RowGroupFilter] skipping row groups whose statistics of the column show that no value
+// is within lo and hi, inclusive. A nil bound is open. Numbers compare by value whatever their type, such as an
+// int bound against int64 statistics. Row groups without comparable statistics are kept, so the rows read still
+// need to be filtered.
+func ColumnBetween(column string, lo, hi any) RowGroupFilter {
+	return func(stats func(string) (ColumnStats, bool)) bool {
+		s, ok := stats(column)
+		if !ok {
+			return true
+		}
+		if c, ok := compareValues(s.Max, lo); ok && lo != nil && c < 0 {
+			return false
+		}
+		if c, ok := compareValues(s.Min, hi); ok && hi != nil && c > 0 {
+			return false
+		}
+		return true
+	}
+}
+
+// compareValues compares numbers by value and other values of the same ordered type, reporting false for values
+// that are not comparable.
+func compareValues(a, b any) (int, bool) {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		if !ok {
+			return 0, false
+		}
+		return x.compare(y), true
+	}
+
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		return cmp.Compare(a, b), ok
+	case []byte:
+		b, ok := b.([]byte)
+		return bytes.Compare(a, b), ok
+	case time.Time:
+		b, ok := b.(time.Time)
+		return a.Compare(b), ok
+	}
+	return 0, false
+}
+
+// numeric is a number normalised to the widest type of its kind.
+type numeric struct {
+	kind reflect.Kind // reflect.Int64, reflect.Uint64, or reflect.Float64
+	i    int64
+	u    uint64
+	f    float64
+}
+
+func number(v any) (numeric, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return numeric{kind: reflect.Int64, i: rv.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return numeric{kind: reflect.Uint64, u: rv.Uint()}, true
+	case reflect.Float32, reflect.Float64:
+		return numeric{kind: reflect.Float64, f: rv.Float()}, true
+	}
+	return numeric{}, false
+}
+
+func (x numeric) compare(y numeric) int {
+	switch {
+	case x.kind == reflect.Float64 || y.kind == reflect.Float64:
+		return cmp.Compare(x.float(), y.float())
+	case x.kind == reflect.Int64 && y.kind == reflect.Int64:
+		return cmp.Compare(x.i, y.i)
+	case x.kind == reflect.Uint64 && y.kind == reflect.Uint64:
+		return cmp.Compare(x.u, y.u)
+	case x.kind == reflect.Int64:
+		if x.i < 0 {
+			return -1
+		}
+		return cmp.Compare(uint64(x.i), y.u)
+	default:
+		if y.i < 0 {
+			return 1
+		}
+		return cmp.Compare(x.u, uint64(y.i))
+	}
+}
+
+// int returns the number as an int64, reporting whether it is exact.
+func (x numeric) int() (int64, bool) {
+	switch x.kind {
+	case reflect.Int64:
+		return x.i, true
+	case reflect.Uint64:
+		return int64(x.u), x.u <= math.MaxInt64
+	}
+	return int64(x.f), x.f == math.Trunc(x.f) && x.f >= math.MinInt64 && x.f < math.MaxInt64
+}
+
+// uint returns the number as a uint64, reporting whether it is exact.
+func (x numeric) uint() (uint64, bool) {
+	switch x.kind {
+	case reflect.Int64:
+		return uint64(x.i), x.i >= 0
+	case reflect.Uint64:
+		return x.u, true
+	}
+	return uint64(x.f), x.f == math.Trunc(x.f) && x.f >= 0 && x.f < math.MaxUint64
+}
+
+func (x numeric) float() float64 {
+	switch x.kind {
+	case reflect.Int64:
+		return float64(x.i)
+	case reflect.Uint64:
+		return float64(x.u)
+	}
+	return x.f
+}
+
+// ParquetOption configures [NewParquetParser] and [NewParquetPresenter].
+type ParquetOption interface{ apply(*parquetConfig) }
+
+type parquetConfig struct {
+	filters      []RowGroupFilter
+	rowGroupSize int
+}
+
+type (
+	rowGroupFilterOption struct{ value RowGroupFilter }
+	rowGroupSizeOption   struct{ value int }
+)
+
+// WithRowGroupFilter skips the row groups rejected by the filter when parsing. It can be given more than once.
+func WithRowGroupFilter(f RowGroupFilter) ParquetOption { return rowGroupFilterOption{value: f} }
+
+// WithRowGroupSize sets the number of rows per row group when presenting. Defaults to 65536.
+func WithRowGroupSize(n int) ParquetOption { return rowGroupSizeOption{value: n} }
+
+func (o rowGroupFilterOption) apply(cfg *parquetConfig) { cfg.filters = append(cfg.filters, o.value) }
+func (o rowGroupSizeOption) apply(cfg *parquetConfig)   { cfg.rowGroupSize = o.value }
+
+// NewParquetParser returns a [Parser] streaming the rows of a Parquet file opened by open, one row group at a
+// time, for processing with [Map] and presenting with [NewStreamPresenter]. Input that is not an [os.File] is
+// read into memory first, since Parquet files are read from their footer.
+func NewParquetParser[T any](open ParquetOpener[T], opts ...ParquetOption) Parser[iter.Seq2[T, error]] {
+	var cfg parquetConfig
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	return ParserFunc[iter.Seq2[T, error]](func(r io.Reader) (iter.Seq2[T, error], error) {
+		ra, size, err := readerAt(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading parquet: %w", err)
+		}
+
+		f, err := open(ra, size)
+		if err != nil {
+			return nil, fmt.Errorf("opening parquet: %w", err)
+		}
+
+		return func(yield func(T, error) bool) {
+			for _, rg := range f.RowGroups() {
+				if !cfg.keep(rg.Stats) {
+					continue
+				}
+				for row, err := range rg.Rows() {
+					if !yield(row, err) || err != nil {
+						return
+					}
+				}
+			}
+		}, nil
+	})
+}
+
+func (cfg parquetConfig) keep(stats func(string) (ColumnStats, bool)) bool {
+	for _, f := range cfg.filters {
+		if !f(stats) {
+			return false
+		}
+	}
+	return true
+}
+
+func readerAt(r io.Reader) (io.ReaderAt, int64, error) {
+	if f, ok := r.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			return f, fi.Size(), nil
+		}
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+// NewParquetPresenter returns a [Presenter] writing a stream of rows to a Parquet file started by create, ending a
+// row group every [WithRowGroupSize] rows so that memory stays bounded for large datasets.
+func NewParquetPresenter[T any](create ParquetCreator[T], opts ...ParquetOption) Presenter[iter.Seq2[T, error]] {
+	cfg := parquetConfig{rowGroupSize: _defaultRowGroupSize}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	if cfg.rowGroupSize <= 0 {
+		cfg.rowGroupSize = _defaultRowGroupSize
+	}
+
+	return PresenterFunc[iter.Seq2[T, error]](func(w io.Writer, seq iter.Seq2[T, error]) error {
+		pw, err := create(w)
+		if err != nil {
+			return fmt.Errorf("creating parquet: %w", err)
+		}
+
+		batch := make([]T, 0, min(cfg.rowGroupSize, 1024))
+		write := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := pw.Write(batch); err != nil {
+				return fmt.Errorf("writing parquet rows: %w", err)
+			}
+			batch = batch[:0]
+			return nil
+		}
+
+		n := 0
+		for row, err := range seq {
+			if err != nil {
+				pw.Close()
+				return err
+			}
+			batch = append(batch, row)
+			n++
+
+			if len(batch) == cap(batch) || n == cfg.rowGroupSize {
+				if err := write(); err != nil {
+					pw.Close()
+					return err
+				}
+			}
+			if n == cfg.rowGroupSize {
+				if err := pw.Flush(); err != nil {
+					pw.Close()
+					return fmt.Errorf("flushing parquet row group: %w", err)
+				}
+				n = 0
+			}
+		}
+
+		if err := write(); err != nil {
+			pw.Close()
+			return err
+		}
+		if err := pw.Close(); err != nil {
+			return fmt.Errorf("closing parquet: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,370 @@
+package ppp
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+var (
+	_parquetMagic = []byte("PAR1")
+	_bytesType    = reflect.TypeFor[[]byte]()
+)
+
+// Physical types of Parquet.
+const (
+	_parquetBoolean   = 0
+	_parquetInt32     = 1
+	_parquetInt64     = 2
+	_parquetInt96     = 3
+	_parquetFloat     = 4
+	_parquetDouble    = 5
+	_parquetByteArray = 6
+	_parquetFixed     = 7
+)
+
+// Converted types of Parquet, the annotations that logical types superseded.
+const (
+	_convertedUTF8            = 0
+	_convertedEnum            = 4
+	_convertedDecimal         = 5
+	_convertedDate            = 6
+	_convertedTimestampMillis = 9
+	_convertedTimestampMicros = 10
+	_convertedUint8           = 11
+	_convertedUint16          = 12
+	_convertedUint32          = 13
+	_convertedUint64          = 14
+	_convertedInt8            = 15
+	_convertedInt16           = 16
+	_convertedJSON            = 19
+)
+
+// Repetitions of Parquet columns.
+const (
+	_parquetRequired = 0
+	_parquetOptional = 1
+	_parquetRepeated = 2
+)
+
+// Encodings, compression codecs, and page types of Parquet.
+const (
+	_encodingPlain           = 0
+	_encodingPlainDictionary = 2
+	_encodingRLE             = 3
+	_encodingRLEDictionary   = 8
+
+	_codecUncompressed = 0
+	_codecSnappy       = 1
+	_codecGzip         = 2
+
+	_pageData       = 0
+	_pageDictionary = 2
+	_pageDataV2     = 3
+)
+
+// _julianUnixEpoch is the Julian day of the Unix epoch, from which INT96 timestamps count their days.
+const _julianUnixEpoch = 2440588
+
+// parquetColumn is a leaf column of a Parquet schema.
+type parquetColumn struct {
+	name       string
+	physical   int64
+	length     int // of fixed length byte arrays
+	repetition int64
+	converted  int64 // -1 without
+	scale      int
+	unit       time.Duration // of timestamps annotated by a logical type
+	unsigned   bool
+	text       bool
+	maxDef     int
+	maxRep     int
+}
+
+// annotate sets the converted type of the column, and its unit, signedness, and text flag from the converted
+// or logical type of its schema element.
+func (c *parquetColumn) annotate(el thriftStruct) {
+	c.converted = -1
+	if el.has(6) {
+		c.converted = el.int(6)
+	}
+	c.scale = int(el.int(7))
+
+	switch c.converted {
+	case _convertedUTF8, _convertedEnum, _convertedJSON:
+		c.text = true
+	case _convertedUint8, _convertedUint16, _convertedUint32, _convertedUint64:
+		c.unsigned = true
+	case _convertedTimestampMillis:
+		c.unit = time.Millisecond
+	case _convertedTimestampMicros:
+		c.unit = time.Microsecond
+	}
+
+	logical := el.child(10)
+	switch {
+	case logical.has(1), logical.has(4), logical.has(12):
+		c.text = true
+	case logical.has(5):
+		c.converted = _convertedDecimal
+		c.scale = int(logical.child(5).int(1))
+	case logical.has(6):
+		c.converted = _convertedDate
+	case logical.has(8):
+		switch unit := logical.child(8).child(2); {
+		case unit.has(1):
+			c.unit = time.Millisecond
+		case unit.has(2):
+			c.unit = time.Microsecond
+		case unit.has(3):
+			c.unit = time.Nanosecond
+		}
+	case logical.has(10):
+		signed, _ := logical.child(10).bool(2)
+		c.unsigned = !signed
+	}
+}
+
+// ordered reports whether the statistics of the column are ordered by the values they decode to.
+func (c *parquetColumn) ordered() bool {
+	return c.physical != _parquetInt96 && c.converted != _convertedDecimal &&
+		(c.physical != _parquetFixed || c.converted == -1)
+}
+
+// compare compares two values of the column in their plain encoding, without the length of byte arrays.
+func (c *parquetColumn) compare(a, b []byte) int {
+	switch c.physical {
+	case _parquetBoolean:
+		return cmp.Compare(a[0]&1, b[0]&1)
+	case _parquetInt32:
+		x, y := binary.LittleEndian.Uint32(a), binary.LittleEndian.Uint32(b)
+		if c.unsigned {
+			return cmp.Compare(x, y)
+		}
+		return cmp.Compare(int32(x), int32(y))
+	case _parquetInt64:
+		x, y := binary.LittleEndian.Uint64(a), binary.LittleEndian.Uint64(b)
+		if c.unsigned {
+			return cmp.Compare(x, y)
+		}
+		return cmp.Compare(int64(x), int64(y))
+	case _parquetFloat:
+		return cmp.Compare(math.Float32frombits(binary.LittleEndian.Uint32(a)), math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case _parquetDouble:
+		return cmp.Compare(math.Float64frombits(binary.LittleEndian.Uint64(a)), math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	}
+	return bytes.Compare(a, b)
+}
+
+// decodePlain decodes n plain encoded values of the column from b.
+func (c *parquetColumn) decodePlain(b []byte, n int) ([]any, error) {
+	if n < 0 || n > 8*len(b) {
+		return nil, errParquetTruncated
+	}
+	values := make([]any, 0, n)
+
+	size := c.size()
+	switch {
+	case c.physical == _parquetBoolean:
+		if len(b) < (n+7)/8 {
+			return nil, errParquetTruncated
+		}
+		for i := range n {
+			values = append(values, c.value(b[i/8]>>(i%8)&1 == 1))
+		}
+		return values, nil
+	case c.physical == _parquetByteArray:
+		for range n {
+			if len(b) < 4 {
+				return nil, errParquetTruncated
+			}
+			length := binary.LittleEndian.Uint32(b)
+			if uint64(length) > uint64(len(b)-4) {
+				return nil, errParquetTruncated
+			}
+			values = append(values, c.value(b[4:4+length:4+length]))
+			b = b[4+length:]
+		}
+		return values, nil
+	case size == 0 || len(b) < n*size:
+		return nil, errParquetTruncated
+	}
+
+	for i := range n {
+		v := b[i*size : (i+1)*size : (i+1)*size]
+		switch c.physical {
+		case _parquetInt32:
+			values = append(values, c.value(int32(binary.LittleEndian.Uint32(v))))
+		case _parquetInt64:
+			values = append(values, c.value(int64(binary.LittleEndian.Uint64(v))))
+		case _parquetFloat:
+			values = append(values, c.value(math.Float32frombits(binary.LittleEndian.Uint32(v))))
+		case _parquetDouble:
+			values = append(values, c.value(math.Float64frombits(binary.LittleEndian.Uint64(v))))
+		default:
+			values = append(values, c.value(v))
+		}
+	}
+	return values, nil
+}
+
+// size returns the size of the plain encoded values of the column, or 0 for booleans and byte arrays.
+func (c *parquetColumn) size() int {
+	switch c.physical {
+	case _parquetInt32, _parquetFloat:
+		return 4
+	case _parquetInt64, _parquetDouble:
+		return 8
+	case _parquetInt96:
+		return 12
+	case _parquetFixed:
+		return c.length
+	}
+	return 0
+}
+
+// value converts a physical value of the column to the Go value of its logical type: a string for text,
+// a [time.Time] for dates and timestamps, an unsigned integer for unsigned integers, and a float64 for
+// decimals.
+func (c *parquetColumn) value(v any) any {
+	switch v := v.(type) {
+	case int32:
+		switch {
+		case c.converted == _convertedDate:
+			return time.Unix(int64(v)*24*60*60, 0).UTC()
+		case c.converted == _convertedDecimal:
+			return float64(v) / math.Pow10(c.scale)
+		case c.unsigned:
+			return uint32(v)
+		}
+	case int64:
+		switch {
+		case c.unit == time.Millisecond:
+			return time.UnixMilli(v).UTC()
+		case c.unit == time.Microsecond:
+			return time.UnixMicro(v).UTC()
+		case c.unit == time.Nanosecond:
+			return time.Unix(0, v).UTC()
+		case c.converted == _convertedDecimal:
+			return float64(v) / math.Pow10(c.scale)
+		case c.unsigned:
+			return uint64(v)
+		}
+	case []byte:
+		switch {
+		case c.physical == _parquetInt96:
+			nanos := int64(binary.LittleEndian.Uint64(v))
+			days := int64(binary.LittleEndian.Uint32(v[8:])) - _julianUnixEpoch
+			return time.Unix(days*24*60*60, nanos).UTC()
+		case c.converted == _convertedDecimal:
+			unscaled := new(big.Int).SetBytes(v)
+			if len(v) > 0 && v[0]&0x80 != 0 {
+				unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(v))))
+			}
+			f, _ := new(big.Float).Quo(new(big.Float).SetInt(unscaled), big.NewFloat(math.Pow10(c.scale))).Float64()
+			return f
+		case c.text:
+			return string(v)
+		}
+	}
+	return v
+}
+
+var errParquetTruncated = errors.New("truncated page")
+
+// decodeHybrid decodes n values of the given bit width in the RLE and bit-packing hybrid encoding from b.
+func decodeHybrid(b []byte, width, n int) ([]uint32, error) {
+	if width > 32 || n < 0 {
+		return nil, fmt.Errorf("invalid bit width %d", width)
+	}
+
+	values := make([]uint32, 0, n)
+	for len(values) < n {
+		h, k := binary.Uvarint(b)
+		if k <= 0 {
+			return nil, errParquetTruncated
+		}
+		b = b[k:]
+
+		if h&1 == 0 {
+			size := (width + 7) / 8
+			if len(b) < size {
+				return nil, errParquetTruncated
+			}
+			var v uint32
+			for i := range size {
+				v |= uint32(b[i]) << (8 * i)
+			}
+			b = b[size:]
+			for range min(h>>1, uint64(n-len(values))) {
+				values = append(values, v)
+			}
+			continue
+		}
+
+		groups := int(min(h>>1, uint64(n)))
+		size := min(groups*width, len(b))
+		for i := 0; i < groups*8 && len(values) < n; i++ {
+			var v uint32
+			for j := range width {
+				bit := i*width + j
+				if bit/8 >= size {
+					return nil, errParquetTruncated
+				}
+				v |= uint32(b[bit/8]>>(bit%8)&1) << j
+			}
+			values = append(values, v)
+		}
+		b = b[size:]
+	}
+	return values, nil
+}
+
+// appendHybrid appends the levels in the RLE and bit-packing hybrid encoding with a bit width of 1, as runs.
+func appendHybrid(b []byte, levels []byte) []byte {
+	for len(levels) > 0 {
+		n := 1
+		for n < len(levels) && levels[n] == levels[0] {
+			n++
+		}
+		b = append(binary.AppendUvarint(b, uint64(n)<<1), levels[0])
+		levels = levels[n:]
+	}
+	return b
+}
+
+// decompress returns the data of a page compressed with the codec.
+func decompress(codec int64, data []byte, size int) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+	switch codec {
+	case _codecUncompressed:
+		out = data
+	case _codecSnappy:
+		out, err = decodeSnappy(data)
+	case _codecGzip:
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			out, err = io.ReadAll(io.LimitReader(zr, int64(size)+1))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression codec %d", codec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decompressing page: %w", err)
+	}
+	if len(out) != size {
+		return nil, fmt.Errorf("decompressing page: got %d bytes, expected %d", len(out), size)
+	}
+	return out, nil
+}
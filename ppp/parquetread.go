@@ -0,0 +1,501 @@
+package ppp
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math/bits"
+	"reflect"
+	"strings"
+)
+
+// OpenParquet is a [ParquetOpener] reading the rows of a Parquet file into structs of type T. Columns map to
+// fields like for [NewCSVParser] by the names of their parquet tag, such as `parquet:"amount"`, or else of their
+// json tag or field name, ignoring case. Columns without a field are not read.
+//
+// It reads flat columns, required or optional, of every physical type, written in data pages of either
+// version with the plain, dictionary, or RLE encodings and compressed with Snappy, gzip, or not at all, which
+// covers the default output of common writers. Values are converted to the field type, with text as strings,
+// dates and timestamps as [time.Time], decimals as floats, and pointers staying nil for nulls. Nested and
+// repeated columns, the delta encodings, and other compression codecs are reported as errors when a field
+// maps to their column.
+func OpenParquet[T any](r io.ReaderAt, size int64) (ParquetFile[T], error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("reading parquet into %s: expected a struct", typ)
+	}
+
+	meta, err := readParquetFooter(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := parquetSchema(meta.list(2))
+	if err != nil {
+		return nil, err
+	}
+
+	f := &parquetFile[T]{r: r, size: size, columns: columns}
+
+	fields := tagFields(typ, "parquet")
+	for i, c := range columns {
+		index, ok := fields[strings.ToLower(c.name)]
+		if !ok {
+			continue
+		}
+		if c.maxRep > 0 || c.maxDef > 1 {
+			return nil, fmt.Errorf("reading parquet column %s: nested and repeated columns are not supported", c.name)
+		}
+		f.fields = append(f.fields, parquetField{column: i, index: index})
+	}
+
+	var first int64
+	for _, v := range meta.list(4) {
+		rg, _ := v.(thriftStruct)
+		chunks := rg.list(1)
+		if len(chunks) != len(columns) {
+			return nil, fmt.Errorf("reading parquet row group: got %d columns, expected %d", len(chunks), len(columns))
+		}
+
+		g := &parquetRowGroup[T]{file: f, first: first, numRows: rg.int(3), chunks: make([]thriftStruct, len(chunks))}
+		if g.numRows < 0 {
+			return nil, fmt.Errorf("reading parquet row group: invalid number of rows %d", g.numRows)
+		}
+		for i, v := range chunks {
+			chunk, _ := v.(thriftStruct)
+			if chunk.string(1) != "" {
+				return nil, fmt.Errorf("reading parquet column %s: column chunks in other files are not supported", columns[i].name)
+			}
+			g.chunks[i] = chunk.child(3)
+		}
+		f.groups = append(f.groups, g)
+		first += g.numRows
+	}
+
+	return f, nil
+}
+
+// readParquetFooter returns the file metadata from the footer of a Parquet file.
+func readParquetFooter(r io.ReaderAt, size int64) (thriftStruct, error) {
+	if size < 12 {
+		return nil, errors.New("reading parquet: file too small")
+	}
+
+	tail := make([]byte, 8)
+	if _, err := r.ReadAt(tail, size-8); err != nil {
+		return nil, fmt.Errorf("reading parquet footer: %w", err)
+	}
+	if !bytes.Equal(tail[4:], _parquetMagic) {
+		return nil, errors.New("reading parquet: not a parquet file")
+	}
+
+	length := int64(binary.LittleEndian.Uint32(tail))
+	if length > size-12 {
+		return nil, errors.New("reading parquet footer: length out of range")
+	}
+
+	footer := make([]byte, length)
+	if _, err := r.ReadAt(footer, size-8-length); err != nil {
+		return nil, fmt.Errorf("reading parquet footer: %w", err)
+	}
+
+	meta, _, err := decodeThrift(footer)
+	if err != nil {
+		return nil, fmt.Errorf("reading parquet footer: %w", err)
+	}
+	return meta, nil
+}
+
+// parquetSchema returns the leaf columns of the flattened schema elements, named by their dotted path.
+func parquetSchema(elements []any) ([]parquetColumn, error) {
+	if len(elements) == 0 {
+		return nil, errors.New("reading parquet schema: no elements")
+	}
+
+	var (
+		columns []parquetColumn
+		next    = 1
+		walk    func(n int, prefix string, def, rep int) error
+	)
+	walk = func(n int, prefix string, def, rep int) error {
+		for range n {
+			if next >= len(elements) {
+				return errors.New("reading parquet schema: truncated")
+			}
+			el, _ := elements[next].(thriftStruct)
+			next++
+
+			name := prefix + el.string(4)
+			d, r := def, rep
+			switch el.int(3) {
+			case _parquetOptional:
+				d++
+			case _parquetRepeated:
+				d, r = d+1, r+1
+			}
+
+			if el.has(5) && !el.has(1) {
+				if err := walk(int(el.int(5)), name+".", d, r); err != nil {
+					return err
+				}
+				continue
+			}
+
+			c := parquetColumn{
+				name:       name,
+				physical:   el.int(1),
+				length:     int(el.int(2)),
+				repetition: el.int(3),
+				maxDef:     d,
+				maxRep:     r,
+			}
+			c.annotate(el)
+			columns = append(columns, c)
+		}
+		return nil
+	}
+
+	root, _ := elements[0].(thriftStruct)
+	if err := walk(int(root.int(5)), "", 0, 0); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// parquetField is a field of the rows mapped to a column.
+type parquetField struct {
+	column int
+	index  []int
+}
+
+type parquetFile[T any] struct {
+	r       io.ReaderAt
+	size    int64
+	columns []parquetColumn
+	fields  []parquetField
+	groups  []*parquetRowGroup[T]
+}
+
+func (f *parquetFile[T]) RowGroups() []ParquetRowGroup[T] {
+	groups := make([]ParquetRowGroup[T], len(f.groups))
+	for i, g := range f.groups {
+		groups[i] = g
+	}
+	return groups
+}
+
+type parquetRowGroup[T any] struct {
+	file    *parquetFile[T]
+	first   int64
+	numRows int64
+	chunks  []thriftStruct
+}
+
+func (g *parquetRowGroup[T]) NumRows() int64 { return g.numRows }
+
+func (g *parquetRowGroup[T]) Stats(column string) (ColumnStats, bool) {
+	for i, c := range g.file.columns {
+		if !strings.EqualFold(c.name, column) {
+			continue
+		}
+
+		st := g.chunks[i].child(12)
+		if st == nil {
+			return ColumnStats{}, false
+		}
+
+		s := ColumnStats{NullCount: st.int(3)}
+		lo, hi := st.bytes(6), st.bytes(5)
+		if !st.has(6) && !st.has(5) && c.physical <= _parquetDouble && c.physical != _parquetInt96 && !c.unsigned {
+			lo, hi = st.bytes(2), st.bytes(1)
+		}
+		if c.ordered() && lo != nil && hi != nil {
+			s.Min, s.Max = c.statValue(lo), c.statValue(hi)
+		}
+		return s, true
+	}
+	return ColumnStats{}, false
+}
+
+// statValue decodes a minimum or maximum value of the column statistics, or returns nil if it is invalid.
+func (c *parquetColumn) statValue(b []byte) any {
+	if c.physical == _parquetByteArray {
+		return c.value(b)
+	}
+	values, err := c.decodePlain(b, 1)
+	if err != nil {
+		return nil
+	}
+	return values[0]
+}
+
+func (g *parquetRowGroup[T]) Rows() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		columns := make([][]any, len(g.file.fields))
+		for i, f := range g.file.fields {
+			c := &g.file.columns[f.column]
+			values, err := g.file.readChunk(c, g.chunks[f.column], g.numRows)
+			if err != nil {
+				yield(zero, fmt.Errorf("reading parquet column %s: %w", c.name, err))
+				return
+			}
+			columns[i] = values
+		}
+
+		for row := range g.numRows {
+			var (
+				t T
+				v = reflect.ValueOf(&t).Elem()
+			)
+			for i, f := range g.file.fields {
+				if err := setParquet(v.FieldByIndex(f.index), columns[i][row]); err != nil {
+					yield(zero, &RowError{Line: int(g.first + row + 1), Column: g.file.columns[f.column].name, Err: err})
+					return
+				}
+			}
+			if !yield(t, nil) {
+				return
+			}
+		}
+	}
+}
+
+// readChunk returns the values of the column chunk with the metadata, nil for nulls.
+func (f *parquetFile[T]) readChunk(c *parquetColumn, meta thriftStruct, numRows int64) ([]any, error) {
+	if meta == nil {
+		return nil, errors.New("missing column metadata")
+	}
+
+	start := meta.int(9)
+	if offset := meta.int(11); offset > 0 && offset < start {
+		start = offset
+	}
+	length := meta.int(7)
+	if start < 0 || length < 0 || start > f.size-length {
+		return nil, errors.New("column chunk out of range")
+	}
+
+	buf := make([]byte, length)
+	if n, err := f.r.ReadAt(buf, start); n < len(buf) {
+		return nil, err
+	}
+
+	var (
+		codec  = meta.int(4)
+		values = make([]any, 0, min(numRows, _defaultRowGroupSize))
+		dict   []any
+	)
+	for int64(len(values)) < numRows && len(buf) > 0 {
+		h, n, err := decodeThrift(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[n:]
+
+		size := h.int(3)
+		if size < 0 || size > int64(len(buf)) {
+			return nil, errParquetTruncated
+		}
+		page := buf[:size]
+		buf = buf[size:]
+
+		switch h.int(1) {
+		case _pageDictionary:
+			data, err := decompress(codec, page, int(h.int(2)))
+			if err != nil {
+				return nil, err
+			}
+			if dict, err = c.decodePlain(data, int(h.child(7).int(1))); err != nil {
+				return nil, fmt.Errorf("decoding dictionary page: %w", err)
+			}
+		case _pageData:
+			data, err := decompress(codec, page, int(h.int(2)))
+			if err != nil {
+				return nil, err
+			}
+			dh := h.child(5)
+			n := int(dh.int(1))
+			if n < 0 || int64(n) > numRows-int64(len(values)) {
+				return nil, fmt.Errorf("page of %d values exceeds the %d rows", n, numRows)
+			}
+
+			var levels []uint32
+			if c.maxDef > 0 {
+				if encoding := dh.int(3); encoding != _encodingRLE {
+					return nil, fmt.Errorf("unsupported definition level encoding %d", encoding)
+				}
+				if len(data) < 4 || uint64(binary.LittleEndian.Uint32(data)) > uint64(len(data)-4) {
+					return nil, errParquetTruncated
+				}
+				end := 4 + int(binary.LittleEndian.Uint32(data))
+				if levels, err = decodeHybrid(data[4:end], bits.Len(uint(c.maxDef)), n); err != nil {
+					return nil, fmt.Errorf("decoding definition levels: %w", err)
+				}
+				data = data[end:]
+			}
+			if values, err = c.appendPage(values, data, dh.int(2), n, levels, dict); err != nil {
+				return nil, err
+			}
+		case _pageDataV2:
+			dh := h.child(8)
+			n := int(dh.int(1))
+			if n < 0 || int64(n) > numRows-int64(len(values)) {
+				return nil, fmt.Errorf("page of %d values exceeds the %d rows", n, numRows)
+			}
+			defLen, repLen := dh.int(5), dh.int(6)
+			if defLen < 0 || repLen < 0 || defLen+repLen > int64(len(page)) {
+				return nil, errParquetTruncated
+			}
+
+			var levels []uint32
+			if c.maxDef > 0 {
+				if levels, err = decodeHybrid(page[repLen:repLen+defLen], bits.Len(uint(c.maxDef)), n); err != nil {
+					return nil, fmt.Errorf("decoding definition levels: %w", err)
+				}
+			}
+			data := page[repLen+defLen:]
+			if compressed, ok := dh.bool(7); !ok || compressed {
+				if data, err = decompress(codec, data, int(h.int(2)-defLen-repLen)); err != nil {
+					return nil, err
+				}
+			}
+			if values, err = c.appendPage(values, data, dh.int(4), n, levels, dict); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if int64(len(values)) != numRows {
+		return nil, fmt.Errorf("got %d values, expected %d", len(values), numRows)
+	}
+	return values, nil
+}
+
+// appendPage appends the n values of a data page with the encoding to values, placing nulls where the
+// definition levels are below the maximum.
+func (c *parquetColumn) appendPage(values []any, data []byte, encoding int64, n int, levels []uint32, dict []any) ([]any, error) {
+	present := n
+	if c.maxDef > 0 {
+		present = 0
+		for _, l := range levels {
+			if int(l) == c.maxDef {
+				present++
+			}
+		}
+	}
+
+	var (
+		page []any
+		err  error
+	)
+	switch encoding {
+	case _encodingPlain:
+		page, err = c.decodePlain(data, present)
+	case _encodingPlainDictionary, _encodingRLEDictionary:
+		if dict == nil {
+			return nil, errors.New("missing dictionary page")
+		}
+		if len(data) == 0 {
+			return nil, errParquetTruncated
+		}
+		var indices []uint32
+		if indices, err = decodeHybrid(data[1:], int(data[0]), present); err == nil {
+			page = make([]any, len(indices))
+			for i, index := range indices {
+				if int64(index) >= int64(len(dict)) {
+					return nil, fmt.Errorf("dictionary index %d out of range", index)
+				}
+				page[i] = dict[index]
+			}
+		}
+	case _encodingRLE:
+		if c.physical != _parquetBoolean {
+			return nil, fmt.Errorf("unsupported encoding %d", encoding)
+		}
+		if len(data) < 4 || uint64(binary.LittleEndian.Uint32(data)) > uint64(len(data)-4) {
+			return nil, errParquetTruncated
+		}
+		var bools []uint32
+		if bools, err = decodeHybrid(data[4:4+binary.LittleEndian.Uint32(data)], 1, present); err == nil {
+			page = make([]any, len(bools))
+			for i, b := range bools {
+				page[i] = b == 1
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %d", encoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding page: %w", err)
+	}
+
+	if c.maxDef == 0 {
+		return append(values, page...), nil
+	}
+	i := 0
+	for _, l := range levels {
+		if int(l) == c.maxDef {
+			values = append(values, page[i])
+			i++
+		} else {
+			values = append(values, nil)
+		}
+	}
+	return values, nil
+}
+
+// setParquet sets the field to a value read from a Parquet column, converting numbers that fit the field.
+func setParquet(v reflect.Value, x any) error {
+	if x == nil {
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	xv := reflect.ValueOf(x)
+	switch {
+	case xv.Type().AssignableTo(v.Type()):
+		v.Set(xv)
+		return nil
+	case v.Kind() == reflect.String && xv.Type() == _bytesType:
+		v.SetString(string(xv.Bytes()))
+		return nil
+	case v.Type() == _bytesType && xv.Kind() == reflect.String:
+		v.SetBytes([]byte(xv.String()))
+		return nil
+	case xv.Kind() == reflect.String && v.Addr().Type().Implements(_textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(xv.String()))
+	}
+
+	n, ok := number(x)
+	switch {
+	case !ok:
+	case v.CanInt():
+		i, exact := n.int()
+		if exact && !v.OverflowInt(i) {
+			v.SetInt(i)
+			return nil
+		}
+		return fmt.Errorf("%v overflows %s", x, v.Type())
+	case v.CanUint():
+		u, exact := n.uint()
+		if exact && !v.OverflowUint(u) {
+			v.SetUint(u)
+			return nil
+		}
+		return fmt.Errorf("%v overflows %s", x, v.Type())
+	case v.CanFloat():
+		v.SetFloat(n.float())
+		return nil
+	}
+
+	return fmt.Errorf("cannot set %s to %T", v.Type(), x)
+}
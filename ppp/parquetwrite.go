@@ -0,0 +1,390 @@
+package ppp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const _parquetCreatedBy = "github.com/drakelthedragon/bazaar/ppp"
+
+// CreateParquet is a [ParquetCreator] writing structs of type T as the rows of a Parquet file, with a column per
+// exported field named by the first name of its parquet tag, or else by its json tag or field name. A field
+// tagged `parquet:"-"` is skipped.
+//
+// Columns are flat and required, or optional for pointer fields, which are null when nil. Booleans, integers,
+// floats, strings, and byte slices are written with their physical type and annotated with their logical type,
+// and times as timestamps in microseconds. Pages are plain encoded and compressed with gzip, and column chunks
+// carry minimum and maximum statistics for [RowGroupFilter] pruning. Every [ParquetWriter.Flush] ends a row
+// group, whose columns are buffered until then.
+func CreateParquet[T any](w io.Writer) (ParquetWriter[T], error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("writing parquet rows of %s: expected a struct", typ)
+	}
+
+	pw := &parquetWriter[T]{w: w}
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous && f.Type.Kind() == reflect.Struct {
+			continue
+		}
+
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("parquet"); ok {
+			if tag == "-" {
+				continue
+			}
+			name, _, _ = strings.Cut(tag, ",")
+		} else if json, _, _ := strings.Cut(f.Tag.Get("json"), ","); json == "-" {
+			continue
+		} else if json != "" {
+			name = json
+		}
+
+		c, err := newParquetColumnWriter(strings.TrimSpace(name), f)
+		if err != nil {
+			return nil, err
+		}
+		pw.columns = append(pw.columns, c)
+	}
+	if len(pw.columns) == 0 {
+		return nil, fmt.Errorf("writing parquet rows of %s: no exported fields", typ)
+	}
+
+	if err := pw.write(_parquetMagic); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// parquetColumnWriter buffers the values of a column for the current row group.
+type parquetColumnWriter struct {
+	parquetColumn
+	index    []int
+	typ      reflect.Type
+	optional bool
+	logical  thriftFields
+
+	values   []byte
+	levels   []byte
+	count    int
+	nulls    int64
+	min, max []byte
+}
+
+func newParquetColumnWriter(name string, f reflect.StructField) (*parquetColumnWriter, error) {
+	c := &parquetColumnWriter{
+		parquetColumn: parquetColumn{name: name, converted: -1},
+		index:         f.Index,
+		typ:           f.Type,
+	}
+
+	t := f.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		c.optional = true
+		c.repetition, c.maxDef = _parquetOptional, 1
+	}
+
+	integer := func(physical, converted int64, bits int, signed bool) {
+		c.physical, c.converted, c.unsigned = physical, converted, !signed
+		c.logical = thriftFields{{10, thriftFields{{1, int8(bits)}, {2, signed}}}}
+	}
+
+	switch {
+	case t == _timeType:
+		c.physical, c.converted, c.unit = _parquetInt64, _convertedTimestampMicros, time.Microsecond
+		c.logical = thriftFields{{8, thriftFields{{1, true}, {2, thriftFields{{2, thriftFields{}}}}}}}
+	case t == _bytesType:
+		c.physical = _parquetByteArray
+	case t.Kind() == reflect.Bool:
+		c.physical = _parquetBoolean
+	case t.Kind() == reflect.Int8:
+		integer(_parquetInt32, _convertedInt8, 8, true)
+	case t.Kind() == reflect.Int16:
+		integer(_parquetInt32, _convertedInt16, 16, true)
+	case t.Kind() == reflect.Int32:
+		c.physical = _parquetInt32
+	case t.Kind() == reflect.Int || t.Kind() == reflect.Int64:
+		c.physical = _parquetInt64
+	case t.Kind() == reflect.Uint8:
+		integer(_parquetInt32, _convertedUint8, 8, false)
+	case t.Kind() == reflect.Uint16:
+		integer(_parquetInt32, _convertedUint16, 16, false)
+	case t.Kind() == reflect.Uint32:
+		integer(_parquetInt32, _convertedUint32, 32, false)
+	case t.Kind() == reflect.Uint || t.Kind() == reflect.Uint64:
+		integer(_parquetInt64, _convertedUint64, 64, false)
+	case t.Kind() == reflect.Float32:
+		c.physical = _parquetFloat
+	case t.Kind() == reflect.Float64:
+		c.physical = _parquetDouble
+	case t.Kind() == reflect.String:
+		c.physical, c.converted, c.text = _parquetByteArray, _convertedUTF8, true
+		c.logical = thriftFields{{1, thriftFields{}}}
+	default:
+		return nil, fmt.Errorf("writing parquet column %s: unsupported field type %s", name, f.Type)
+	}
+
+	return c, nil
+}
+
+// append appends the plain encoding of the value, or a null, and updates the statistics.
+func (c *parquetColumnWriter) append(v reflect.Value) {
+	if c.optional {
+		if v.IsNil() {
+			c.levels = append(c.levels, 0)
+			c.nulls++
+			return
+		}
+		c.levels = append(c.levels, 1)
+		v = v.Elem()
+	}
+
+	var stat []byte
+	switch c.physical {
+	case _parquetBoolean:
+		if c.count%8 == 0 {
+			c.values = append(c.values, 0)
+		}
+		bit := byte(0)
+		if v.Bool() {
+			bit = 1
+			c.values[len(c.values)-1] |= 1 << (c.count % 8)
+		}
+		stat = []byte{bit}
+	case _parquetInt32:
+		c.values = binary.LittleEndian.AppendUint32(c.values, uint32(integerBits(v)))
+		stat = c.values[len(c.values)-4:]
+	case _parquetInt64:
+		var n uint64
+		if c.unit != 0 {
+			n = uint64(v.Interface().(time.Time).UnixMicro())
+		} else {
+			n = integerBits(v)
+		}
+		c.values = binary.LittleEndian.AppendUint64(c.values, n)
+		stat = c.values[len(c.values)-8:]
+	case _parquetFloat:
+		c.values = binary.LittleEndian.AppendUint32(c.values, math.Float32bits(float32(v.Float())))
+		if !math.IsNaN(v.Float()) {
+			stat = c.values[len(c.values)-4:]
+		}
+	case _parquetDouble:
+		c.values = binary.LittleEndian.AppendUint64(c.values, math.Float64bits(v.Float()))
+		if !math.IsNaN(v.Float()) {
+			stat = c.values[len(c.values)-8:]
+		}
+	case _parquetByteArray:
+		var b []byte
+		if v.Kind() == reflect.String {
+			b = []byte(v.String())
+		} else {
+			b = v.Bytes()
+		}
+		c.values = append(binary.LittleEndian.AppendUint32(c.values, uint32(len(b))), b...)
+		stat = c.values[len(c.values)-len(b):]
+	}
+	c.count++
+
+	if stat == nil {
+		return
+	}
+	if c.min == nil || c.compare(stat, c.min) < 0 {
+		c.min = bytes.Clone(stat)
+	}
+	if c.max == nil || c.compare(stat, c.max) > 0 {
+		c.max = bytes.Clone(stat)
+	}
+}
+
+// integerBits returns the two's complement bits of a signed or unsigned integer.
+func integerBits(v reflect.Value) uint64 {
+	if v.CanInt() {
+		return uint64(v.Int())
+	}
+	return v.Uint()
+}
+
+// reset clears the buffered values and statistics for the next row group.
+func (c *parquetColumnWriter) reset() {
+	c.values, c.levels = c.values[:0], c.levels[:0]
+	c.count, c.nulls = 0, 0
+	c.min, c.max = nil, nil
+}
+
+// schemaElement returns the schema element describing the column.
+func (c *parquetColumnWriter) schemaElement() thriftFields {
+	el := thriftFields{{1, int32(c.physical)}, {3, int32(c.repetition)}, {4, c.name}}
+	if c.converted >= 0 {
+		el = append(el, thriftField{6, int32(c.converted)})
+	}
+	if c.logical != nil {
+		el = append(el, thriftField{10, c.logical})
+	}
+	return el
+}
+
+type parquetWriter[T any] struct {
+	w       io.Writer
+	offset  int64
+	columns []*parquetColumnWriter
+	rows    int
+	numRows int64
+	groups  []thriftFields
+	zw      *gzip.Writer
+	page    bytes.Buffer
+	closed  bool
+}
+
+func (w *parquetWriter[T]) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing parquet: %w", err)
+	}
+	return nil
+}
+
+func (w *parquetWriter[T]) Write(rows []T) error {
+	if w.closed {
+		return errors.New("writing parquet: writer closed")
+	}
+	if int64(w.rows)+int64(len(rows)) > math.MaxInt32 {
+		return errors.New("writing parquet: row group too large")
+	}
+
+	for i := range rows {
+		v := reflect.ValueOf(&rows[i]).Elem()
+		for _, c := range w.columns {
+			fv, err := v.FieldByIndexErr(c.index)
+			if err != nil {
+				fv = reflect.Zero(c.typ)
+			}
+			c.append(fv)
+		}
+	}
+	w.rows += len(rows)
+	return nil
+}
+
+func (w *parquetWriter[T]) Flush() error {
+	if w.closed {
+		return errors.New("writing parquet: writer closed")
+	}
+	if w.rows == 0 {
+		return nil
+	}
+
+	var (
+		chunks = make([]thriftFields, 0, len(w.columns))
+		size   int64
+	)
+	for _, c := range w.columns {
+		chunk, n, err := w.writeChunk(c)
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, chunk)
+		size += n
+		c.reset()
+	}
+
+	w.groups = append(w.groups, thriftFields{{1, chunks}, {2, size}, {3, int64(w.rows)}})
+	w.numRows += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+// writeChunk writes the buffered values of the column as a data page and returns the column chunk describing it
+// with its uncompressed size.
+func (w *parquetWriter[T]) writeChunk(c *parquetColumnWriter) (thriftFields, int64, error) {
+	var body []byte
+	if c.optional {
+		levels := appendHybrid(nil, c.levels)
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(levels)))
+		body = append(body, levels...)
+	}
+	body = append(body, c.values...)
+
+	w.page.Reset()
+	if w.zw == nil {
+		w.zw = gzip.NewWriter(&w.page)
+	} else {
+		w.zw.Reset(&w.page)
+	}
+	if _, err := w.zw.Write(body); err != nil {
+		return nil, 0, fmt.Errorf("compressing parquet page: %w", err)
+	}
+	if err := w.zw.Close(); err != nil {
+		return nil, 0, fmt.Errorf("compressing parquet page: %w", err)
+	}
+
+	header := appendThrift(nil, thriftFields{
+		{1, int32(_pageData)},
+		{2, int32(len(body))},
+		{3, int32(w.page.Len())},
+		{5, thriftFields{{1, int32(w.rows)}, {2, int32(_encodingPlain)}, {3, int32(_encodingRLE)}, {4, int32(_encodingRLE)}}},
+	})
+
+	offset := w.offset
+	if err := w.write(header); err != nil {
+		return nil, 0, err
+	}
+	if err := w.write(w.page.Bytes()); err != nil {
+		return nil, 0, err
+	}
+
+	stats := thriftFields{{3, c.nulls}}
+	if c.min != nil {
+		stats = append(stats, thriftField{5, c.max}, thriftField{6, c.min})
+	}
+	size := int64(len(header) + len(body))
+	meta := thriftFields{
+		{1, int32(c.physical)},
+		{2, []int32{_encodingPlain, _encodingRLE}},
+		{3, []string{c.name}},
+		{4, int32(_codecGzip)},
+		{5, int64(w.rows)},
+		{6, size},
+		{7, int64(len(header) + w.page.Len())},
+		{9, offset},
+		{12, stats},
+	}
+	return thriftFields{{2, offset}, {3, meta}}, size, nil
+}
+
+func (w *parquetWriter[T]) Close() error {
+	if w.closed {
+		return nil
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	w.closed = true
+
+	schema := []thriftFields{{{4, "schema"}, {5, int32(len(w.columns))}}}
+	orders := make([]thriftFields, 0, len(w.columns))
+	for _, c := range w.columns {
+		schema = append(schema, c.schemaElement())
+		orders = append(orders, thriftFields{{1, thriftFields{}}})
+	}
+
+	footer := appendThrift(nil, thriftFields{
+		{1, int32(1)},
+		{2, schema},
+		{3, w.numRows},
+		{4, w.groups},
+		{6, _parquetCreatedBy},
+		{7, orders},
+	})
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	return w.write(append(footer, _parquetMagic...))
+}
@@ -3,6 +3,7 @@ package ppp
 
 import (
 	"context"
+	"fmt"
 	"io"
 )
 
@@ -39,6 +40,10 @@ func NewExecutor[I, O any](parser Parser[I], processor Processor[I, O], presente
 
 // Execute runs the parsing, processing, and presenting steps in order.
 func (e *Executor[I, O]) Execute(ctx context.Context, r io.Reader, w io.Writer) error {
+	return e.execute(ctx, r, w, e.presenter)
+}
+
+func (e *Executor[I, O]) execute(ctx context.Context, r io.Reader, w io.Writer, presenter Presenter[O]) error {
 	input, err := e.parser.Parse(r)
 	if err != nil {
 		return err
@@ -49,5 +54,23 @@ func (e *Executor[I, O]) Execute(ctx context.Context, r io.Reader, w io.Writer)
 		return err
 	}
 
-	return e.presenter.Present(w, output)
+	return presenter.Present(w, output)
+}
+
+// ExecuteFormat runs the steps like [Executor.Execute] presenting in the format, such as one given by a CLI flag,
+// when the presenter is a [FormatRegistry] or another presenter with a Format method selecting the presenter.
+func (e *Executor[I, O]) ExecuteFormat(ctx context.Context, r io.Reader, w io.Writer, format string) error {
+	f, ok := e.presenter.(interface {
+		Format(string) (Presenter[O], error)
+	})
+	if !ok {
+		return fmt.Errorf("presenting as %s: %w", format, ErrUnknownFormat)
+	}
+
+	presenter, err := f.Format(format)
+	if err != nil {
+		return err
+	}
+
+	return e.execute(ctx, r, w, presenter)
 }
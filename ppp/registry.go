@@ -0,0 +1,156 @@
+package ppp
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+)
+
+// ErrUsage is returned by [Registry.Run] for an unknown command or invalid flags, after printing the usage.
+var ErrUsage = errors.New("invalid usage")
+
+// Pipeline is a named step of a [Registry], such as an [Executor].
+type Pipeline interface {
+	Execute(ctx context.Context, r io.Reader, w io.Writer) error
+}
+
+// Registry holds named pipelines run as subcommands of a CLI by [Registry.Run] or [Main].
+type Registry struct {
+	names    []string
+	commands map[string]command
+}
+
+type command struct {
+	usage    string
+	pipeline Pipeline
+}
+
+// NewRegistry returns an empty [Registry].
+func NewRegistry() *Registry { return &Registry{commands: make(map[string]command)} }
+
+// Register adds or replaces the pipeline run by the command name, described by usage in the help output.
+func (reg *Registry) Register(name, usage string, p Pipeline) *Registry {
+	if _, ok := reg.commands[name]; !ok {
+		reg.names = append(reg.names, name)
+	}
+	reg.commands[name] = command{usage: usage, pipeline: p}
+	return reg
+}
+
+// Run runs the pipeline of the command named by the first argument with the flags that follow it:
+//
+//	-in path      read the input from the file instead of stdin
+//	-out path     write the output to the file instead of stdout
+//	-format name  present in the format, for an [Executor] presenting with a [FormatRegistry]
+//
+// A path of - stands for stdin or stdout.
+func (reg *Registry) Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		reg.usage(stderr)
+		if len(args) == 0 {
+			return fmt.Errorf("%w: missing command", ErrUsage)
+		}
+		return nil
+	}
+
+	name := args[0]
+	cmd, ok := reg.commands[name]
+	if !ok {
+		reg.usage(stderr)
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, name)
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	in := fs.String("in", "-", "input `path`, - for stdin")
+	out := fs.String("out", "-", "output `path`, - for stdout")
+	format := fs.String("format", "", "output format `name`")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "%s\n\nUsage of %s:\n", cmd.usage, name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+
+	fp, ok := cmd.pipeline.(interface {
+		ExecuteFormat(context.Context, io.Reader, io.Writer, string) error
+	})
+	if !ok && *format != "" {
+		return fmt.Errorf("%w: command %s does not support -format", ErrUsage, name)
+	}
+
+	r := stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return fmt.Errorf("opening input: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	w := stdout
+	var f *os.File
+	if *out != "-" {
+		var err error
+		if f, err = os.Create(*out); err != nil {
+			return fmt.Errorf("creating output: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var err error
+	if *format != "" {
+		err = fp.ExecuteFormat(ctx, r, w, *format)
+	} else {
+		err = cmd.pipeline.Execute(ctx, r, w)
+	}
+	if err != nil {
+		return fmt.Errorf("running %s: %w", name, err)
+	}
+
+	if f != nil {
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("closing output: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (reg *Registry) usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [-in path] [-out path] [-format name]\n\nCommands:\n", filepath.Base(os.Args[0]))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, name := range reg.names {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, reg.commands[name].usage)
+	}
+	tw.Flush()
+}
+
+// Main runs the registry with the process arguments, stdin, and stdout until done or interrupted, then exits
+// with status 2 on invalid usage, 1 on failure, and 0 otherwise, so that a main function is a single call.
+func Main(reg *Registry) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err := reg.Run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, ErrUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
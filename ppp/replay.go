@@ -0,0 +1,105 @@
+package ppp
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrNotRecorded is returned by the processor of [Replay] for an input that was not recorded.
+var ErrNotRecorded = errors.New("input not recorded")
+
+// recording is a line of a recording, holding an output unless the error is set. Empty outputs, such as "" or
+// [], are recorded too, and nil slices and maps as null.
+type recording struct {
+	Input  jsontext.Value `json:"input"`
+	Output jsontext.Value `json:"output,omitzero"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// Record returns a [Processor] calling p and writing each input with its output or error as a JSON line to w,
+// such as a file under testdata during a run against real services, to be replayed with [Replay].
+func Record[I, O any](p Processor[I, O], w io.Writer) Processor[I, O] {
+	var mu sync.Mutex
+
+	return ProcessorFunc[I, O](func(ctx context.Context, i I) (O, error) {
+		o, err := p.Process(ctx, i)
+
+		rec := recording{}
+		var merr error
+		if rec.Input, merr = json.Marshal(i, json.Deterministic(true)); merr != nil {
+			return o, fmt.Errorf("recording input: %w", merr)
+		}
+		if err != nil {
+			rec.Error = err.Error()
+		} else if rec.Output, merr = json.Marshal(o, json.Deterministic(true), json.FormatNilSliceAsNull(true), json.FormatNilMapAsNull(true)); merr != nil {
+			return o, fmt.Errorf("recording output: %w", merr)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if merr := json.MarshalWrite(w, rec); merr != nil {
+			return o, fmt.Errorf("recording: %w", merr)
+		}
+		if _, merr := io.WriteString(w, "\n"); merr != nil {
+			return o, fmt.Errorf("recording: %w", merr)
+		}
+
+		return o, err
+	})
+}
+
+// Replay returns a [Processor] answering inputs with the outputs and errors read from a recording of [Record],
+// so that processors calling external services are tested deterministically. Inputs recorded more than once are
+// answered in the recorded order, repeating the last answer. Recorded errors are replayed with their message
+// only. Other inputs fail with [ErrNotRecorded].
+func Replay[I, O any](r io.Reader) (Processor[I, O], error) {
+	answers := make(map[string][]recording)
+	dec := jsontext.NewDecoder(r)
+	for {
+		var rec recording
+		if err := json.UnmarshalDecode(dec, &rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("reading recording: %w", err)
+		}
+		answers[string(rec.Input)] = append(answers[string(rec.Input)], rec)
+	}
+
+	var mu sync.Mutex
+
+	return ProcessorFunc[I, O](func(_ context.Context, i I) (O, error) {
+		var o O
+
+		key, err := json.Marshal(i, json.Deterministic(true))
+		if err != nil {
+			return o, fmt.Errorf("replaying input: %w", err)
+		}
+
+		mu.Lock()
+		recs := answers[string(key)]
+		if len(recs) == 0 {
+			mu.Unlock()
+			return o, fmt.Errorf("%w: %s", ErrNotRecorded, key)
+		}
+		rec := recs[0]
+		if len(recs) > 1 {
+			answers[string(key)] = recs[1:]
+		}
+		mu.Unlock()
+
+		if rec.Error != "" {
+			return o, errors.New(rec.Error)
+		}
+		if err := json.Unmarshal(rec.Output, &o); err != nil {
+			return o, fmt.Errorf("replaying output: %w", err)
+		}
+		return o, nil
+	}), nil
+}
@@ -0,0 +1,92 @@
+package ppp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errSnappyCorrupt = errors.New("corrupt snappy data")
+
+// decodeSnappy decompresses a block of the raw Snappy format, which Parquet writers use by default.
+func decodeSnappy(src []byte) ([]byte, error) {
+	n, k := binary.Uvarint(src)
+	if k <= 0 || n > 1<<31 || n > 32*uint64(len(src)) {
+		return nil, errSnappyCorrupt
+	}
+	src = src[k:]
+	dst := make([]byte, 0, n)
+
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 0x03 {
+		case 0:
+			length := int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				size := length - 59
+				if len(src) < size {
+					return nil, errSnappyCorrupt
+				}
+				length = 0
+				for i := range size {
+					length |= int(src[i]) << (8 * i)
+				}
+				src = src[size:]
+			}
+			length++
+			if length > len(src) || len(dst)+length > int(n) {
+				return nil, errSnappyCorrupt
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+		case 1:
+			if len(src) < 2 {
+				return nil, errSnappyCorrupt
+			}
+			length, offset := 4+int(tag>>2&0x07), int(tag&0xe0)<<3|int(src[1])
+			src = src[2:]
+			if dst = snappyCopy(dst, offset, length); dst == nil {
+				return nil, errSnappyCorrupt
+			}
+		case 2:
+			if len(src) < 3 {
+				return nil, errSnappyCorrupt
+			}
+			length, offset := 1+int(tag>>2), int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+			if dst = snappyCopy(dst, offset, length); dst == nil {
+				return nil, errSnappyCorrupt
+			}
+		case 3:
+			if len(src) < 5 {
+				return nil, errSnappyCorrupt
+			}
+			length, offset := 1+int(tag>>2), int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+			if dst = snappyCopy(dst, offset, length); dst == nil {
+				return nil, errSnappyCorrupt
+			}
+		}
+		if len(dst) > int(n) {
+			return nil, errSnappyCorrupt
+		}
+	}
+
+	if len(dst) != int(n) {
+		return nil, errSnappyCorrupt
+	}
+	return dst, nil
+}
+
+// snappyCopy appends length bytes copied from offset bytes back in dst, which may overlap the bytes it appends,
+// or returns nil for an offset out of range.
+func snappyCopy(dst []byte, offset, length int) []byte {
+	if offset <= 0 || offset > len(dst) {
+		return nil
+	}
+	start := len(dst) - offset
+	for i := range length {
+		dst = append(dst, dst[start+i])
+	}
+	return dst
+}
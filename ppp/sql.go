@@ -0,0 +1,285 @@
+package ppp
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	_defaultSQLBatchSize = 500
+	_defaultSQLMaxParams = 999
+)
+
+// Placeholder returns the placeholder of the nth argument of a statement, counting from 1.
+type Placeholder func(n int) string
+
+var (
+	// QuestionPlaceholder is the placeholder of MySQL and SQLite.
+	QuestionPlaceholder Placeholder = func(int) string { return "?" }
+	// DollarPlaceholder is the placeholder of PostgreSQL.
+	DollarPlaceholder Placeholder = func(n int) string { return "$" + strconv.Itoa(n) }
+)
+
+// SQLOption configures [NewSQLParser] and [NewSQLPresenter].
+type SQLOption interface{ apply(*sqlConfig) }
+
+type sqlConfig struct {
+	args        []any
+	batchSize   int
+	maxParams   int
+	placeholder Placeholder
+	conflict    []string
+}
+
+type (
+	queryArgsOption   struct{ value []any }
+	batchSizeOption   struct{ value int }
+	maxParamsOption   struct{ value int }
+	placeholderOption struct{ value Placeholder }
+	upsertOption      struct{ value []string }
+)
+
+// WithQueryArgs sets the arguments of the query when parsing.
+func WithQueryArgs(args ...any) SQLOption { return queryArgsOption{value: args} }
+
+// WithBatchSize sets the number of rows inserted per statement when presenting. Defaults to 500.
+func WithBatchSize(n int) SQLOption { return batchSizeOption{value: n} }
+
+// WithMaxParams sets the maximum number of parameters of a statement when presenting, lowering the rows inserted
+// per statement below the batch size for tables with many columns. Defaults to 999, the limit of SQLite before
+// 3.32, while PostgreSQL and MySQL allow 65535.
+func WithMaxParams(n int) SQLOption { return maxParamsOption{value: n} }
+
+// WithPlaceholder sets the placeholders of the statements when presenting. Defaults to [QuestionPlaceholder].
+func WithPlaceholder(p Placeholder) SQLOption { return placeholderOption{value: p} }
+
+// WithUpsert updates the rows conflicting on the key columns instead of failing when presenting, with the
+// ON CONFLICT clause of PostgreSQL and SQLite.
+func WithUpsert(keys ...string) SQLOption { return upsertOption{value: keys} }
+
+func (o queryArgsOption) apply(cfg *sqlConfig)   { cfg.args = o.value }
+func (o batchSizeOption) apply(cfg *sqlConfig)   { cfg.batchSize = o.value }
+func (o maxParamsOption) apply(cfg *sqlConfig)   { cfg.maxParams = o.value }
+func (o placeholderOption) apply(cfg *sqlConfig) { cfg.placeholder = o.value }
+func (o upsertOption) apply(cfg *sqlConfig)      { cfg.conflict = o.value }
+
+func newSQLConfig(opts []SQLOption) sqlConfig {
+	cfg := sqlConfig{batchSize: _defaultSQLBatchSize, maxParams: _defaultSQLMaxParams, placeholder: QuestionPlaceholder}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	if cfg.batchSize <= 0 {
+		cfg.batchSize = _defaultSQLBatchSize
+	}
+	if cfg.maxParams <= 0 {
+		cfg.maxParams = _defaultSQLMaxParams
+	}
+	return cfg
+}
+
+// NewSQLParser returns a [Parser] streaming the rows of the query into structs of type T, for processing with
+// [Map] and presenting with [NewStreamPresenter] or [NewSQLPresenter]. Columns are matched to fields by their
+// db tag, such as `db:"created_at"`, or else by their JSON name or field name, ignoring case, and columns without
+// a field are skipped. The input is not read: the query runs with ctx when the rows are iterated, and stops once
+// the iteration does.
+func NewSQLParser[T any](ctx context.Context, db *sql.DB, query string, opts ...SQLOption) Parser[iter.Seq2[T, error]] {
+	cfg := newSQLConfig(opts)
+
+	return ParserFunc[iter.Seq2[T, error]](func(io.Reader) (iter.Seq2[T, error], error) {
+		typ := reflect.TypeFor[T]()
+		if typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("scanning rows into %s: expected a struct", typ)
+		}
+		fields := tagFields(typ, "db")
+
+		return func(yield func(T, error) bool) {
+			var zero T
+
+			rows, err := db.QueryContext(ctx, query, cfg.args...)
+			if err != nil {
+				yield(zero, fmt.Errorf("querying: %w", err))
+				return
+			}
+			defer rows.Close()
+
+			columns, err := rows.Columns()
+			if err != nil {
+				yield(zero, fmt.Errorf("reading columns: %w", err))
+				return
+			}
+
+			for rows.Next() {
+				var row T
+				v := reflect.ValueOf(&row).Elem()
+
+				dest := make([]any, len(columns))
+				for i, name := range columns {
+					if index, ok := fields[strings.ToLower(name)]; ok {
+						dest[i] = v.FieldByIndex(index).Addr().Interface()
+					} else {
+						dest[i] = new(any)
+					}
+				}
+
+				if err := rows.Scan(dest...); err != nil {
+					yield(zero, fmt.Errorf("scanning row: %w", err))
+					return
+				}
+				if !yield(row, nil) {
+					return
+				}
+			}
+
+			if err := rows.Err(); err != nil {
+				yield(zero, fmt.Errorf("reading rows: %w", err))
+			}
+		}, nil
+	})
+}
+
+// NewSQLPresenter returns a [Presenter] inserting a stream of structs of type T into the table in a transaction
+// run with ctx, a batch of [WithBatchSize] rows per statement within [WithMaxParams], so that the table is only
+// changed once every row is inserted. Columns are named like with [NewSQLParser]. The table and column names are
+// not quoted. The output is not written to.
+func NewSQLPresenter[T any](ctx context.Context, db *sql.DB, table string, opts ...SQLOption) Presenter[iter.Seq2[T, error]] {
+	cfg := newSQLConfig(opts)
+
+	return PresenterFunc[iter.Seq2[T, error]](func(_ io.Writer, seq iter.Seq2[T, error]) error {
+		typ := reflect.TypeFor[T]()
+		if typ.Kind() != reflect.Struct {
+			return fmt.Errorf("inserting %s: expected a struct", typ)
+		}
+		columns, fields := sqlColumns(typ)
+		if len(columns) == 0 {
+			return fmt.Errorf("inserting %s: no columns", typ)
+		}
+		batchSize := max(min(cfg.batchSize, cfg.maxParams/len(columns)), 1)
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("beginning transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		batch := make([]T, 0, batchSize)
+		insert := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+
+			args := make([]any, 0, len(batch)*len(fields))
+			for i := range batch {
+				v := reflect.ValueOf(&batch[i]).Elem()
+				for _, index := range fields {
+					args = append(args, v.FieldByIndex(index).Interface())
+				}
+			}
+
+			if _, err := tx.ExecContext(ctx, cfg.insert(table, columns, len(batch)), args...); err != nil {
+				return fmt.Errorf("inserting rows: %w", err)
+			}
+			batch = batch[:0]
+			return nil
+		}
+
+		for row, err := range seq {
+			if err != nil {
+				return err
+			}
+			batch = append(batch, row)
+			if len(batch) == batchSize {
+				if err := insert(); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := insert(); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("committing transaction: %w", err)
+		}
+		return nil
+	})
+}
+
+// insert returns the statement inserting n rows of the columns into the table.
+func (cfg sqlConfig) insert(table string, columns []string, n int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+
+	arg := 0
+	for i := range n {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for j := range columns {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			arg++
+			b.WriteString(cfg.placeholder(arg))
+		}
+		b.WriteByte(')')
+	}
+
+	if len(cfg.conflict) > 0 {
+		var set []string
+		for _, c := range columns {
+			if !containsFold(cfg.conflict, c) {
+				set = append(set, c+" = excluded."+c)
+			}
+		}
+		fmt.Fprintf(&b, " ON CONFLICT (%s) ", strings.Join(cfg.conflict, ", "))
+		if len(set) == 0 {
+			b.WriteString("DO NOTHING")
+		} else {
+			b.WriteString("DO UPDATE SET " + strings.Join(set, ", "))
+		}
+	}
+
+	return b.String()
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlColumns returns the column names of the fields of typ, named like by [tagFields], with their index.
+func sqlColumns(typ reflect.Type) ([]string, [][]int) {
+	var (
+		columns []string
+		fields  [][]int
+	)
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous && f.Type.Kind() == reflect.Struct {
+			continue
+		}
+
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("db"); ok {
+			name, _, _ = strings.Cut(tag, ",")
+		} else if n, _, _ := strings.Cut(f.Tag.Get("json"), ","); n != "" {
+			name = n
+		}
+		if name = strings.TrimSpace(name); name == "" || name == "-" {
+			continue
+		}
+
+		columns, fields = append(columns, name), append(fields, f.Index)
+	}
+	return columns, fields
+}
@@ -0,0 +1,224 @@
+package ppp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultFlushBytes    = 32 << 10
+	_defaultFlushInterval = time.Second
+	_defaultStreamBuffer  = 64
+)
+
+// FlushOption configures [NewFlushWriter] and [NewStreamPresenter].
+type FlushOption interface{ apply(*flushConfig) }
+
+type flushConfig struct {
+	bytes    int
+	interval time.Duration
+	items    int
+}
+
+type (
+	flushBytesOption    struct{ value int }
+	flushIntervalOption struct{ value time.Duration }
+	flushItemsOption    struct{ value int }
+)
+
+// WithFlushBytes flushes once n bytes are buffered. Defaults to 32 KiB.
+func WithFlushBytes(n int) FlushOption { return flushBytesOption{value: n} }
+
+// WithFlushInterval flushes buffered bytes at most d after they were written, so that a slow producer does not
+// hold back output from the reader. Zero disables it. Defaults to 1s.
+func WithFlushInterval(d time.Duration) FlushOption { return flushIntervalOption{value: d} }
+
+// WithFlushItems makes [NewStreamPresenter] flush after every n items. Zero disables it, the default.
+func WithFlushItems(n int) FlushOption { return flushItemsOption{value: n} }
+
+func (o flushBytesOption) apply(cfg *flushConfig)    { cfg.bytes = o.value }
+func (o flushIntervalOption) apply(cfg *flushConfig) { cfg.interval = o.value }
+func (o flushItemsOption) apply(cfg *flushConfig)    { cfg.items = o.value }
+
+func newFlushConfig(opts []FlushOption) flushConfig {
+	cfg := flushConfig{bytes: _defaultFlushBytes, interval: _defaultFlushInterval}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	return cfg
+}
+
+var errFlushWriterClosed = errors.New("flush writer closed")
+
+// FlushWriter buffers writes to a slow sink, such as an HTTP client or a pipe, and flushes them through to it,
+// including flushing the sink itself when it has a Flush method like an [net/http.ResponseWriter].
+// Writes block while the sink is flushed, so that a producer writing into it is slowed down to the pace of the
+// sink instead of buffering without bound.
+type FlushWriter struct {
+	cfg flushConfig
+
+	mu    sync.Mutex
+	w     io.Writer
+	buf   *bufio.Writer
+	timer *time.Timer
+	err   error
+}
+
+// NewFlushWriter returns a [FlushWriter] writing to w.
+func NewFlushWriter(w io.Writer, opts ...FlushOption) *FlushWriter {
+	cfg := newFlushConfig(opts)
+	return &FlushWriter{cfg: cfg, w: w, buf: bufio.NewWriterSize(w, max(cfg.bytes, 1))}
+}
+
+// Write buffers p, flushing once the buffer is full.
+func (fw *FlushWriter) Write(p []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if fw.err != nil {
+		return 0, fw.err
+	}
+
+	n, err := fw.buf.Write(p)
+	if err == nil && fw.buf.Buffered() >= fw.cfg.bytes {
+		err = fw.flush()
+	}
+	if err != nil {
+		fw.err = err
+		return n, err
+	}
+
+	if fw.buf.Buffered() > 0 && fw.timer == nil && fw.cfg.interval > 0 {
+		fw.timer = time.AfterFunc(fw.cfg.interval, func() { fw.Flush() })
+	}
+
+	return n, nil
+}
+
+// Flush writes the buffered bytes to the sink and flushes it.
+func (fw *FlushWriter) Flush() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if fw.err != nil {
+		return fw.err
+	}
+	fw.err = fw.flush()
+	return fw.err
+}
+
+// Close flushes the buffered bytes and stops flushing at the interval, so that nothing is written to the sink once
+// it returns, such as after an HTTP handler returned. Writes then fail.
+func (fw *FlushWriter) Close() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if errors.Is(fw.err, errFlushWriterClosed) {
+		return nil
+	}
+	if fw.timer != nil {
+		fw.timer.Stop()
+		fw.timer = nil
+	}
+
+	err := fw.err
+	if err == nil {
+		err = fw.flush()
+	}
+	fw.err = errFlushWriterClosed
+	return err
+}
+
+func (fw *FlushWriter) flush() error {
+	if fw.timer != nil {
+		fw.timer.Stop()
+		fw.timer = nil
+	}
+
+	if err := fw.buf.Flush(); err != nil {
+		return err
+	}
+
+	switch f := fw.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+
+	return nil
+}
+
+// NewStreamPresenter returns a [Presenter] of a stream of items, such as produced lazily by a processor, encoding
+// each item to a [FlushWriter] as it is yielded. Since the next item is only pulled once the previous one was
+// written, a processor producing the stream waits for a slow sink. A stream yielding an error stops presenting.
+func NewStreamPresenter[T any](encode func(io.Writer, T) error, opts ...FlushOption) Presenter[iter.Seq2[T, error]] {
+	return PresenterFunc[iter.Seq2[T, error]](func(w io.Writer, seq iter.Seq2[T, error]) error {
+		fw := NewFlushWriter(w, opts...)
+		defer fw.Close()
+
+		n := 0
+		for item, err := range seq {
+			if err != nil {
+				return err
+			}
+			if err := encode(fw, item); err != nil {
+				return err
+			}
+			if n++; fw.cfg.items > 0 && n%fw.cfg.items == 0 {
+				if err := fw.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+
+		return fw.Close()
+	})
+}
+
+// Produce runs produce concurrently with the consumer of the returned stream, such as a [NewStreamPresenter],
+// sending items through a channel holding up to buffer items. Once it is full send blocks, so that the producer
+// is held back by the consumer. The context passed to produce is canceled when the consumer stops early, and
+// send then returns its error. An error returned by produce is yielded last.
+func Produce[T any](ctx context.Context, buffer int, produce func(ctx context.Context, send func(T) error) error) iter.Seq2[T, error] {
+	if buffer <= 0 {
+		buffer = _defaultStreamBuffer
+	}
+
+	return func(yield func(T, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		items, done := make(chan T, buffer), make(chan error, 1)
+		go func() {
+			defer close(items)
+			done <- produce(ctx, func(item T) error {
+				select {
+				case items <- item:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+		}()
+
+		for item := range items {
+			if !yield(item, nil) {
+				cancel()
+				for range items {
+				}
+				return
+			}
+		}
+
+		if err := <-done; err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
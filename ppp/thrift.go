@@ -0,0 +1,288 @@
+package ppp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Types of the Thrift compact protocol, in which Parquet encodes its metadata.
+const (
+	_thriftStop   = 0
+	_thriftTrue   = 1
+	_thriftFalse  = 2
+	_thriftByte   = 3
+	_thriftI16    = 4
+	_thriftI32    = 5
+	_thriftI64    = 6
+	_thriftDouble = 7
+	_thriftBinary = 8
+	_thriftList   = 9
+	_thriftSet    = 10
+	_thriftMap    = 11
+	_thriftStruct = 12
+
+	_thriftMaxDepth = 64
+)
+
+var errThriftTruncated = errors.New("truncated thrift data")
+
+// thriftStruct holds the fields of a decoded struct by id: integers as int64, booleans as bool, binaries as
+// []byte, lists and sets as []any, and structs as thriftStruct. Maps are skipped.
+type thriftStruct map[int16]any
+
+func (s thriftStruct) has(id int16) bool { _, ok := s[id]; return ok }
+
+func (s thriftStruct) int(id int16) int64 { v, _ := s[id].(int64); return v }
+
+func (s thriftStruct) bool(id int16) (bool, bool) { v, ok := s[id].(bool); return v, ok }
+
+func (s thriftStruct) bytes(id int16) []byte { v, _ := s[id].([]byte); return v }
+
+func (s thriftStruct) string(id int16) string { return string(s.bytes(id)) }
+
+func (s thriftStruct) list(id int16) []any { v, _ := s[id].([]any); return v }
+
+func (s thriftStruct) child(id int16) thriftStruct { v, _ := s[id].(thriftStruct); return v }
+
+// thriftDecoder decodes the compact protocol, keeping the first error.
+type thriftDecoder struct {
+	b     []byte
+	err   error
+	depth int
+}
+
+// decodeThrift decodes the struct at the start of b and returns it with the number of bytes it took.
+func decodeThrift(b []byte) (thriftStruct, int, error) {
+	d := &thriftDecoder{b: b}
+	s := d.structure()
+	if d.err != nil {
+		return nil, 0, fmt.Errorf("decoding thrift: %w", d.err)
+	}
+	return s, len(b) - len(d.b), nil
+}
+
+func (d *thriftDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+	d.b = nil
+}
+
+func (d *thriftDecoder) byte() byte {
+	if len(d.b) == 0 {
+		d.fail(errThriftTruncated)
+		return 0
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+func (d *thriftDecoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.fail(errThriftTruncated)
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *thriftDecoder) varint() int64 {
+	u := d.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (d *thriftDecoder) binary() []byte {
+	n := d.uvarint()
+	if n > uint64(len(d.b)) {
+		d.fail(errThriftTruncated)
+		return nil
+	}
+	v := d.b[:n:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *thriftDecoder) structure() thriftStruct {
+	if d.depth++; d.depth > _thriftMaxDepth {
+		d.fail(errors.New("thrift structs nested too deeply"))
+	}
+	defer func() { d.depth-- }()
+
+	s := make(thriftStruct)
+	var id int16
+	for d.err == nil {
+		h := d.byte()
+		typ := h & 0x0f
+		if typ == _thriftStop {
+			break
+		}
+		if delta := int16(h >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(d.varint())
+		}
+
+		switch typ {
+		case _thriftTrue:
+			s[id] = true
+		case _thriftFalse:
+			s[id] = false
+		default:
+			if v := d.value(typ); v != nil {
+				s[id] = v
+			}
+		}
+	}
+	return s
+}
+
+func (d *thriftDecoder) value(typ byte) any {
+	switch typ {
+	case _thriftTrue, _thriftFalse:
+		return d.byte() == _thriftTrue
+	case _thriftByte:
+		return int64(int8(d.byte()))
+	case _thriftI16, _thriftI32, _thriftI64:
+		return d.varint()
+	case _thriftDouble:
+		if len(d.b) < 8 {
+			d.fail(errThriftTruncated)
+			return nil
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(d.b))
+		d.b = d.b[8:]
+		return v
+	case _thriftBinary:
+		return d.binary()
+	case _thriftList, _thriftSet:
+		h := d.byte()
+		n := uint64(h >> 4)
+		if n == 15 {
+			n = d.uvarint()
+		}
+		if n > uint64(len(d.b)) {
+			d.fail(errThriftTruncated)
+			return nil
+		}
+		items := make([]any, 0, n)
+		for range n {
+			items = append(items, d.value(h&0x0f))
+		}
+		return items
+	case _thriftMap:
+		n := d.uvarint()
+		if n == 0 {
+			return nil
+		}
+		if n > uint64(len(d.b)) {
+			d.fail(errThriftTruncated)
+			return nil
+		}
+		kv := d.byte()
+		for range n {
+			d.value(kv >> 4)
+			d.value(kv & 0x0f)
+		}
+		return nil
+	case _thriftStruct:
+		return d.structure()
+	}
+
+	d.fail(fmt.Errorf("unknown thrift type %d", typ))
+	return nil
+}
+
+// thriftField is a field of a struct to encode with a value of type bool, int8, int32, int64, string, []byte,
+// thriftFields, or a slice of them other than bool.
+type thriftField struct {
+	id    int16
+	value any
+}
+
+// thriftFields is a struct to encode, whose fields are sorted by id.
+type thriftFields []thriftField
+
+// appendThrift appends the compact encoding of the struct to b.
+func appendThrift(b []byte, s thriftFields) []byte {
+	var last int16
+	for _, f := range s {
+		typ := thriftType(f.value)
+		if delta := f.id - last; delta > 0 && delta <= 15 {
+			b = append(b, byte(delta)<<4|typ)
+		} else {
+			b = append(b, typ)
+			b = binary.AppendUvarint(b, zigzag(int64(f.id)))
+		}
+		last = f.id
+
+		if typ != _thriftTrue && typ != _thriftFalse {
+			b = appendThriftValue(b, f.value)
+		}
+	}
+	return append(b, _thriftStop)
+}
+
+func thriftType(v any) byte {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return _thriftTrue
+		}
+		return _thriftFalse
+	case int8:
+		return _thriftByte
+	case int32:
+		return _thriftI32
+	case int64:
+		return _thriftI64
+	case string, []byte:
+		return _thriftBinary
+	case thriftFields:
+		return _thriftStruct
+	case []int32, []string, []thriftFields:
+		return _thriftList
+	}
+	panic(fmt.Sprintf("ppp: cannot encode %T with thrift", v))
+}
+
+func appendThriftValue(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case int8:
+		return append(b, byte(v))
+	case int32:
+		return binary.AppendUvarint(b, zigzag(int64(v)))
+	case int64:
+		return binary.AppendUvarint(b, zigzag(v))
+	case string:
+		return append(binary.AppendUvarint(b, uint64(len(v))), v...)
+	case []byte:
+		return append(binary.AppendUvarint(b, uint64(len(v))), v...)
+	case thriftFields:
+		return appendThrift(b, v)
+	case []int32:
+		return appendThriftList(b, _thriftI32, v)
+	case []string:
+		return appendThriftList(b, _thriftBinary, v)
+	case []thriftFields:
+		return appendThriftList(b, _thriftStruct, v)
+	}
+	panic(fmt.Sprintf("ppp: cannot encode %T with thrift", v))
+}
+
+func appendThriftList[E any](b []byte, typ byte, items []E) []byte {
+	if len(items) < 15 {
+		b = append(b, byte(len(items))<<4|typ)
+	} else {
+		b = binary.AppendUvarint(append(b, 0xf0|typ), uint64(len(items)))
+	}
+	for _, item := range items {
+		b = appendThriftValue(b, item)
+	}
+	return b
+}
+
+func zigzag(v int64) uint64 { return uint64(v<<1) ^ uint64(v>>63) }
@@ -0,0 +1,30 @@
+package ppp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drakelthedragon/bazaar/hval"
+)
+
+// Validate returns an [ItemFunc] for [Map] passing on the items that are valid according to their Validate()
+// error method when they have one, or else to their validate tags checked with hval.Struct, in which case T must
+// be a struct or a pointer to one. Invalid items fail the stream with an error wrapping the [hval.Errors] of
+// their fields.
+func Validate[T any]() ItemFunc[T, T] {
+	return func(_ context.Context, item T) (T, error) {
+		var err error
+		if v, ok := any(item).(interface{ Validate() error }); ok {
+			err = v.Validate()
+		} else {
+			err = hval.Struct(item)
+		}
+		if err == nil {
+			return item, nil
+		}
+		if k, ok := any(item).(Keyer); ok {
+			return item, fmt.Errorf("validating item %s: %w", k.Key(), err)
+		}
+		return item, fmt.Errorf("validating item: %w", err)
+	}
+}
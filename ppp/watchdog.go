@@ -0,0 +1,135 @@
+package ppp
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"iter"
+	"log/slog"
+	"time"
+)
+
+// StalledItems counts the items that exceeded their timeout under [Watch].
+var StalledItems = expvar.NewInt("ppp_stalled_items")
+
+// ItemFunc processes a single item of a stream.
+type ItemFunc[T, U any] func(context.Context, T) (U, error)
+
+// Keyer is implemented by items with a key identifying them in the logs of [Watch].
+type Keyer interface{ Key() string }
+
+// WatchOption configures [Watch].
+type WatchOption interface{ apply(*watchConfig) }
+
+type watchConfig struct {
+	timeout time.Duration
+	logger  *slog.Logger
+	key     func(any) string
+}
+
+type (
+	itemTimeoutOption    struct{ value time.Duration }
+	watchdogLoggerOption struct{ value *slog.Logger }
+	itemKeyOption[T any] struct{ fn func(T) string }
+)
+
+// WithItemTimeout sets the deadline of the context each item is processed with, and how long an item may take
+// before the watchdog logs it.
+func WithItemTimeout(d time.Duration) WatchOption { return itemTimeoutOption{value: d} }
+
+// WithWatchdogLogger sets the logger of stalled items. Defaults to [slog.Default].
+func WithWatchdogLogger(l *slog.Logger) WatchOption { return watchdogLoggerOption{value: l} }
+
+// WithItemKey sets how stalled items are identified in the logs, for items that do not implement [Keyer].
+func WithItemKey[T any](fn func(T) string) WatchOption { return itemKeyOption[T]{fn: fn} }
+
+func (o itemTimeoutOption) apply(cfg *watchConfig)    { cfg.timeout = o.value }
+func (o watchdogLoggerOption) apply(cfg *watchConfig) { cfg.logger = o.value }
+
+func (o itemKeyOption[T]) apply(cfg *watchConfig) {
+	cfg.key = func(v any) string {
+		if t, ok := v.(T); ok {
+			return o.fn(t)
+		}
+		return ""
+	}
+}
+
+// Watch wraps fn so that every item is processed with a context that ends after the item timeout, and a watchdog
+// logs an item still running at the timeout, then again every timeout until it returns, with its key and how long
+// it has run. An item ignoring its context then no longer stalls a worker silently. Without a timeout fn is
+// returned as is.
+func Watch[T, U any](fn ItemFunc[T, U], opts ...WatchOption) ItemFunc[T, U] {
+	var cfg watchConfig
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	if cfg.timeout <= 0 {
+		return fn
+	}
+	if cfg.logger == nil {
+		cfg.logger = slog.Default()
+	}
+
+	return func(ctx context.Context, item T) (U, error) {
+		ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			start := time.Now()
+			ticker := time.NewTicker(cfg.timeout)
+			defer ticker.Stop()
+
+			for stalled := false; ; stalled = true {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+				}
+				if !stalled {
+					StalledItems.Add(1)
+				}
+				attrs := []slog.Attr{slog.Duration("elapsed", time.Since(start)), slog.Duration("timeout", cfg.timeout)}
+				if key := itemKey(cfg, item); key != "" {
+					attrs = append(attrs, slog.String("key", key))
+				}
+				cfg.logger.LogAttrs(ctx, slog.LevelWarn, "item exceeded timeout", attrs...)
+			}
+		}()
+
+		return fn(ctx, item)
+	}
+}
+
+func itemKey(cfg watchConfig, item any) string {
+	if k, ok := item.(Keyer); ok {
+		return k.Key()
+	}
+	if cfg.key != nil {
+		return cfg.key(item)
+	}
+	if s, ok := item.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return ""
+}
+
+// Map returns a stream of the items of seq processed one at a time by fn, such as one wrapped by [Watch],
+// stopping at the first error.
+func Map[T, U any](ctx context.Context, seq iter.Seq2[T, error], fn ItemFunc[T, U]) iter.Seq2[U, error] {
+	return func(yield func(U, error) bool) {
+		for item, err := range seq {
+			var u U
+			if err == nil {
+				u, err = fn(ctx, item)
+			}
+			if !yield(u, err) || err != nil {
+				return
+			}
+		}
+	}
+}
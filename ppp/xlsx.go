@@ -0,0 +1,432 @@
+package ppp
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSheetNotFound is returned when parsing a workbook without the sheet selected by [WithSheet].
+var ErrSheetNotFound = errors.New("sheet not found")
+
+// _excelEpoch is day zero of the 1900 date system of spreadsheets, shifted by their phantom 1900-02-29.
+var _excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// XLSXOption configures [NewXLSXParser].
+type XLSXOption interface{ apply(*xlsxConfig) }
+
+type xlsxConfig struct {
+	sheet string
+}
+
+type sheetOption struct{ value string }
+
+// WithSheet reads the sheet with the name instead of the first one.
+func WithSheet(name string) XLSXOption { return sheetOption{value: name} }
+
+func (o sheetOption) apply(cfg *xlsxConfig) { cfg.sheet = o.value }
+
+// Sheet is a named sheet of a [Workbook] holding rows like those presented by [NewCSVPresenter].
+type Sheet struct {
+	Name string
+	Rows any
+}
+
+// Workbook is presented by [NewXLSXPresenter] with a sheet per element.
+type Workbook []Sheet
+
+// NewXLSXParser returns a [Parser] reading the first sheet of an XLSX workbook, or the one selected by [WithSheet],
+// into a slice of structs of type T. Its first non-empty row is the header mapped to fields like [NewCSVParser],
+// and dates stored as numbers are converted to [time.Time] fields. Invalid values are reported with [RowErrors]
+// giving their row number.
+func NewXLSXParser[T any](opts ...XLSXOption) Parser[[]T] {
+	var cfg xlsxConfig
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	decodeCfg := csvConfig{
+		decimal:     '.',
+		layouts:     []string{time.RFC3339, time.DateTime, time.DateOnly},
+		serialDates: true,
+	}
+
+	return ParserFunc[[]T](func(r io.Reader) ([]T, error) {
+		records, lines, err := readXLSX(r, cfg.sheet)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, nil
+		}
+
+		dec, err := newRowDecoder[T](decodeCfg, records[0])
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			rows []T
+			errs RowErrors
+		)
+		for i, record := range records[1:] {
+			row, rerrs := dec.decode(record, func(int) int { return lines[i+1] })
+			if rerrs != nil {
+				errs = append(errs, rerrs...)
+				continue
+			}
+			rows = append(rows, row)
+		}
+
+		if len(errs) > 0 {
+			return rows, errs
+		}
+		return rows, nil
+	})
+}
+
+type (
+	xlsxWorkbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+
+	xlsxRelationships struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+
+	xlsxText struct {
+		T string `xml:"t"`
+		R []struct {
+			T string `xml:"t"`
+		} `xml:"r"`
+	}
+
+	xlsxSharedStrings struct {
+		Items []xlsxText `xml:"si"`
+	}
+
+	xlsxWorksheet struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R  string   `xml:"r,attr"`
+				T  string   `xml:"t,attr"`
+				V  string   `xml:"v"`
+				IS xlsxText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+)
+
+func (t xlsxText) String() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.R {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+// readXLSX returns the non-empty rows of the sheet as strings with their row numbers.
+func readXLSX(r io.Reader, sheet string) ([][]string, []int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading xlsx: %w", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening xlsx: %w", err)
+	}
+
+	var (
+		wb   xlsxWorkbook
+		rels xlsxRelationships
+		sst  xlsxSharedStrings
+		ws   xlsxWorksheet
+	)
+	if err := decodeZipXML(zr, "xl/workbook.xml", &wb); err != nil {
+		return nil, nil, err
+	}
+	if err := decodeZipXML(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, nil, err
+	}
+	if err := decodeZipXML(zr, "xl/sharedStrings.xml", &sst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	rid := ""
+	for _, s := range wb.Sheets {
+		if sheet == "" || s.Name == sheet {
+			rid = s.RID
+			break
+		}
+	}
+	if rid == "" {
+		return nil, nil, fmt.Errorf("reading xlsx sheet %q: %w", sheet, ErrSheetNotFound)
+	}
+
+	target := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == rid {
+			target = rel.Target
+		}
+	}
+	if t, ok := strings.CutPrefix(target, "/"); ok {
+		target = t
+	} else {
+		target = path.Join("xl", target)
+	}
+	if err := decodeZipXML(zr, target, &ws); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		records [][]string
+		lines   []int
+		line    int
+	)
+	for _, row := range ws.Rows {
+		line++
+		if row.R > 0 {
+			line = row.R
+		}
+
+		var (
+			record []string
+			col    = -1
+			empty  = true
+		)
+		for _, c := range row.Cells {
+			col++
+			if c.R != "" {
+				col = columnIndex(c.R)
+			}
+
+			value := c.V
+			switch c.T {
+			case "s":
+				i, err := strconv.Atoi(c.V)
+				if err != nil || i < 0 || i >= len(sst.Items) {
+					return nil, nil, fmt.Errorf("reading xlsx cell %s: invalid shared string %q", c.R, c.V)
+				}
+				value = sst.Items[i].String()
+			case "inlineStr":
+				value = c.IS.String()
+			case "b":
+				value = strconv.FormatBool(c.V == "1")
+			}
+
+			for len(record) <= col {
+				record = append(record, "")
+			}
+			record[col] = value
+			empty = empty && value == ""
+		}
+
+		if !empty {
+			records, lines = append(records, record), append(lines, line)
+		}
+	}
+
+	return records, lines, nil
+}
+
+func decodeZipXML(zr *zip.Reader, name string, v any) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return fmt.Errorf("opening xlsx part %s: %w", name, err)
+	}
+	defer f.Close()
+
+	if err := xml.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("decoding xlsx part %s: %w", name, err)
+	}
+	return nil
+}
+
+// columnIndex returns the zero-based column of a cell reference such as AB12.
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A') + 1
+	}
+	return col - 1
+}
+
+// columnName returns the letters of the zero-based column, such as AB for 27.
+func columnName(col int) string {
+	var name []byte
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = append([]byte{byte('A' + (col-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+func fromSerial(serial float64) time.Time {
+	return _excelEpoch.Add(time.Duration(serial * float64(24*time.Hour)).Round(time.Millisecond))
+}
+
+func toSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return (float64(wall.Unix()-_excelEpoch.Unix()) + float64(wall.Nanosecond())/1e9) / (24 * 60 * 60)
+}
+
+// NewXLSXPresenter returns a [Presenter] writing an XLSX workbook, with a sheet per [Sheet] of a [Workbook] or a
+// single sheet for other outputs, whose rows are slices of structs or maps like for [NewCSVPresenter]. Cells are
+// typed: numbers and booleans are written as such and times as dates.
+func NewXLSXPresenter[O any]() Presenter[O] {
+	return PresenterFunc[O](func(w io.Writer, o O) error {
+		wb, ok := any(o).(Workbook)
+		if !ok {
+			wb = Workbook{{Name: "Sheet1", Rows: o}}
+		}
+
+		zw := zip.NewWriter(w)
+
+		var sheets, rels, types strings.Builder
+		for i, sheet := range wb {
+			header, rows, err := tabulate(sheet.Rows)
+			if err != nil {
+				return fmt.Errorf("presenting sheet %s: %w", sheet.Name, err)
+			}
+
+			f, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+			if err != nil {
+				return err
+			}
+			if err := writeSheet(f, header, rows); err != nil {
+				return fmt.Errorf("writing sheet %s: %w", sheet.Name, err)
+			}
+
+			fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.Name), i+1, i+1)
+			fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="%s/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, _xlsxRelType, i+1)
+			fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="%s.worksheet+xml"/>`, i+1, _xlsxContentType)
+		}
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="%s/styles" Target="styles.xml"/>`, len(wb)+1, _xlsxRelType)
+
+		parts := []struct{ name, content string }{
+			{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+				`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+				`<Default Extension="xml" ContentType="application/xml"/>` +
+				`<Override PartName="/xl/workbook.xml" ContentType="` + _xlsxContentType + `.sheet.main+xml"/>` +
+				`<Override PartName="/xl/styles.xml" ContentType="` + _xlsxContentType + `.styles+xml"/>` +
+				types.String() + `</Types>`},
+			{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+				`<Relationship Id="rId1" Type="` + _xlsxRelType + `/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+			{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+				`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets.String() +
+				`</sheets></workbook>`},
+			{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+				rels.String() + `</Relationships>`},
+			{"xl/styles.xml", _xlsxStyles},
+		}
+		for _, part := range parts {
+			f, err := zw.Create(part.name)
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(f, xml.Header+part.content); err != nil {
+				return err
+			}
+		}
+
+		return zw.Close()
+	})
+}
+
+const (
+	_xlsxRelType     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	_xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml"
+
+	// _xlsxStyles holds the default cell style and style 1 formatting dates with the built-in format 22.
+	_xlsxStyles = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="1"><font/></fonts><fills count="1"><fill/></fills><borders count="1"><border/></borders>` +
+		`<cellStyleXfs count="1"><xf/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf/><xf numFmtId="22" applyNumberFormat="1"/></cellXfs></styleSheet>`
+)
+
+func writeSheet(w io.Writer, header []string, rows [][]any) error {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	cells := make([]any, len(header))
+	for i, name := range header {
+		cells[i] = name
+	}
+	writeRow(&b, 1, cells)
+	for i, row := range rows {
+		writeRow(&b, i+2, row)
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeRow(b *strings.Builder, n int, cells []any) {
+	fmt.Fprintf(b, `<row r="%d">`, n)
+	for i, cell := range cells {
+		v := reflect.ValueOf(cell)
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if !v.IsValid() {
+			continue
+		}
+
+		if t, ok := v.Interface().(time.Time); ok && t.IsZero() {
+			continue
+		}
+
+		ref := columnName(i) + strconv.Itoa(n)
+		switch {
+		case v.Type() == _timeType:
+			fmt.Fprintf(b, `<c r="%s" s="1"><v>%s</v></c>`, ref, strconv.FormatFloat(toSerial(v.Interface().(time.Time)), 'f', -1, 64))
+		case v.Type() == _durationType:
+			fmt.Fprintf(b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, v.Interface())
+		case v.CanInt():
+			fmt.Fprintf(b, `<c r="%s"><v>%d</v></c>`, ref, v.Int())
+		case v.CanUint():
+			fmt.Fprintf(b, `<c r="%s"><v>%d</v></c>`, ref, v.Uint())
+		case v.CanFloat() && !math.IsNaN(v.Float()) && !math.IsInf(v.Float(), 0):
+			fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v.Float(), 'g', -1, 64))
+		case v.Kind() == reflect.Bool:
+			bit := 0
+			if v.Bool() {
+				bit = 1
+			}
+			fmt.Fprintf(b, `<c r="%s" t="b"><v>%d</v></c>`, ref, bit)
+		default:
+			fmt.Fprintf(b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(fmt.Sprint(v.Interface())))
+		}
+	}
+	b.WriteString(`</row>`)
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}